@@ -0,0 +1,138 @@
+// Package certificate renders participation certificates as PDF.
+package certificate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/jung-kurt/gofpdf"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Data is what is printed on one certificate
+type Data struct {
+	Name      string
+	VerifyURL string // encoded in the QR code
+}
+
+const (
+	fontFamily = "certificate"
+	pageWidth  = 297.0 // A4 landscape, in mm
+	pageHeight = 210.0
+	qrSize     = 32.0
+	margin     = 15.0
+)
+
+// ErrAssetOutsideDir is returned for template file names that are absolute or leave the asset directory
+var ErrAssetOutsideDir = errors.New("certificate asset outside the asset directory")
+
+// AssetPath resolves the name of a template's font or background file inside dir. Names are relative to dir;
+// absolute names and names with a ".." element are rejected, so a template can only use the files deployed for it.
+func AssetPath(dir, name string) (string, error) {
+	if name == "" || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", ErrAssetOutsideDir
+	}
+	for _, element := range strings.Split(filepath.ToSlash(name), "/") {
+		if element == ".." {
+			return "", ErrAssetOutsideDir
+		}
+	}
+	path := filepath.Join(dir, name)
+	if rel, err := filepath.Rel(dir, path); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", ErrAssetOutsideDir
+	}
+	return path, nil
+}
+
+// Render writes the certificate for data as a single-page A4 landscape PDF. The template's files are
+// read from assetDir (see AssetPath).
+func Render(w io.Writer, assetDir string, tmpl domain.CertificateTemplate, data Data) error {
+	fontPath, err := AssetPath(assetDir, tmpl.FontFile)
+	if err != nil {
+		return fmt.Errorf("certificate font %q: %w", tmpl.FontFile, err)
+	}
+	font, err := os.ReadFile(fontPath)
+	if err != nil {
+		return fmt.Errorf("read certificate font: %w", err)
+	}
+	var backgroundPath string
+	if tmpl.BackgroundFile != nil && *tmpl.BackgroundFile != "" {
+		if backgroundPath, err = AssetPath(assetDir, *tmpl.BackgroundFile); err != nil {
+			return fmt.Errorf("certificate background %q: %w", *tmpl.BackgroundFile, err)
+		}
+	}
+
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddUTF8FontFromBytes(fontFamily, "", font)
+	pdf.AddPage()
+
+	if backgroundPath != "" {
+		pdf.ImageOptions(backgroundPath, 0, 0, pageWidth, pageHeight, false, gofpdf.ImageOptions{ReadDpi: true}, 0, "")
+	} else {
+		pdf.SetDrawColor(120, 120, 120)
+		pdf.SetLineWidth(0.8)
+		pdf.Rect(margin/2, margin/2, pageWidth-margin, pageHeight-margin, "D")
+	}
+
+	fill := placeholders(tmpl, data)
+	pdf.SetTextColor(30, 30, 30)
+
+	pdf.SetFont(fontFamily, "", 32)
+	pdf.SetXY(margin, 45)
+	pdf.CellFormat(pageWidth-2*margin, 14, fill.Replace(tmpl.Title), "", 1, "C", false, 0, "")
+
+	pdf.SetFont(fontFamily, "", 28)
+	pdf.SetXY(margin, 80)
+	pdf.CellFormat(pageWidth-2*margin, 14, data.Name, "", 1, "C", false, 0, "")
+
+	pdf.SetFont(fontFamily, "", 16)
+	pdf.SetXY(margin*3, 105)
+	pdf.MultiCell(pageWidth-6*margin, 9, fill.Replace(tmpl.Body), "", "C", false)
+
+	png, err := qrcode.Encode(data.VerifyURL, qrcode.Medium, 256)
+	if err != nil {
+		return fmt.Errorf("encode verification QR code: %w", err)
+	}
+	pdf.RegisterImageOptionsReader("verify", gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(png))
+	qrX, qrY := pageWidth-margin-qrSize, pageHeight-margin-qrSize-6
+	pdf.ImageOptions("verify", qrX, qrY, qrSize, qrSize, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, data.VerifyURL)
+
+	pdf.SetFont(fontFamily, "", 8)
+	pdf.SetXY(qrX-10, qrY+qrSize)
+	pdf.CellFormat(qrSize+20, 5, "Scan to verify", "", 0, "C", false, 0, data.VerifyURL)
+
+	if err := pdf.Error(); err != nil {
+		return err
+	}
+	return pdf.Output(w)
+}
+
+// placeholders fills {name}, {event} and {date} in the template texts
+func placeholders(tmpl domain.CertificateTemplate, data Data) *strings.Replacer {
+	return strings.NewReplacer(
+		"{name}", data.Name,
+		"{event}", tmpl.EventName,
+		"{date}", FormatDate(tmpl.EventDate),
+	)
+}
+
+// FormatDate formats an event date as printed on certificates, e.g. "11 January 2025"
+func FormatDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format("2 January 2006")
+}
+
+// FileName is the download name of a holder's certificate for an event
+func FileName(event string, holderId string) string {
+	return filepath.Base(fmt.Sprintf("certificate_%s_%s.pdf", event, holderId))
+}
@@ -0,0 +1,37 @@
+package certificate
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestAssetPathStaysInsideDir(t *testing.T) {
+	dir := filepath.Join("srv", "certificates")
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Sarabun-Regular.ttf", filepath.Join(dir, "Sarabun-Regular.ttf")},
+		{"fonts/Sarabun-Regular.ttf", filepath.Join(dir, "fonts", "Sarabun-Regular.ttf")},
+		{"./oph-2025.png", filepath.Join(dir, "oph-2025.png")},
+		{"", ""},
+		{"/etc/passwd", ""},
+		{"../../etc/passwd", ""},
+		{"fonts/../../secret.key", ""},
+		{"fonts/../Sarabun-Regular.ttf", ""},
+		{"..", ""},
+	}
+	for _, tt := range tests {
+		got, err := AssetPath(dir, tt.name)
+		if tt.want == "" {
+			if !errors.Is(err, ErrAssetOutsideDir) {
+				t.Errorf("AssetPath(%q) = %q, %v; want ErrAssetOutsideDir", tt.name, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("AssetPath(%q) = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
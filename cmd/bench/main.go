@@ -0,0 +1,304 @@
+// Command bench seeds a local Postgres with synthetic students and measures the QR scan,
+// dashboard and evaluation queries, optionally before and after the index migration.
+//
+//	go run ./cmd/bench -users 100000 -compare -explain
+//
+// Seeded rows use IDs starting with "bench-" and are deleted afterwards unless -keep is set.
+// Run it against a throwaway database: -compare reverts and re-applies migrations.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isd-sgcu/oph-67-backend/config"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/infrastructure"
+	"github.com/isd-sgcu/oph-67-backend/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+const idPrefix = "bench-"
+
+// baselineVersion is the schema without the hot path indexes, used by -compare
+const baselineVersion = 6
+
+var faculties = []string{
+	"Engineering", "Medicine", "Science", "Arts", "Law", "Economics", "Commerce and Accountancy",
+	"Architecture", "Education", "Dentistry", "Pharmaceutical Sciences", "Political Science",
+}
+
+var provinces = []string{"Bangkok", "Nonthaburi", "Chiang Mai", "Khon Kaen", "Songkhla", "Chonburi"}
+
+// benchmark is one measured query with the SQL shown by -explain
+type benchmark struct {
+	name    string
+	explain string
+	run     func(r *rand.Rand) error
+}
+
+func main() {
+	configFile := flag.String("config", "", "path to a YAML config file")
+	users := flag.Int("users", 100000, "number of students to seed")
+	compare := flag.Bool("compare", false, "also run the suite on the baseline schema without the indexes")
+	explain := flag.Bool("explain", false, "print EXPLAIN ANALYZE for every query")
+	keep := flag.Bool("keep", false, "keep the seeded rows")
+	flag.Parse()
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+
+	db := infrastructure.ConnectDatabase(cfg, slog.Default())
+	db.Logger = logger.Default.LogMode(logger.Silent)
+	migrator := infrastructure.NewMigrator(db)
+	if _, err := migrator.Up(); err != nil {
+		log.Fatal("Error migrating database: ", err)
+	}
+
+	var existing int64
+	if err := db.Model(&domain.User{}).Where("id NOT LIKE ?", idPrefix+"%").Count(&existing).Error; err != nil {
+		log.Fatal(err)
+	}
+	if existing > 0 && *compare {
+		log.Fatalf("The database has %d real users; -compare drops indexes, so run it on a throwaway database", existing)
+	}
+
+	if err := cleanup(db); err != nil {
+		log.Fatal("Error removing old benchmark rows: ", err)
+	}
+	if !*keep {
+		defer func() {
+			if err := cleanup(db); err != nil {
+				log.Println("Error removing benchmark rows: ", err)
+			}
+		}()
+	}
+
+	start := time.Now()
+	if err := seed(db, *users); err != nil {
+		log.Fatal("Error seeding: ", err)
+	}
+	log.Printf("Seeded %d students in %s", *users, time.Since(start).Round(time.Millisecond))
+
+	suite := newSuite(db, *users)
+	if *compare {
+		if _, err := migrator.To(baselineVersion); err != nil {
+			log.Fatal("Error reverting to the baseline schema: ", err)
+		}
+		run(db, "baseline schema", suite, *explain)
+		if _, err := migrator.Up(); err != nil {
+			log.Fatal("Error re-applying migrations: ", err)
+		}
+	}
+	run(db, "current schema", suite, *explain)
+}
+
+// seed inserts students, their scans and evaluations with realistic proportions
+func seed(db *gorm.DB, count int) error {
+	r := rand.New(rand.NewSource(1))
+	day := time.Now().Truncate(24 * time.Hour)
+	const batch = 1000
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for offset := 0; offset < count; offset += batch {
+			var users []domain.User
+			var transactions []domain.StudentTransaction
+			var evaluations []domain.StudentEvaluation
+
+			for i := offset; i < offset+batch && i < count; i++ {
+				id := fmt.Sprintf("%s%07d", idPrefix, i)
+				registered := day.Add(-time.Duration(r.Intn(30*24)) * time.Hour)
+				first, second, third := pick(r, faculties), pick(r, faculties), pick(r, faculties)
+				province := pick(r, provinces)
+				user := domain.User{
+					ID:             id,
+					UID:            id,
+					Name:           "Bench Student " + id,
+					Role:           domain.Student,
+					Phone:          id,
+					Province:       &province,
+					FirstInterest:  &first,
+					SecondInterest: &second,
+					ThirdInterest:  &third,
+					RegisteredAt:   &registered,
+				}
+
+				// Most students attend and are scanned at one to three faculties
+				if r.Intn(10) < 7 {
+					entered := day.Add(time.Duration(r.Intn(10*60)) * time.Minute)
+					user.LastEntered = &entered
+					for n := r.Intn(3) + 1; n > 0; n-- {
+						transactions = append(transactions, domain.StudentTransaction{
+							ID:                    fmt.Sprintf("%s%07d-%d", idPrefix, i, n),
+							StudentRegistrationID: id,
+							Faculty:               pick(r, faculties),
+							RegisteredAt:          entered.Add(time.Duration(n) * time.Hour),
+						})
+					}
+					if r.Intn(10) < 4 {
+						evaluations = append(evaluations, domain.StudentEvaluation{StudentId: id})
+					}
+				}
+				users = append(users, user)
+			}
+
+			if err := tx.Omit(clause.Associations).Create(&users).Error; err != nil {
+				return err
+			}
+			if len(transactions) > 0 {
+				if err := tx.Omit(clause.Associations).Create(&transactions).Error; err != nil {
+					return err
+				}
+			}
+			if len(evaluations) > 0 {
+				if err := tx.Omit(clause.Associations).Create(&evaluations).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Exec("ANALYZE users, student_transactions, student_evaluations").Error
+	})
+}
+
+func cleanup(db *gorm.DB) error {
+	// Transactions and evaluations are removed by their ON DELETE CASCADE foreign keys
+	return db.Where("id LIKE ?", idPrefix+"%").Delete(&domain.User{}).Error
+}
+
+func newSuite(db *gorm.DB, users int) []benchmark {
+	userRepo := repository.NewUserRepository(db)
+	transactionRepo := repository.NewStudentTransactionRepository(db)
+	dashboardRepo := repository.NewDashBoardRepository(db)
+	evaluationRepo := repository.NewStudentEvaluationRepository(db)
+
+	ctx := context.Background()
+	studentId := func(r *rand.Rand) string { return fmt.Sprintf("%s%07d", idPrefix, r.Intn(users)) }
+	sample := idPrefix + "0000001"
+
+	return []benchmark{
+		{
+			name:    "scan/user",
+			explain: fmt.Sprintf("SELECT * FROM users WHERE id = '%s'", sample),
+			run: func(r *rand.Rand) error {
+				_, err := userRepo.GetById(ctx, studentId(r))
+				return err
+			},
+		},
+		{
+			name:    "scan/faculty-transactions",
+			explain: fmt.Sprintf("SELECT * FROM student_transactions WHERE student_registration_id = '%s' AND faculty = '%s'", sample, faculties[0]),
+			run: func(r *rand.Rand) error {
+				_, err := transactionRepo.GetByStudentIdAndFaculty(ctx, studentId(r), pick(r, faculties))
+				return err
+			},
+		},
+		{
+			name:    "evaluation/by-student",
+			explain: fmt.Sprintf("SELECT * FROM student_evaluations WHERE student_id = '%s'", sample),
+			run: func(r *rand.Rand) error {
+				_, err := evaluationRepo.GetStudentEvaluationByStudentId(ctx, studentId(r))
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil
+				}
+				return err
+			},
+		},
+		{
+			name:    "users/list-students",
+			explain: "SELECT * FROM users WHERE role = 'student' ORDER BY registered_at DESC NULLS LAST, id DESC LIMIT 51",
+			run: func(r *rand.Rand) error {
+				filter := domain.UserFilter{Role: domain.Student}
+				order := domain.UserOrder{Sort: domain.UserSortRegisteredAt, Desc: true}
+				_, err := userRepo.List(ctx, filter, order, nil, 51)
+				return err
+			},
+		},
+		{
+			name:    "users/search",
+			explain: fmt.Sprintf("SELECT * FROM users WHERE (name ILIKE '%%%[1]s%%' OR phone ILIKE '%%%[1]s%%' OR email ILIKE '%%%[1]s%%' OR uid ILIKE '%%%[1]s%%' OR school ILIKE '%%%[1]s%%') ORDER BY registered_at DESC NULLS LAST, id DESC LIMIT 51", sample),
+			run: func(r *rand.Rand) error {
+				filter := domain.UserFilter{Search: studentId(r)}
+				order := domain.UserOrder{Sort: domain.UserSortRegisteredAt, Desc: true}
+				_, err := userRepo.List(ctx, filter, order, nil, 51)
+				return err
+			},
+		},
+		{
+			name:    "dashboard/attended-count",
+			explain: "SELECT COUNT(*) FROM users WHERE last_entered IS NOT NULL",
+			run: func(r *rand.Rand) error {
+				_, err := dashboardRepo.GetAttendedCount(ctx, nil)
+				return err
+			},
+		},
+		{
+			name:    "dashboard/faculty-today",
+			explain: "SELECT faculty, COUNT(*) FROM student_transactions WHERE registered_at >= CURRENT_DATE AND registered_at < CURRENT_DATE + 1 GROUP BY faculty",
+			run: func(r *rand.Rand) error {
+				_, err := dashboardRepo.GetFacultyToday(ctx)
+				return err
+			},
+		},
+		{
+			name:    "dashboard/students-by-interest",
+			explain: fmt.Sprintf("SELECT id FROM users WHERE role = 'student' AND (first_interest = '%[1]s' OR second_interest = '%[1]s' OR third_interest = '%[1]s')", faculties[0]),
+			run: func(r *rand.Rand) error {
+				filter := domain.StudentExportFilter{Faculty: pick(r, faculties), By: domain.ByInterest}
+				return dashboardRepo.StreamStudents(ctx, filter, func(*domain.StudentExportRow) error { return nil })
+			},
+		},
+		{
+			name:    "dashboard/faculty-count",
+			explain: "SELECT first_interest, COUNT(*) FROM users GROUP BY first_interest",
+			run: func(r *rand.Rand) error {
+				_, err := dashboardRepo.GetFacultyCount(ctx)
+				return err
+			},
+		},
+	}
+}
+
+// run measures every benchmark with the testing package and prints one line per query
+func run(db *gorm.DB, title string, suite []benchmark, explain bool) {
+	fmt.Printf("\n== %s ==\n", title)
+	for _, bm := range suite {
+		var failure error
+		result := testing.Benchmark(func(b *testing.B) {
+			r := rand.New(rand.NewSource(int64(b.N)))
+			for i := 0; i < b.N; i++ {
+				if err := bm.run(r); err != nil {
+					failure = err
+					b.FailNow()
+				}
+			}
+		})
+		if failure != nil {
+			log.Fatalf("%s: %v", bm.name, failure)
+		}
+		fmt.Printf("%-32s %8d runs %12s/op\n", bm.name, result.N, time.Duration(result.NsPerOp()))
+
+		if explain {
+			var plan []string
+			if err := db.Raw("EXPLAIN ANALYZE " + bm.explain).Scan(&plan).Error; err != nil {
+				log.Fatalf("%s: explain: %v", bm.name, err)
+			}
+			fmt.Println("    " + strings.Join(plan, "\n    "))
+		}
+	}
+}
+
+func pick(r *rand.Rand, values []string) string {
+	return values[r.Intn(len(values))]
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/isd-sgcu/oph-67-backend/config"
+	"github.com/isd-sgcu/oph-67-backend/keyring"
+)
+
+const keysUsage = `usage:
+  keys list                        show the active and accepted key IDs
+  keys publish jwt|cert|link       create a new key in KEYS_DIR that is accepted but not yet used for signing
+  keys activate jwt|cert|link ID   sign with a published key; previous keys keep verifying`
+
+// runKeysCommand handles the "keys" subcommand used by admins to inspect and rotate signing keys
+func runKeysCommand(cfg *config.Config, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, keysUsage)
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "list":
+		keys, err := keyring.Load(cfg.Keys)
+		if err != nil {
+			log.Fatal("Invalid signing keys: ", err)
+		}
+		fmt.Printf("jwt:  active %s, accepted %v\n", keys.JWT.ActiveID, keys.JWT.IDs())
+		fmt.Printf("cert: active %s, accepted %v\n", keys.Cert.ActiveID, keys.Cert.IDs())
+		fmt.Printf("link: active %s, accepted %v\n", keys.Link.ActiveID, keys.Link.IDs())
+	case "publish":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, keysUsage)
+			os.Exit(2)
+		}
+		requireKeysDir(cfg)
+		id, err := keyring.Publish(cfg.Keys.Dir, args[1], time.Now())
+		if err != nil {
+			log.Fatal("Error publishing key: ", err)
+		}
+		fmt.Printf("%s: published key %s; restart every replica, then run \"keys activate %s %s\"\n", args[1], id, args[1], id)
+	case "activate":
+		if len(args) != 3 {
+			fmt.Fprintln(os.Stderr, keysUsage)
+			os.Exit(2)
+		}
+		requireKeysDir(cfg)
+		if err := keyring.Activate(cfg.Keys.Dir, args[1], args[2]); err != nil {
+			log.Fatal("Error activating key: ", err)
+		}
+		fmt.Printf("%s: active key %s; restart every replica to start signing with it\n", args[1], args[2])
+	default:
+		fmt.Fprintln(os.Stderr, keysUsage)
+		os.Exit(2)
+	}
+}
+
+func requireKeysDir(cfg *config.Config) {
+	if cfg.Keys.Dir == "" {
+		log.Fatal("KEYS_DIR must be set to rotate keys; keys from the environment are rotated by editing it")
+	}
+}
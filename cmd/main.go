@@ -1,7 +1,16 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/compress"
@@ -10,33 +19,100 @@ import (
 	"github.com/isd-sgcu/oph-67-backend/config"
 	_ "github.com/isd-sgcu/oph-67-backend/docs"
 	"github.com/isd-sgcu/oph-67-backend/infrastructure"
+	"github.com/isd-sgcu/oph-67-backend/keyring"
+	"github.com/isd-sgcu/oph-67-backend/logging"
+	"github.com/isd-sgcu/oph-67-backend/metrics"
 	"github.com/isd-sgcu/oph-67-backend/middleware"
 	"github.com/isd-sgcu/oph-67-backend/repository"
 	"github.com/isd-sgcu/oph-67-backend/routes"
+	"github.com/isd-sgcu/oph-67-backend/tracing"
 	"github.com/isd-sgcu/oph-67-backend/usecase"
 )
 
 func main() {
+	configFile := flag.String("config", "", "path to a YAML config file (default "+config.DefaultFile+" if present)")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	flag.Parse()
+
 	// Load configuration
-	cfg := config.LoadConfig()
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+
+	if *printConfig {
+		out, err := cfg.Redacted().YAML()
+		if err != nil {
+			log.Fatal("Error printing configuration: ", err)
+		}
+		fmt.Print(out)
+		return
+	}
+
+	logger, err := logging.New(cfg.Log, os.Stdout)
+	if err != nil {
+		log.Fatal("Invalid log configuration: ", err)
+	}
+	// Libraries writing through the standard log package end up in the same structured output
+	slog.SetDefault(logger)
+
+	if flag.Arg(0) == "keys" {
+		runKeysCommand(cfg, flag.Args()[1:])
+		return
+	}
+	if flag.Arg(0) == "migrate" {
+		runMigrateCommand(cfg, flag.Args()[1:])
+		return
+	}
+
+	// Load signing keys, refusing to start with missing or weak keys
+	keys, err := keyring.Load(cfg.Keys)
+	if err != nil {
+		logger.Error("Invalid signing keys", "error", err)
+		os.Exit(1)
+	}
+
+	// Spans are exported only when an OTLP endpoint is configured
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		logger.Error("Invalid tracing configuration", "error", err)
+		os.Exit(1)
+	}
 
 	// Initialize Fiber app
-	app := fiber.New()
+	app := fiber.New(fiber.Config{
+		ErrorHandler: middleware.ErrorHandler(logger),
+	})
 
 	// Add middleware
-	app.Use(middleware.RequestLoggerMiddleware())
+	app.Use(middleware.RequestIDMiddleware())
+	app.Use(middleware.TracingMiddleware())
+	app.Use(middleware.MetricsMiddleware())
+	app.Use(middleware.RequestLoggerMiddleware(logger))
+	app.Use(middleware.RequestTimeoutMiddleware(cfg.Server.RequestTimeout))
 	app.Use(compress.New(compress.Config{
 		Level: compress.LevelBestSpeed,
 	}))
 
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",                                           // Allowed origin
-		AllowMethods: "GET,POST,PUT,PATCH,DELETE,OPTIONS",           // Allow all necessary HTTP methods
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization", // Include Authorization and other headers
+		AllowOrigins:  strings.Join(cfg.Server.CORSOrigins, ","),                                                             // Allowed origins
+		AllowMethods:  "GET,POST,PUT,PATCH,DELETE,OPTIONS",                                                                   // Allow all necessary HTTP methods
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, traceparent, tracestate, " + middleware.HeaderRequestID, // Include Authorization and other headers
+		ExposeHeaders: middleware.HeaderRequestID,
 	}))
 
+	// Cancelled on SIGINT/SIGTERM to start a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	// Connect to the database
-	db := infrastructure.ConnectDatabase(cfg)
+	db := infrastructure.ConnectDatabase(cfg, logger)
+	if cfg.Database.MigrateOnStart {
+		infrastructure.MigrateDatabase(db, logger)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		metrics.RegisterDB(sqlDB, cfg.Database.Name)
+	}
 
 	// Connect to Cache
 
@@ -45,23 +121,101 @@ func main() {
 	dashBoardRepo := repository.NewDashBoardRepository(db)
 	transactionRepo := repository.NewStudentTransactionRepository(db)
 	studentEvaluationRepo := repository.NewStudentEvaluationRepository(db)
+	exportJobRepo := repository.NewExportJobRepository(db)
+	questionnaireRepo := repository.NewQuestionnaireRepository(db)
+	feedbackRepo := repository.NewFeedbackRepository(db)
+	facultySurveyRepo := repository.NewFacultySurveyRepository(db)
+	certificateTemplateRepo := repository.NewCertificateTemplateRepository(db)
+	certificateRepo := repository.NewCertificateRepository(db)
+	healthRepo := repository.NewHealthRepository(db, infrastructure.NewMigrator(db))
 
 	// Initialize use cases
-	userUsecase := usecase.NewUserUsecase(userRepo, transactionRepo)
+	userUsecase := usecase.NewUserUsecase(userRepo, transactionRepo, keys, cfg.Server.BaseURL, logger)
 	dashBoardUssecase := usecase.NewDashBoardUseCase(dashBoardRepo)
-	studentEvaluationUsecase := usecase.NewStudentEvaluationUsecase(studentEvaluationRepo)
+	healthUsecase := usecase.NewHealthUsecase(healthRepo)
+	questionnaireUsecase := usecase.NewQuestionnaireUsecase(questionnaireRepo)
+	feedbackUsecase := usecase.NewFeedbackUsecase(feedbackRepo)
+	facultySurveyUsecase := usecase.NewFacultySurveyUsecase(facultySurveyRepo, transactionRepo, questionnaireUsecase)
+	studentEvaluationUsecase := usecase.NewStudentEvaluationUsecase(studentEvaluationRepo, questionnaireRepo, userRepo, transactionRepo)
+	certificateUsecase := usecase.NewCertificateUsecase(certificateTemplateRepo, certificateRepo, userRepo, studentEvaluationUsecase, usecase.CertificateConfig{
+		Keys:     keys.Cert,
+		BaseURL:  cfg.Server.BaseURL,
+		AssetDir: cfg.Certificate.AssetDir,
+	})
+	exportJobUsecase := usecase.NewExportJobUsecase(exportJobRepo, dashBoardUssecase, usecase.ExportJobConfig{
+		Dir:       cfg.Export.Dir,
+		Retention: cfg.Export.Retention,
+		LinkTTL:   cfg.Export.LinkTTL,
+		LinkKeys:  keys.Link,
+		BaseURL:   cfg.Server.BaseURL,
+		Workers:   cfg.Export.Workers,
+	}, logger)
+	// Export workers get their own context so they outlive the HTTP drain and stop after it
+	exportCtx, stopExports := context.WithCancel(context.Background())
+	if err := exportJobUsecase.Start(exportCtx); err != nil {
+		logger.Error("Error starting export workers", "error", err)
+		os.Exit(1)
+	}
+
+	// Seed the default evaluation form and move old evaluations onto it
+	if err := questionnaireUsecase.EnsureDefault(ctx); err != nil {
+		logger.Error("Error creating default questionnaire", "error", err)
+		os.Exit(1)
+	}
+	if err := studentEvaluationUsecase.MigrateLegacyEvaluations(ctx); err != nil {
+		logger.Error("Error migrating evaluations", "error", err)
+		os.Exit(1)
+	}
 
 	// Register routes
+	routes.RegisterHealthRoutes(app, healthUsecase)
+	routes.RegisterMetricsRoutes(app, cfg.Server.MetricsToken)
 	routes.RegisterUserRoutes(app, userUsecase, studentEvaluationUsecase) // Register the user routes
-	routes.RegisterDashboardRoutes(app, dashBoardUssecase)
+	routes.RegisterDashboardRoutes(app, dashBoardUssecase, userUsecase, cfg.Export.StreamTimeout)
 	routes.RegisterStudentEvaluationRoutes(app, studentEvaluationUsecase, userUsecase)
+	routes.RegisterExportJobRoutes(app, exportJobUsecase, userUsecase)
+	routes.RegisterQuestionnaireRoutes(app, questionnaireUsecase, userUsecase)
+	routes.RegisterFeedbackRoutes(app, feedbackUsecase, userUsecase)
+	routes.RegisterFacultySurveyRoutes(app, facultySurveyUsecase, userUsecase)
+	routes.RegisterCertificateRoutes(app, certificateUsecase, userUsecase)
 
 	app.Get("/swagger/*", swagger.New(swagger.Config{
 		URL: "/swagger/doc.json", // URL to access the Swagger docs
 	}))
 
 	// Start the server
-	if err := app.Listen(":4000"); err != nil {
-		log.Fatal("Error starting the server:", err)
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- app.Listen(fmt.Sprintf(":%d", cfg.Server.Port))
+	}()
+
+	select {
+	case err := <-listenErr:
+		logger.Error("Error starting the server", "error", err)
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+
+	// Stop taking traffic, let in-flight requests such as scans finish, then stop background work.
+	// The listener stays open while /readyz fails, until the load balancer has stopped routing here.
+	healthUsecase.SetDraining()
+	logger.Info("Shutting down, waiting for the load balancer", "delay", cfg.Server.PreStopDelay)
+	time.Sleep(cfg.Server.PreStopDelay)
+	logger.Info("Draining requests", "timeout", cfg.Server.DrainTimeout)
+	if err := app.ShutdownWithTimeout(cfg.Server.DrainTimeout); err != nil {
+		logger.Error("Error draining requests", "error", err)
+	}
+	stopExports()
+	exportJobUsecase.Wait()
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.Error("Error flushing traces", "error", err)
+	}
+	cancelFlush()
+
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
 	}
+	logger.Info("Server stopped")
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/isd-sgcu/oph-67-backend/config"
+	"github.com/isd-sgcu/oph-67-backend/infrastructure"
+	"github.com/isd-sgcu/oph-67-backend/migration"
+)
+
+const migrateUsage = `usage:
+  migrate up             apply every pending migration
+  migrate down [n]       revert the last n applied migrations (default 1)
+  migrate status         list migrations and when they were applied
+  migrate to <version>   apply or revert until version is the newest applied; 0 reverts everything`
+
+// runMigrateCommand handles the "migrate" subcommand used to manage the database schema
+func runMigrateCommand(cfg *config.Config, args []string) {
+	if len(args) == 0 || !strings.Contains(" up down to status ", " "+args[0]+" ") {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	// number parses the optional numeric argument of a command
+	number := func(def int) int {
+		if len(args) < 2 {
+			if def < 0 {
+				fmt.Fprintln(os.Stderr, migrateUsage)
+				os.Exit(2)
+			}
+			return def
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 || len(args) > 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			os.Exit(2)
+		}
+		return n
+	}
+
+	migrator := infrastructure.NewMigrator(infrastructure.ConnectDatabase(cfg, slog.Default()))
+
+	var done []migration.Migration
+	var err error
+	switch args[0] {
+	case "up":
+		done, err = migrator.Up()
+	case "down":
+		done, err = migrator.Down(number(1))
+	case "to":
+		done, err = migrator.To(number(-1))
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatal("Error reading migration status: ", err)
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%s  %s\n", s.Migration, applied)
+		}
+		return
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	for _, m := range done {
+		fmt.Printf("%s: %s\n", args[0], m)
+	}
+	if err != nil {
+		log.Fatal("Error migrating database: ", err)
+	}
+	if len(done) == 0 {
+		fmt.Println("nothing to do")
+	}
+}
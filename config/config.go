@@ -1,32 +1,323 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
 
-	"github.com/isd-sgcu/oph-67-backend/utils"
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
+// DefaultFile is read when no config file is given and it exists
+const DefaultFile = "config.yaml"
+
+// redacted replaces secrets when the configuration is printed
+const redacted = "[REDACTED]"
+
+// Config holds every setting of the server. Values come from the defaults below, then the YAML file,
+// then environment variables (including .env), each overriding the previous.
 type Config struct {
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
-	DBName     string
+	Server      ServerConfig      `yaml:"server"`
+	Database    DatabaseConfig    `yaml:"database"`
+	Keys        KeysConfig        `yaml:"keys"`
+	Export      ExportConfig      `yaml:"export"`
+	Certificate CertificateConfig `yaml:"certificate"`
+	Log         LogConfig         `yaml:"log"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+}
+
+type ServerConfig struct {
+	Port        int      `yaml:"port"`        // PORT
+	BaseURL     string   `yaml:"baseUrl"`     // PRODUCTION_BASE_URL, public URL used in links and QR codes
+	CORSOrigins []string `yaml:"corsOrigins"` // CORS_ALLOW_ORIGINS, comma separated
+
+	RequestTimeout time.Duration `yaml:"requestTimeout"` // REQUEST_TIMEOUT, deadline for the database work of a request
+	DrainTimeout   time.Duration `yaml:"drainTimeout"`   // DRAIN_TIMEOUT, how long in-flight requests may finish on shutdown
+	PreStopDelay   time.Duration `yaml:"preStopDelay"`   // PRE_STOP_DELAY, how long /readyz fails before the listener closes
+	MetricsToken   string        `yaml:"metricsToken"`   // METRICS_TOKEN, bearer token required on /metrics when set
 }
 
-// LoadConfig loads environment variables from .env and returns a Config struct
-func LoadConfig() *Config {
-	err := godotenv.Load()
-	if err != nil {
+type DatabaseConfig struct {
+	Host     string `yaml:"host"`     // DB_HOST
+	Port     int    `yaml:"port"`     // DB_PORT
+	User     string `yaml:"user"`     // DB_USER
+	Password string `yaml:"password"` // DB_PASSWORD
+	Name     string `yaml:"name"`     // DB_NAME
+
+	MigrateOnStart bool          `yaml:"migrateOnStart"` // DB_MIGRATE_ON_START, apply pending migrations at startup
+	ConnectTimeout time.Duration `yaml:"connectTimeout"` // DB_CONNECT_TIMEOUT, how long startup retries the first connection
+
+	MaxOpenConns     int           `yaml:"maxOpenConns"`     // DB_MAX_OPEN_CONNS
+	MaxIdleConns     int           `yaml:"maxIdleConns"`     // DB_MAX_IDLE_CONNS
+	ConnMaxLifetime  time.Duration `yaml:"connMaxLifetime"`  // DB_CONN_MAX_LIFETIME, 0 keeps connections forever
+	ConnMaxIdleTime  time.Duration `yaml:"connMaxIdleTime"`  // DB_CONN_MAX_IDLE_TIME, 0 keeps idle connections forever
+	StatementTimeout time.Duration `yaml:"statementTimeout"` // DB_STATEMENT_TIMEOUT, server-side limit per statement, 0 disables
+}
+
+// KeysConfig locates the signing keys: a key directory when Dir is set, otherwise the values below
+type KeysConfig struct {
+	Dir             string `yaml:"dir"`             // KEYS_DIR
+	JWTSecret       string `yaml:"jwtSecret"`       // SECRET_JWT_KEY
+	JWTKeyID        string `yaml:"jwtKeyId"`        // JWT_KEY_ID
+	JWTPreviousKeys string `yaml:"jwtPreviousKeys"` // JWT_PREVIOUS_KEYS, keyID=secret,...
+	CertPrivateKey  string `yaml:"certPrivateKey"`  // CERT_PRIVATE_KEY
+	CertKeyID       string `yaml:"certKeyId"`       // CERT_KEY_ID
+	CertPublicKeys  string `yaml:"certPublicKeys"`  // CERT_PUBLIC_KEYS, keyID=base64 public key,...
+	// Public key of the name-only tokens of /api/users/certToken when it is no longer CERT_PRIVATE_KEY
+	CertLegacyPublicKey string `yaml:"certLegacyPublicKey"` // CERT_LEGACY_PUBLIC_KEY
+	// Download links are signed with their own key, so a leaked link key cannot mint access tokens
+	LinkSecret       string `yaml:"linkSecret"`       // LINK_SIGNING_KEY
+	LinkKeyID        string `yaml:"linkKeyId"`        // LINK_KEY_ID
+	LinkPreviousKeys string `yaml:"linkPreviousKeys"` // LINK_PREVIOUS_KEYS, keyID=secret,...
+}
+
+type ExportConfig struct {
+	Dir       string        `yaml:"dir"`       // EXPORT_DIR
+	Retention time.Duration `yaml:"retention"` // EXPORT_RETENTION
+	LinkTTL   time.Duration `yaml:"linkTtl"`   // EXPORT_LINK_TTL
+	Workers   int           `yaml:"workers"`   // EXPORT_WORKERS
+
+	StreamTimeout time.Duration `yaml:"streamTimeout"` // EXPORT_STREAM_TIMEOUT, limit of a direct /api/dashboard/download
+}
+
+// CertificateConfig locates the fonts and backgrounds certificate templates may use
+type CertificateConfig struct {
+	AssetDir string `yaml:"assetDir"` // CERT_ASSET_DIR, template files are named relative to it
+}
+
+type LogConfig struct {
+	Level     string        `yaml:"level"`     // LOG_LEVEL: debug, info, warn or error
+	Format    string        `yaml:"format"`    // LOG_FORMAT: json or text
+	SlowQuery time.Duration `yaml:"slowQuery"` // LOG_SLOW_QUERY, queries slower than this are logged as warnings, 0 disables
+}
+
+// TracingConfig configures the OTLP trace exporter; tracing is off when Endpoint is empty
+type TracingConfig struct {
+	Endpoint    string  `yaml:"endpoint"`    // OTEL_EXPORTER_OTLP_ENDPOINT, e.g. http://otel-collector:4318
+	ServiceName string  `yaml:"serviceName"` // OTEL_SERVICE_NAME
+	SampleRatio float64 `yaml:"sampleRatio"` // TRACING_SAMPLE_RATIO, share of new traces recorded, 0 to 1
+}
+
+// Default returns the settings used when nothing else is configured
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:        4000,
+			BaseURL:     "http://localhost:4000",
+			CORSOrigins: []string{"*"},
+
+			RequestTimeout: 15 * time.Second,
+			DrainTimeout:   20 * time.Second,
+			PreStopDelay:   5 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host: "localhost",
+			Port: 5432,
+			User: "postgres",
+			Name: "postgres",
+
+			MigrateOnStart: true,
+			ConnectTimeout: time.Minute,
+
+			MaxOpenConns:     25,
+			MaxIdleConns:     10,
+			ConnMaxLifetime:  30 * time.Minute,
+			ConnMaxIdleTime:  5 * time.Minute,
+			StatementTimeout: 30 * time.Second,
+		},
+		Keys: KeysConfig{
+			JWTKeyID:  "k1",
+			CertKeyID: "k1",
+			LinkKeyID: "k1",
+		},
+		Export: ExportConfig{
+			Dir:       "./exports",
+			Retention: 24 * time.Hour,
+			LinkTTL:   15 * time.Minute,
+			Workers:   2,
+
+			StreamTimeout: 10 * time.Minute,
+		},
+		Certificate: CertificateConfig{
+			AssetDir: "./assets/certificates",
+		},
+		Log: LogConfig{
+			Level:     "info",
+			Format:    "json",
+			SlowQuery: 500 * time.Millisecond,
+		},
+		Tracing: TracingConfig{
+			ServiceName: "oph-67-backend",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// Load reads the configuration from file (DefaultFile if empty and present) and the environment, and validates it
+func Load(file string) (*Config, error) {
+	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using environment variables")
 	}
 
-	return &Config{
-		DBHost:     utils.GetEnv("DB_HOST", "localhost"),
-		DBPort:     utils.GetEnv("DB_PORT", "5432"),
-		DBUser:     utils.GetEnv("DB_USER", "postgres"),
-		DBPassword: utils.GetEnv("DB_PASSWORD", ""),
-		DBName:     utils.GetEnv("DB_NAME", "postgres"),
+	cfg := Default()
+
+	if file == "" {
+		if _, err := os.Stat(DefaultFile); err == nil {
+			file = DefaultFile
+		}
+	}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read config file: %w", err)
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", file, err)
+		}
+	}
+
+	var errs []error
+	errs = append(errs, applyEnv(&cfg)...)
+	errs = append(errs, cfg.Validate()...)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &cfg, nil
+}
+
+// Validate reports every invalid setting. Signing keys are checked when the keyring is loaded.
+func (c *Config) Validate() []error {
+	var errs []error
+	invalid := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		invalid("server.port: %d is not a valid port", c.Server.Port)
+	}
+	if u, err := url.Parse(c.Server.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		invalid("server.baseUrl: %q is not an absolute URL", c.Server.BaseURL)
+	}
+	if strings.HasSuffix(c.Server.BaseURL, "/") {
+		invalid("server.baseUrl: must not end with /")
+	}
+	if len(c.Server.CORSOrigins) == 0 {
+		invalid("server.corsOrigins: at least one origin is required")
+	}
+	if c.Server.RequestTimeout <= 0 {
+		invalid("server.requestTimeout: must be positive")
+	}
+	if c.Server.DrainTimeout <= 0 {
+		invalid("server.drainTimeout: must be positive")
+	}
+	if c.Server.PreStopDelay < 0 {
+		invalid("server.preStopDelay: must not be negative")
+	}
+
+	if c.Database.Host == "" {
+		invalid("database.host: is required")
+	}
+	if c.Database.Port < 1 || c.Database.Port > 65535 {
+		invalid("database.port: %d is not a valid port", c.Database.Port)
+	}
+	if c.Database.User == "" {
+		invalid("database.user: is required")
+	}
+	if c.Database.Name == "" {
+		invalid("database.name: is required")
+	}
+	if c.Database.ConnectTimeout < 0 {
+		invalid("database.connectTimeout: must not be negative")
+	}
+	if c.Database.MaxOpenConns < 1 {
+		invalid("database.maxOpenConns: must be at least 1")
+	}
+	if c.Database.MaxIdleConns < 0 || c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		invalid("database.maxIdleConns: must be between 0 and maxOpenConns")
+	}
+	if c.Database.ConnMaxLifetime < 0 {
+		invalid("database.connMaxLifetime: must not be negative")
+	}
+	if c.Database.ConnMaxIdleTime < 0 {
+		invalid("database.connMaxIdleTime: must not be negative")
+	}
+	if c.Database.StatementTimeout < 0 {
+		invalid("database.statementTimeout: must not be negative")
+	}
+
+	if c.Export.Dir == "" {
+		invalid("export.dir: is required")
+	}
+	if c.Export.Retention <= 0 {
+		invalid("export.retention: must be positive")
 	}
+	if c.Export.LinkTTL <= 0 {
+		invalid("export.linkTtl: must be positive")
+	}
+	if c.Export.Workers < 1 {
+		invalid("export.workers: must be at least 1")
+	}
+	if c.Export.StreamTimeout <= 0 {
+		invalid("export.streamTimeout: must be positive")
+	}
+
+	if c.Certificate.AssetDir == "" {
+		invalid("certificate.assetDir: is required")
+	}
+
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		invalid("log.level: %q must be debug, info, warn or error", c.Log.Level)
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		invalid("log.format: %q must be json or text", c.Log.Format)
+	}
+	if c.Log.SlowQuery < 0 {
+		invalid("log.slowQuery: must not be negative")
+	}
+
+	if c.Tracing.Endpoint != "" {
+		if u, err := url.Parse(c.Tracing.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			invalid("tracing.endpoint: %q is not an absolute URL", c.Tracing.Endpoint)
+		}
+	}
+	if c.Tracing.ServiceName == "" {
+		invalid("tracing.serviceName: is required")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		invalid("tracing.sampleRatio: must be between 0 and 1")
+	}
+	return errs
+}
+
+// Redacted returns a copy with every secret replaced, for printing
+func (c Config) Redacted() Config {
+	redact := func(s *string) {
+		if *s != "" {
+			*s = redacted
+		}
+	}
+	redact(&c.Server.MetricsToken)
+	redact(&c.Database.Password)
+	redact(&c.Keys.JWTSecret)
+	redact(&c.Keys.JWTPreviousKeys)
+	redact(&c.Keys.CertPrivateKey)
+	redact(&c.Keys.LinkSecret)
+	redact(&c.Keys.LinkPreviousKeys)
+	return c
+}
+
+// YAML renders the configuration in the format of the config file
+func (c Config) YAML() (string, error) {
+	out, err := yaml.Marshal(c)
+	return string(out), err
 }
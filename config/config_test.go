@@ -0,0 +1,299 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envVars maps every environment variable to the setting it overrides and a valid value for it
+var envVars = []struct {
+	key   string
+	path  string
+	value string
+	want  interface{}
+}{
+	{"PORT", "server.port", "8080", 8080},
+	{"PRODUCTION_BASE_URL", "server.baseUrl", "https://example.com", "https://example.com"},
+	{"CORS_ALLOW_ORIGINS", "server.corsOrigins", "https://a.com, https://b.com,", []string{"https://a.com", "https://b.com"}},
+	{"REQUEST_TIMEOUT", "server.requestTimeout", "3s", 3 * time.Second},
+	{"DRAIN_TIMEOUT", "server.drainTimeout", "4s", 4 * time.Second},
+	{"PRE_STOP_DELAY", "server.preStopDelay", "6s", 6 * time.Second},
+	{"METRICS_TOKEN", "server.metricsToken", "metrics-token", "metrics-token"},
+
+	{"DB_HOST", "database.host", "db", "db"},
+	{"DB_PORT", "database.port", "6543", 6543},
+	{"DB_USER", "database.user", "app", "app"},
+	{"DB_PASSWORD", "database.password", "db-password", "db-password"},
+	{"DB_NAME", "database.name", "oph", "oph"},
+	{"DB_MIGRATE_ON_START", "database.migrateOnStart", "false", false},
+	{"DB_CONNECT_TIMEOUT", "database.connectTimeout", "2m", 2 * time.Minute},
+	{"DB_MAX_OPEN_CONNS", "database.maxOpenConns", "40", 40},
+	{"DB_MAX_IDLE_CONNS", "database.maxIdleConns", "20", 20},
+	{"DB_CONN_MAX_LIFETIME", "database.connMaxLifetime", "1h", time.Hour},
+	{"DB_CONN_MAX_IDLE_TIME", "database.connMaxIdleTime", "1m", time.Minute},
+	{"DB_STATEMENT_TIMEOUT", "database.statementTimeout", "45s", 45 * time.Second},
+
+	{"KEYS_DIR", "keys.dir", "/etc/oph/keys", "/etc/oph/keys"},
+	{"SECRET_JWT_KEY", "keys.jwtSecret", "jwt-secret", "jwt-secret"},
+	{"JWT_KEY_ID", "keys.jwtKeyId", "j2", "j2"},
+	{"JWT_PREVIOUS_KEYS", "keys.jwtPreviousKeys", "j1=old-jwt-secret", "j1=old-jwt-secret"},
+	{"CERT_PRIVATE_KEY", "keys.certPrivateKey", "cert-private-key", "cert-private-key"},
+	{"CERT_KEY_ID", "keys.certKeyId", "c2", "c2"},
+	{"CERT_PUBLIC_KEYS", "keys.certPublicKeys", "c1=cert-public-key", "c1=cert-public-key"},
+	{"CERT_LEGACY_PUBLIC_KEY", "keys.certLegacyPublicKey", "legacy-public-key", "legacy-public-key"},
+	{"LINK_SIGNING_KEY", "keys.linkSecret", "link-secret", "link-secret"},
+	{"LINK_KEY_ID", "keys.linkKeyId", "l2", "l2"},
+	{"LINK_PREVIOUS_KEYS", "keys.linkPreviousKeys", "l1=old-link-secret", "l1=old-link-secret"},
+
+	{"EXPORT_DIR", "export.dir", "/var/exports", "/var/exports"},
+	{"EXPORT_RETENTION", "export.retention", "48h", 48 * time.Hour},
+	{"EXPORT_LINK_TTL", "export.linkTtl", "5m", 5 * time.Minute},
+	{"EXPORT_WORKERS", "export.workers", "4", 4},
+	{"EXPORT_STREAM_TIMEOUT", "export.streamTimeout", "20m", 20 * time.Minute},
+
+	{"CERT_ASSET_DIR", "certificate.assetDir", "/srv/assets", "/srv/assets"},
+
+	{"LOG_LEVEL", "log.level", "debug", "debug"},
+	{"LOG_FORMAT", "log.format", "text", "text"},
+	{"LOG_SLOW_QUERY", "log.slowQuery", "1s", time.Second},
+
+	{"OTEL_EXPORTER_OTLP_ENDPOINT", "tracing.endpoint", "http://collector:4318", "http://collector:4318"},
+	{"OTEL_SERVICE_NAME", "tracing.serviceName", "oph", "oph"},
+	{"TRACING_SAMPLE_RATIO", "tracing.sampleRatio", "0.25", 0.25},
+}
+
+// clearEnv unsets every variable the configuration reads, so the environment of the test run does not leak in
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, v := range envVars {
+		t.Setenv(v.key, "")
+	}
+}
+
+// writeFile writes a config file in a temporary directory and returns its path
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// leaves returns the YAML path of every setting, with its value, walking the nested sections
+func leaves(v reflect.Value, prefix string, fn func(path string, field reflect.StructField, value reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		path := prefix + field.Tag.Get("yaml")
+		if field.Type.Kind() == reflect.Struct {
+			leaves(v.Field(i), path+".", fn)
+			continue
+		}
+		fn(path, field, v.Field(i))
+	}
+}
+
+// setting returns the value of the setting at a YAML path
+func setting(t *testing.T, cfg *Config, path string) interface{} {
+	t.Helper()
+	var found interface{}
+	leaves(reflect.ValueOf(cfg).Elem(), "", func(p string, _ reflect.StructField, value reflect.Value) {
+		if p == path {
+			found = value.Interface()
+		}
+	})
+	if found == nil {
+		t.Fatalf("no setting %s", path)
+	}
+	return found
+}
+
+func TestDefaultIsValid(t *testing.T) {
+	cfg := Default()
+	if errs := cfg.Validate(); len(errs) > 0 {
+		t.Errorf("default configuration is invalid: %v", errs)
+	}
+}
+
+func TestEveryEnvVarOverridesYAML(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, `
+server:
+  port: 5000
+  metricsToken: yaml-token
+database:
+  host: yaml-db
+  migrateOnStart: true
+keys:
+  jwtSecret: yaml-secret
+log:
+  level: warn
+tracing:
+  sampleRatio: 0.5
+`)
+	for _, v := range envVars {
+		t.Setenv(v.key, v.value)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range envVars {
+		if got := setting(t, cfg, v.path); !reflect.DeepEqual(got, v.want) {
+			t.Errorf("%s: %s = %v, want %v", v.key, v.path, got, v.want)
+		}
+	}
+}
+
+func TestEverySettingHasAnEnvVar(t *testing.T) {
+	covered := make(map[string]bool, len(envVars))
+	for _, v := range envVars {
+		covered[v.path] = true
+	}
+	cfg := Default()
+	leaves(reflect.ValueOf(&cfg).Elem(), "", func(path string, _ reflect.StructField, _ reflect.Value) {
+		if !covered[path] {
+			t.Errorf("%s has no environment variable in envVars; add it to applyEnv and to this test", path)
+		}
+	})
+}
+
+func TestYAMLOverridesDefaultsAndEmptyEnvIsUnset(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, `
+server:
+  port: 5000
+export:
+  workers: 3
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Port != 5000 || cfg.Export.Workers != 3 {
+		t.Errorf("port %d, workers %d; want the values of the file", cfg.Server.Port, cfg.Export.Workers)
+	}
+	if cfg.Database.Port != Default().Database.Port {
+		t.Errorf("database port %d, want the default", cfg.Database.Port)
+	}
+}
+
+func TestUnknownYAMLKeysAreRejected(t *testing.T) {
+	clearEnv(t)
+	tests := map[string]string{
+		"misspelled setting": "server:\n  prot: 5000\n",
+		"unknown section":    "smtp:\n  host: mail\n",
+		"env var name":       "database:\n  DB_HOST: db\n",
+	}
+	for name, content := range tests {
+		if _, err := Load(writeFile(t, content)); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("%s: error %v, want the unknown key rejected", name, err)
+		}
+	}
+}
+
+func TestLoadReportsEveryProblemAtOnce(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("PORT", "http")
+	t.Setenv("DB_MIGRATE_ON_START", "maybe")
+	t.Setenv("EXPORT_RETENTION", "1 day")
+	t.Setenv("LOG_LEVEL", "loud")
+	t.Setenv("DB_MAX_OPEN_CONNS", "0")
+	t.Setenv("TRACING_SAMPLE_RATIO", "2")
+
+	_, err := Load(writeFile(t, "server:\n  baseUrl: example.com/\n"))
+	if err == nil {
+		t.Fatal("invalid configuration loaded")
+	}
+	for _, want := range []string{
+		"PORT", "DB_MIGRATE_ON_START", "EXPORT_RETENTION",
+		"log.level", "database.maxOpenConns", "database.maxIdleConns", "tracing.sampleRatio", "server.baseUrl: \"example.com/\"", "server.baseUrl: must not end with /",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not report %s:\n%v", want, err)
+		}
+	}
+}
+
+func TestValidateReportsEveryInvalidSetting(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Port = 0
+	cfg.Server.RequestTimeout = 0
+	cfg.Database.Host = ""
+	cfg.Export.Workers = 0
+	cfg.Certificate.AssetDir = ""
+	cfg.Log.Format = "xml"
+	cfg.Tracing.Endpoint = "collector"
+
+	errs := cfg.Validate()
+	want := []string{"server.port", "server.requestTimeout", "database.host", "export.workers", "certificate.assetDir", "log.format", "tracing.endpoint"}
+	if len(errs) != len(want) {
+		t.Fatalf("%d errors, want %d: %v", len(errs), len(want), errs)
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(errs[i].Error(), prefix+":") {
+			t.Errorf("error %d = %q, want one about %s", i, errs[i], prefix)
+		}
+	}
+}
+
+// secretName matches settings that hold a secret. A new secret setting must be redacted in Redacted.
+var secretName = regexp.MustCompile(`(?i)secret|password|token|private|previouskeys`)
+
+func TestRedactedHidesEverySecret(t *testing.T) {
+	cfg := Default()
+	values := map[string]string{}
+	leaves(reflect.ValueOf(&cfg).Elem(), "", func(path string, field reflect.StructField, value reflect.Value) {
+		if value.Kind() == reflect.String {
+			v := fmt.Sprintf("value-of-%s", path)
+			value.SetString(v)
+			values[path] = v
+		}
+	})
+
+	redactedCfg := cfg.Redacted()
+	out, err := redactedCfg.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secrets := 0
+	for path, value := range values {
+		field := path[strings.LastIndexByte(path, '.')+1:]
+		if !secretName.MatchString(field) {
+			continue
+		}
+		secrets++
+		if strings.Contains(out, value) {
+			t.Errorf("--print-config prints %s; redact it in Config.Redacted", path)
+		}
+		if got := setting(t, &redactedCfg, path); got != redacted {
+			t.Errorf("%s = %v, want %s", path, got, redacted)
+		}
+	}
+	if secrets < 7 {
+		t.Errorf("found %d secret settings, want at least the 7 known ones", secrets)
+	}
+
+	// Public settings are still printed, and the original is left alone
+	if !strings.Contains(out, values["keys.certPublicKeys"]) || !strings.Contains(out, values["server.baseUrl"]) {
+		t.Errorf("public settings were redacted:\n%s", out)
+	}
+	if cfg.Keys.JWTSecret != values["keys.jwtSecret"] {
+		t.Error("Redacted changed the original configuration")
+	}
+}
+
+func TestRedactedKeepsUnsetSecretsEmpty(t *testing.T) {
+	cfg := Default()
+	if got := cfg.Redacted(); got.Database.Password != "" || got.Keys.JWTSecret != "" {
+		t.Errorf("unset secrets printed as %q and %q, want empty", got.Database.Password, got.Keys.JWTSecret)
+	}
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lookup returns an environment variable; empty values count as unset
+func lookup(key string) (string, bool) {
+	value := os.Getenv(key)
+	return value, value != ""
+}
+
+// applyEnv overrides settings with the environment variables that are set
+func applyEnv(c *Config) []error {
+	var errs []error
+
+	setString := func(target *string, key string) {
+		if value, ok := lookup(key); ok {
+			*target = value
+		}
+	}
+	setInt := func(target *int, key string) {
+		if value, ok := lookup(key); ok {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not an integer", key, value))
+				return
+			}
+			*target = n
+		}
+	}
+	setFloat := func(target *float64, key string) {
+		if value, ok := lookup(key); ok {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a number", key, value))
+				return
+			}
+			*target = f
+		}
+	}
+	setBool := func(target *bool, key string) {
+		if value, ok := lookup(key); ok {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not true or false", key, value))
+				return
+			}
+			*target = b
+		}
+	}
+	setDuration := func(target *time.Duration, key string) {
+		if value, ok := lookup(key); ok {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a duration such as 15m or 24h", key, value))
+				return
+			}
+			*target = d
+		}
+	}
+	setList := func(target *[]string, key string) {
+		if value, ok := lookup(key); ok {
+			var list []string
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+			*target = list
+		}
+	}
+
+	setInt(&c.Server.Port, "PORT")
+	setString(&c.Server.BaseURL, "PRODUCTION_BASE_URL")
+	setList(&c.Server.CORSOrigins, "CORS_ALLOW_ORIGINS")
+	setDuration(&c.Server.RequestTimeout, "REQUEST_TIMEOUT")
+	setDuration(&c.Server.DrainTimeout, "DRAIN_TIMEOUT")
+	setDuration(&c.Server.PreStopDelay, "PRE_STOP_DELAY")
+	setString(&c.Server.MetricsToken, "METRICS_TOKEN")
+
+	setString(&c.Database.Host, "DB_HOST")
+	setInt(&c.Database.Port, "DB_PORT")
+	setString(&c.Database.User, "DB_USER")
+	setString(&c.Database.Password, "DB_PASSWORD")
+	setString(&c.Database.Name, "DB_NAME")
+	setBool(&c.Database.MigrateOnStart, "DB_MIGRATE_ON_START")
+	setDuration(&c.Database.ConnectTimeout, "DB_CONNECT_TIMEOUT")
+	setInt(&c.Database.MaxOpenConns, "DB_MAX_OPEN_CONNS")
+	setInt(&c.Database.MaxIdleConns, "DB_MAX_IDLE_CONNS")
+	setDuration(&c.Database.ConnMaxLifetime, "DB_CONN_MAX_LIFETIME")
+	setDuration(&c.Database.ConnMaxIdleTime, "DB_CONN_MAX_IDLE_TIME")
+	setDuration(&c.Database.StatementTimeout, "DB_STATEMENT_TIMEOUT")
+
+	setString(&c.Keys.Dir, "KEYS_DIR")
+	setString(&c.Keys.JWTSecret, "SECRET_JWT_KEY")
+	setString(&c.Keys.JWTKeyID, "JWT_KEY_ID")
+	setString(&c.Keys.JWTPreviousKeys, "JWT_PREVIOUS_KEYS")
+	setString(&c.Keys.CertPrivateKey, "CERT_PRIVATE_KEY")
+	setString(&c.Keys.CertKeyID, "CERT_KEY_ID")
+	setString(&c.Keys.CertPublicKeys, "CERT_PUBLIC_KEYS")
+	setString(&c.Keys.CertLegacyPublicKey, "CERT_LEGACY_PUBLIC_KEY")
+	setString(&c.Keys.LinkSecret, "LINK_SIGNING_KEY")
+	setString(&c.Keys.LinkKeyID, "LINK_KEY_ID")
+	setString(&c.Keys.LinkPreviousKeys, "LINK_PREVIOUS_KEYS")
+
+	setString(&c.Export.Dir, "EXPORT_DIR")
+	setDuration(&c.Export.Retention, "EXPORT_RETENTION")
+	setDuration(&c.Export.LinkTTL, "EXPORT_LINK_TTL")
+	setInt(&c.Export.Workers, "EXPORT_WORKERS")
+	setDuration(&c.Export.StreamTimeout, "EXPORT_STREAM_TIMEOUT")
+
+	setString(&c.Certificate.AssetDir, "CERT_ASSET_DIR")
+
+	setString(&c.Log.Level, "LOG_LEVEL")
+	setString(&c.Log.Format, "LOG_FORMAT")
+	setDuration(&c.Log.SlowQuery, "LOG_SLOW_QUERY")
+
+	setString(&c.Tracing.Endpoint, "OTEL_EXPORTER_OTLP_ENDPOINT")
+	setString(&c.Tracing.ServiceName, "OTEL_SERVICE_NAME")
+	setFloat(&c.Tracing.SampleRatio, "TRACING_SAMPLE_RATIO")
+
+	return errs
+}
@@ -15,99 +15,147 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/users": {
-            "get": {
+        "/api/admin/staff/{id}": {
+            "patch": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieve a list of all users with optional filtering",
+                "description": "Move a staff member to the central team or to a faculty",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Get all users",
+                "summary": "Update staff scope",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Filter by name",
-                        "name": "name",
-                        "in": "query"
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Faculty or central team",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.AddStaffRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/domain.User"
-                            }
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "User is not a staff",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Rejected fields",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to fetch users",
+                        "description": "Failed to update staff",
                         "schema": {
                             "$ref": "#/definitions/domain.ErrorResponse"
                         }
                     }
                 }
-            },
-            "patch": {
+            }
+        },
+        "/api/users": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update a user by its ID",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "List users a page at a time, filtered and sorted in the database. Pass the nextCursor of a page as cursor to get the next one.",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Update Account Info",
+                "summary": "List users",
                 "parameters": [
                     {
-                        "description": "User data",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/domain.User"
-                        }
-                    }
-                ],
-                "responses": {
-                    "204": {
-                        "description": "No Content"
+                        "type": "string",
+                        "description": "Search the name and school, and the phone, email and UID for staff allowed to export students",
+                        "name": "q",
+                        "in": "query"
                     },
-                    "400": {
-                        "description": "Invalid input",
-                        "schema": {
-                            "$ref": "#/definitions/domain.ErrorResponse"
-                        }
+                    {
+                        "type": "string",
+                        "description": "Filter by name",
+                        "name": "name",
+                        "in": "query"
                     },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/domain.ErrorResponse"
-                        }
+                    {
+                        "type": "string",
+                        "description": "Filter by role",
+                        "name": "role",
+                        "in": "query"
                     },
-                    "403": {
-                        "description": "Forbidden",
+                    {
+                        "type": "string",
+                        "description": "registeredAt, lastEntered, name or id; prefix with - for descending (default -registeredAt)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "nextCursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 1 to 200 (default 50)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/domain.ErrorResponse"
+                            "$ref": "#/definitions/domain.UserPage"
                         }
                     },
-                    "404": {
-                        "description": "User not found",
+                    "400": {
+                        "description": "Invalid query",
                         "schema": {
                             "$ref": "#/definitions/domain.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to update user role",
+                        "description": "Failed to fetch users",
                         "schema": {
                             "$ref": "#/definitions/domain.ErrorResponse"
                         }
@@ -122,7 +170,10 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Add Staff By phone number",
+                "description": "Add Staff By phone number, either to the central team or to a faculty. Without a body the user joins the central team.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
@@ -134,6 +185,14 @@ const docTemplate = `{
                         "name": "phone",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Faculty or central team",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/domain.AddStaffRequest"
+                        }
                     }
                 ],
                 "responses": {
@@ -146,6 +205,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/domain.ErrorResponse"
                         }
                     },
+                    "422": {
+                        "description": "Rejected fields",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Failed to add staff",
                         "schema": {
@@ -493,7 +558,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieve a user by its ID",
+                "description": "Retrieve a user by its ID. Users get their own account; admins and staff allowed to export\nstudents get anyone's. Other staff get the user without phone, email and UID.",
                 "consumes": [
                     "application/json"
                 ],
@@ -517,6 +582,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/domain.User"
                         }
                     },
+                    "403": {
+                        "description": "Not the caller's account",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
                     "404": {
                         "description": "User not found",
                         "schema": {
@@ -587,7 +658,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update a user by its ID",
+                "description": "Update the caller's own profile; admins may update any user",
                 "consumes": [
                     "application/json"
                 ],
@@ -609,7 +680,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/domain.User"
+                            "$ref": "#/definitions/domain.UpdateUserRequest"
                         }
                     }
                 ],
@@ -641,6 +712,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/domain.ErrorResponse"
                         }
                     },
+                    "422": {
+                        "description": "Rejected fields",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Failed to update user",
                         "schema": {
@@ -652,14 +729,53 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "domain.AddStaffRequest": {
+            "type": "object",
+            "properties": {
+                "faculty": {
+                    "description": "required unless isCentralStaff",
+                    "type": "string"
+                },
+                "isCentralStaff": {
+                    "type": "boolean"
+                }
+            }
+        },
         "domain.ErrorResponse": {
             "type": "object",
             "properties": {
-                "error": {
+                "code": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.FieldError"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                },
+                "requestId": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.FieldError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "field": {
                     "type": "string"
                 },
                 "message": {
                     "type": "string"
+                },
+                "params": {
+                    "type": "object",
+                    "additionalProperties": true
                 }
             }
         },
@@ -698,6 +814,65 @@ const docTemplate = `{
                 }
             }
         },
+        "domain.UpdateUserRequest": {
+            "type": "object",
+            "properties": {
+                "birthDate": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "firstInterest": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "nickname": {
+                    "type": "string"
+                },
+                "objective": {
+                    "type": "string"
+                },
+                "otherSource": {
+                    "type": "string"
+                },
+                "otherStatus": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "province": {
+                    "type": "string"
+                },
+                "school": {
+                    "type": "string"
+                },
+                "secondInterest": {
+                    "type": "string"
+                },
+                "selectedSources": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "studentId": {
+                    "type": "string"
+                },
+                "thirdInterest": {
+                    "type": "string"
+                },
+                "year": {
+                    "type": "integer"
+                }
+            }
+        },
         "domain.User": {
             "type": "object",
             "properties": {
@@ -766,6 +941,25 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "domain.UserPage": {
+            "type": "object",
+            "properties": {
+                "nextCursor": {
+                    "description": "absent on the last page",
+                    "type": "string"
+                },
+                "total": {
+                    "description": "users matching the filter on all pages",
+                    "type": "integer"
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.User"
+                    }
+                }
+            }
         }
     }
 }`
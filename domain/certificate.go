@@ -0,0 +1,78 @@
+package domain
+
+import "time"
+
+// CertificateTemplate describes how the participation certificate of one event is rendered.
+// Title and Body may contain the placeholders {name}, {event} and {date}.
+type CertificateTemplate struct {
+	ID             int       `json:"id" gorm:"primaryKey autoIncrement"`
+	Event          string    `json:"event" gorm:"not null;uniqueIndex"` // short key used in URLs, e.g. "oph-67"
+	EventName      string    `json:"eventName" gorm:"not null"`
+	EventDate      time.Time `json:"eventDate"`
+	Title          string    `json:"title" gorm:"not null"`
+	Body           string    `json:"body"`
+	FontFile       string    `json:"fontFile" gorm:"not null"` // TrueType font with Thai glyphs, relative to the asset directory
+	BackgroundFile *string   `json:"backgroundFile"`           // optional PNG or JPEG drawn over the whole page, relative like FontFile
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// CertificateFile is a rendered certificate ready to be sent to the holder
+type CertificateFile struct {
+	FileName string
+	PDF      []byte
+}
+
+// Certificate records an issued certificate. A holder has at most one unrevoked certificate per event;
+// downloading it again re-renders the same token.
+type Certificate struct {
+	Serial           string     `json:"serial" gorm:"primaryKey"`
+	UserID           string     `json:"userId" gorm:"not null;uniqueIndex:idx_active_certificate,where:revoked_at IS NULL"`
+	UID              string     `json:"uid"`
+	HolderName       string     `json:"holderName" gorm:"not null"`
+	Event            string     `json:"event" gorm:"not null;uniqueIndex:idx_active_certificate,where:revoked_at IS NULL"`
+	KeyID            string     `json:"keyId" gorm:"not null"`
+	Token            string     `json:"-" gorm:"not null"`
+	IssuedAt         time.Time  `json:"issuedAt" gorm:"not null"`
+	RevokedAt        *time.Time `json:"revokedAt" gorm:"index"`
+	RevocationReason *string    `json:"revocationReason"`
+	RevokedBy        *string    `json:"revokedBy"`
+
+	User User `gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+// CertificateClaims is the message signed into a certificate token
+type CertificateClaims struct {
+	Serial   string `json:"serial,omitempty"`
+	UserID   string `json:"userId,omitempty"`
+	UID      string `json:"uid,omitempty"`
+	Name     string `json:"name"`
+	Event    string `json:"event,omitempty"`
+	IssuedAt int64  `json:"iat,omitempty"` // unix seconds
+}
+
+// CertificateVerification is the public result of checking a certificate token
+type CertificateVerification struct {
+	Valid     bool       `json:"valid"`
+	Revoked   bool       `json:"revoked,omitempty"`
+	Legacy    bool       `json:"legacy,omitempty"`    // a name-only token from /api/users/certToken: only the name is vouched for
+	RevokedAt *time.Time `json:"revokedAt,omitempty"` // the reason is only shown to admins, in the certificate list
+	Serial    string     `json:"serial,omitempty"`
+	KeyID     string     `json:"keyId,omitempty"`
+	Name      string     `json:"name,omitempty"`
+	Event     string     `json:"event,omitempty"`
+	EventName string     `json:"eventName,omitempty"`
+	EventDate *time.Time `json:"eventDate,omitempty"`
+	IssuedAt  *time.Time `json:"issuedAt,omitempty"`
+}
+
+// RevokeCertificateRequest is the body of a revocation
+type RevokeCertificateRequest struct {
+	Reason string `json:"reason"`
+}
+
+// CertificateFilter narrows the list of issued certificates
+type CertificateFilter struct {
+	Event   string
+	Revoked *bool
+}
@@ -32,16 +32,43 @@ type StatusCount struct {
 	Count  int    `json:"count"`
 }
 
-type StudentProfile struct {
-	ID             string `json:"id"`
-	Name           string `json:"name"`
-	Email          string `json:"email"`
-	Phone          string `json:"phone"`
-	FirstInterest  string `json:"first_interest"`
-	SecondInterest string `json:"second_interest"`
-	ThirdInterest  string `json:"third_interest"`
-}
-
 type AttendedCount struct {
 	Count int `json:"count"`
 }
+
+type ProvinceCount struct {
+	Province   string `json:"province"`
+	Code       string `json:"code"` // ISO 3166-2:TH code, empty when the name is not recognised
+	Registered int    `json:"registered"`
+	Attended   int    `json:"attended"`
+}
+
+type SchoolCount struct {
+	School     string `json:"school"`
+	Registered int    `json:"registered"`
+	Attended   int    `json:"attended"`
+}
+
+// OtherGroup is the label used when the tail of a top-N breakdown is folded together
+const OtherGroup = "other"
+
+// FacultyListBasis selects how students are tied to a faculty in a faculty export
+type FacultyListBasis string
+
+const (
+	ByInterest FacultyListBasis = "interest" // listed the faculty as first, second or third interest
+	ByVisit    FacultyListBasis = "visit"    // was scanned at the faculty's booth
+)
+
+// StudentExportRow is a student with the derived columns available to exports
+type StudentExportRow struct {
+	User             `gorm:"embedded"`
+	FacultiesVisited *string // comma-separated faculties the student was scanned at
+	Evaluated        bool    // whether the student submitted the event evaluation
+}
+
+// StudentExportFilter restricts an export to the students tied to one faculty. The zero value exports everyone.
+type StudentExportFilter struct {
+	Faculty string
+	By      FacultyListBasis
+}
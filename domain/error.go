@@ -1,15 +1,206 @@
 package domain
 
-import "errors"
+import (
+	"fmt"
+	"strings"
+)
 
-// ErrorResponse represents a basic error structure
+// ErrorResponse is the body of every error response
 type ErrorResponse struct {
-	Error   string  `json:"error"`
-	Message *string `json:"message,omitempty"`
+	Code      string       `json:"code"`                // stable identifier for clients to match on, e.g. user_not_found
+	Message   string       `json:"message"`             // human readable, may change
+	Details   []FieldError `json:"details,omitempty"`   // rejected fields of validation errors
+	RequestID string       `json:"requestId,omitempty"` // quote it when reporting a problem
 }
 
-var ErrUserAlreadyEntered = errors.New("user has already entered")
-var ErrUserNotFound = errors.New("user not found")
-var ErrUserAlreadyStaff = errors.New("user is already a staff")
-var ErrUserNotCentralStaff = errors.New("user is not a central staff")
-var ErrStudentEvaluationAlreadyExists = errors.New("evaluation for this student already exists")
+// Kind is the class of an error, which decides the HTTP status it is answered with
+type Kind int
+
+const (
+	KindInternal     Kind = iota // 500, the cause is logged and never shown
+	KindInvalidInput             // 400
+	KindUnauthorized             // 401
+	KindForbidden                // 403
+	KindNotFound                 // 404
+	KindConflict                 // 409
+	KindValidation               // 422, with the rejected fields as details
+	KindUnavailable              // 503
+)
+
+// Error is an error meant to be shown to clients. Usecases return the sentinels below, optionally
+// with details or a cause; the error handler turns them into an ErrorResponse.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Details []FieldError
+	Err     error // underlying cause, for logs only
+}
+
+// NewError returns an error of the given kind with a stable code and a client-facing message
+func NewError(kind Kind, code string, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches errors with the same code, so a sentinel still matches after WithDetails or Wrap
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithDetails returns a copy of the error carrying field details
+func (e *Error) WithDetails(details ...FieldError) *Error {
+	copied := *e
+	copied.Details = append(append([]FieldError(nil), e.Details...), details...)
+	return &copied
+}
+
+// WithMessage returns a copy of the error with another client-facing message
+func (e *Error) WithMessage(message string) *Error {
+	copied := *e
+	copied.Message = message
+	return &copied
+}
+
+// Wrap returns a copy of the error recording its cause
+func (e *Error) Wrap(err error) *Error {
+	copied := *e
+	copied.Err = err
+	return &copied
+}
+
+// InvalidInput reports a malformed request, such as a body that is not JSON or a bad path parameter
+func InvalidInput(message string) *Error {
+	return NewError(KindInvalidInput, "invalid_input", message)
+}
+
+// FieldError describes why a single input field was rejected
+type FieldError struct {
+	Field   string                 `json:"field"`
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Params  map[string]interface{} `json:"params,omitempty"` // e.g. min and max for out_of_range
+}
+
+// Field error codes, stable for clients to match on
+const (
+	FieldRequired      = "required"
+	FieldInvalidType   = "invalid_type"
+	FieldOutOfRange    = "out_of_range"
+	FieldInvalidOption = "invalid_option"
+	FieldUnknown       = "unknown_field"
+	FieldDuplicate     = "duplicate"
+	FieldReserved      = "reserved"
+	FieldInvalid       = "invalid"
+)
+
+// ValidationError collects every rejected field of an input
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = f.Field + ": " + f.Message
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// Add records a rejected field
+func (e *ValidationError) Add(field string, code string, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Code: code, Message: message})
+}
+
+// AddOutOfRange records a value outside [min, max]
+func (e *ValidationError) AddOutOfRange(field string, min int, max int) {
+	e.Fields = append(e.Fields, FieldError{
+		Field:   field,
+		Code:    FieldOutOfRange,
+		Message: fmt.Sprintf("must be between %d and %d", min, max),
+		Params:  map[string]interface{}{"min": min, "max": max},
+	})
+}
+
+// AsError converts the rejected fields into an error with the validation_failed code
+func (e *ValidationError) AsError() *Error {
+	return ErrValidation.WithDetails(e.Fields...)
+}
+
+// OrNil returns the error only if a field was rejected
+func (e *ValidationError) OrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+var (
+	ErrValidation   = NewError(KindValidation, "validation_failed", "Invalid input")
+	ErrUnauthorized = NewError(KindUnauthorized, "unauthorized", "Unauthorized")
+	ErrInvalidToken = NewError(KindUnauthorized, "invalid_token", "Invalid or expired token")
+	ErrForbidden    = NewError(KindForbidden, "forbidden", "Access forbidden: insufficient permissions")
+	ErrNotFound     = NewError(KindNotFound, "not_found", "Not found")
+	ErrConflict     = NewError(KindConflict, "conflict", "Conflicts with existing data")
+	ErrTimeout      = NewError(KindUnavailable, "timeout", "Request timed out")
+	ErrInternal     = NewError(KindInternal, "internal", "Internal server error")
+)
+
+var (
+	ErrUserAlreadyEntered  = NewError(KindConflict, "user_already_entered", "User has already entered")
+	ErrUserNotFound        = NewError(KindNotFound, "user_not_found", "User not found")
+	ErrUserAlreadyStaff    = NewError(KindConflict, "user_already_staff", "User is already a staff")
+	ErrUserNotStaff        = NewError(KindConflict, "user_not_staff", "User is not a staff")
+	ErrUserNotCentralStaff = NewError(KindForbidden, "user_not_central_staff", "User is not a central staff")
+	ErrInvalidUserSort     = NewError(KindInvalidInput, "invalid_sort", "sort must be registeredAt, lastEntered, name or id, optionally prefixed with -")
+	ErrInvalidCursor       = NewError(KindInvalidInput, "invalid_cursor", "Invalid cursor")
+)
+
+var (
+	ErrStudentEvaluationAlreadyExists = NewError(KindConflict, "evaluation_already_exists", "Student evaluation already exists")
+	ErrStudentEvaluationNotFound      = NewError(KindNotFound, "evaluation_not_found", "Student evaluation not found")
+	ErrInvalidEvaluationGroupBy       = NewError(KindInvalidInput, "invalid_group_by", "by must be status or faculty")
+	ErrStudentNotAttended             = NewError(KindForbidden, "student_not_attended", "Student has not entered the event")
+	ErrNoActiveQuestionnaire          = NewError(KindUnavailable, "no_active_questionnaire", "No active questionnaire")
+)
+
+var (
+	ErrInvalidFacultyListBasis = NewError(KindInvalidInput, "invalid_faculty_list_basis", "by must be interest or visit")
+	ErrUnknownExportColumn     = NewError(KindInvalidInput, "unknown_export_column", "Unknown export column")
+	ErrDuplicateExportColumn   = NewError(KindInvalidInput, "duplicate_export_column", "Export column listed more than once")
+	ErrUnknownExportFormat     = NewError(KindInvalidInput, "unknown_export_format", "Unknown export format")
+	ErrExportJobNotFound       = NewError(KindNotFound, "export_job_not_found", "Export job not found")
+	ErrExportJobNotReady       = NewError(KindNotFound, "export_not_ready", "Export not available")
+	ErrInvalidDownloadLink     = NewError(KindForbidden, "invalid_download_link", "Invalid or expired download link")
+	ErrNoFacultyAssigned       = NewError(KindForbidden, "no_faculty_assigned", "No faculty assigned to this staff")
+	ErrOtherFacultyData        = NewError(KindForbidden, "other_faculty_data", "Access forbidden: other faculty's data")
+	ErrFacultyRequired         = NewError(KindInvalidInput, "faculty_required", "faculty is required")
+)
+
+var (
+	ErrQuestionnaireNotFound  = NewError(KindNotFound, "questionnaire_not_found", "Questionnaire not found")
+	ErrInvalidQuestionnaire   = NewError(KindValidation, "invalid_questionnaire", "Invalid questionnaire")
+	ErrAnswerNotFound         = NewError(KindNotFound, "answer_not_found", "Answer not found")
+	ErrSurveyNotEligible      = NewError(KindForbidden, "survey_not_eligible", "Only students scanned at this faculty can answer its survey")
+	ErrSurveyAlreadySubmitted = NewError(KindConflict, "survey_already_submitted", "Survey already submitted")
+
+	ErrQuestionnaireVersionConflict = NewError(KindConflict, "questionnaire_version_conflict", "Another version was created at the same time, please try again")
+)
+
+var (
+	ErrCertificateTemplateNotFound = NewError(KindNotFound, "certificate_template_not_found", "No certificate for this event")
+	ErrEvaluationRequired          = NewError(KindForbidden, "evaluation_required", "Complete the evaluation to get a certificate")
+	ErrCertificateNotFound         = NewError(KindNotFound, "certificate_not_found", "Certificate not found")
+	ErrCertificateAlreadyRevoked   = NewError(KindConflict, "certificate_already_revoked", "Certificate already revoked")
+)
@@ -6,7 +6,7 @@ import (
 
 type StudentEvaluation struct {
 	ID                                int             `json:"id" gorm:"primaryKey autoIncrement"`
-	StudentId                         string          `json:"studentId" gorm:"not null"`
+	StudentId                         string          `json:"studentId" gorm:"not null;uniqueIndex"`
 	NewSources                        *pq.StringArray `json:"newSources" gorm:"type:text[]"`
 	OverallActivity                   int             `json:"overallActivity"`
 	InterestActivity                  int             `json:"interestActivity"`
@@ -23,5 +23,44 @@ type StudentEvaluation struct {
 	DesignBeautyRating                int             `json:"designBeautyRating"`
 	WebsiteImprovementSuggestions     *string         `json:"websiteImprovementSuggestions"`
 
-	Student User `gorm:"foreignKey:StudentId;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	// The columns above are kept for version 1 of the form; answers are the source of truth
+	QuestionnaireID *int               `json:"questionnaireId"`
+	Answers         []EvaluationAnswer `json:"answers" gorm:"foreignKey:EvaluationID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+
+	Questionnaire *Questionnaire `gorm:"foreignKey:QuestionnaireID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT" json:"-"`
+	Student       User           `gorm:"foreignKey:StudentId;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+// LegacyColumns points at the fixed columns of version 1 of the form, by the exact key of their question
+type LegacyColumns struct {
+	Ratings map[string]*int
+	Texts   map[string]**string
+	Choices map[string]**pq.StringArray
+}
+
+// LegacyColumns returns the fixed columns of the evaluation by question key
+func (e *StudentEvaluation) LegacyColumns() LegacyColumns {
+	return LegacyColumns{
+		Ratings: map[string]*int{
+			"overallActivity":                   &e.OverallActivity,
+			"interestActivity":                  &e.InterestActivity,
+			"receivedFacultyInfoClearly":        &e.ReceivedFacultyInfoClearly,
+			"wouldRecommendCUOpenHouseNextTime": &e.WouldRecommendCUOpenHouseNextTime,
+			"activityDiversity":                 &e.ActivityDiversity,
+			"perceivedCrowdDensity":             &e.PerceivedCrowdDensity,
+			"hasFullBoothAccess":                &e.HasFullBoothAccess,
+			"facilityConvenienceRating":         &e.FacilityConvenienceRating,
+			"campusNavigationRating":            &e.CampusNavigationRating,
+			"hesitationLevelAfterDisaster":      &e.HesitationLevelAfterDisaster,
+			"lineOASignupRating":                &e.LineOASignupRating,
+			"designBeautyRating":                &e.DesignBeautyRating,
+		},
+		Texts: map[string]**string{
+			"favoriteBooth":                 &e.FavoriteBooth,
+			"websiteImprovementSuggestions": &e.WebsiteImprovementSuggestions,
+		},
+		Choices: map[string]**pq.StringArray{
+			"newSources": &e.NewSources,
+		},
+	}
 }
@@ -0,0 +1,53 @@
+package domain
+
+// EvaluationGroupBy selects how evaluation analytics are broken down
+type EvaluationGroupBy string
+
+const (
+	GroupByNone    EvaluationGroupBy = ""
+	GroupByStatus  EvaluationGroupBy = "status"  // the student's status (ม.ปลาย, ปวช., ...)
+	GroupByFaculty EvaluationGroupBy = "faculty" // faculties the student was scanned at
+)
+
+// NPSQuestion is the rating question used for the NPS-style score
+const NPSQuestion = "wouldRecommendCUOpenHouseNextTime"
+
+// EvaluationScoreCount is how many evaluations in a group gave a question a score
+type EvaluationScoreCount struct {
+	Group    string
+	Question string
+	Score    int
+	Count    int
+}
+
+// EvaluationGroupCount is how many evaluations fall in a group
+type EvaluationGroupCount struct {
+	Group string
+	Count int
+}
+
+type QuestionStats struct {
+	Question     string      `json:"question"`
+	Responses    int         `json:"responses"`
+	Mean         float64     `json:"mean"`
+	Median       float64     `json:"median"`
+	Distribution map[int]int `json:"distribution"` // score -> count
+}
+
+// NPSStats rescales ratings to 0-10 and treats 9-10 as promoters, 7-8 as passives and 0-6 as detractors,
+// so on the default 1-5 scale a 5 is a promoter, 4 passive and 1-3 a detractor.
+// Score ranges from -100 to 100.
+type NPSStats struct {
+	Responses  int     `json:"responses"`
+	Promoters  int     `json:"promoters"`
+	Passives   int     `json:"passives"`
+	Detractors int     `json:"detractors"`
+	Score      float64 `json:"score"`
+}
+
+type EvaluationAnalytics struct {
+	Group     string          `json:"group,omitempty"`
+	Responses int             `json:"responses"`
+	Questions []QuestionStats `json:"questions"`
+	NPS       NPSStats        `json:"nps"`
+}
@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+type ExportJobStatus string
+
+const (
+	ExportJobPending ExportJobStatus = "pending"
+	ExportJobRunning ExportJobStatus = "running"
+	ExportJobDone    ExportJobStatus = "done"
+	ExportJobFailed  ExportJobStatus = "failed"
+	ExportJobExpired ExportJobStatus = "expired" // file removed after the retention period
+)
+
+// ExportJob is a student export produced in the background and kept on local storage until it expires
+type ExportJob struct {
+	ID          string           `json:"id" gorm:"primaryKey"`
+	RequestedBy string           `json:"requestedBy" gorm:"not null"`
+	Status      ExportJobStatus  `json:"status" gorm:"not null"`
+	Format      string           `json:"format"`
+	Columns     string           `json:"columns"` // comma-separated column keys, empty for the defaults
+	Faculty     string           `json:"faculty,omitempty"`
+	By          FacultyListBasis `json:"by,omitempty"`
+	Error       *string          `json:"error,omitempty"`
+	FilePath    string           `json:"-"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	CompletedAt *time.Time       `json:"completedAt"`
+	ExpiresAt   *time.Time       `json:"expiresAt"` // when the file is deleted
+	HeartbeatAt *time.Time       `json:"-"`         // last renewal of the running worker's lease
+
+	Requester User `gorm:"foreignKey:RequestedBy;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+// ExportJobRequest is the body of an export job submission
+type ExportJobRequest struct {
+	Format  string `json:"format"`
+	Columns string `json:"columns"`
+	Faculty string `json:"faculty"`
+	By      string `json:"by"`
+}
+
+// ExportJobResponse is an export job with a signed download link once the file is ready
+type ExportJobResponse struct {
+	ExportJob
+	DownloadURL *string `json:"downloadUrl,omitempty"`
+}
@@ -0,0 +1,54 @@
+package domain
+
+import "github.com/lib/pq"
+
+// Default free-text questions of the version 1 form
+const (
+	SuggestionQuestion    = "websiteImprovementSuggestions"
+	FavoriteBoothQuestion = "favoriteBooth"
+)
+
+// Booth is a catalog entry that free-text booth answers are grouped under
+type Booth struct {
+	ID      int             `json:"id" gorm:"primaryKey autoIncrement"`
+	Name    string          `json:"name" gorm:"not null;unique"`
+	Faculty *string         `json:"faculty"`
+	Aliases *pq.StringArray `json:"aliases" gorm:"type:text[]"` // other spellings, e.g. "วิศวะ" for "วิศวกรรมศาสตร์"
+}
+
+// TextAnswer is a free-text answer with the tags admins gave it
+type TextAnswer struct {
+	AnswerID     int            `json:"answerId"`
+	EvaluationID int            `json:"evaluationId"`
+	StudentID    string         `json:"studentId"`
+	Question     string         `json:"question"`
+	Text         string         `json:"text"`
+	Tags         pq.StringArray `json:"tags" gorm:"type:text[]"`
+}
+
+type TextAnswerPage struct {
+	Total   int64        `json:"total"`
+	Answers []TextAnswer `json:"answers"`
+}
+
+type SpellingCount struct {
+	Text  string `json:"text"`
+	Count int    `json:"count"`
+}
+
+// BoothGroup is every spelling that was matched to one booth
+type BoothGroup struct {
+	BoothID  int             `json:"boothId"`
+	Name     string          `json:"name"`
+	Count    int             `json:"count"`
+	Variants []SpellingCount `json:"variants"`
+}
+
+type BoothGroups struct {
+	Booths    []BoothGroup    `json:"booths"`
+	Unmatched []SpellingCount `json:"unmatched"`
+}
+
+type TagRequest struct {
+	Tags []string `json:"tags"`
+}
@@ -0,0 +1,12 @@
+package domain
+
+const (
+	HealthOK          = "ok"
+	HealthUnavailable = "unavailable"
+)
+
+// HealthResponse is returned by /healthz and /readyz. Checks maps each dependency to "ok" or "unavailable".
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
@@ -0,0 +1,51 @@
+package domain
+
+// Permission names an action a user may perform, granted through their role
+type Permission string
+
+const (
+	PermissionViewDashboard  Permission = "dashboard:view"   // aggregate statistics only
+	PermissionExportStudents Permission = "dashboard:export" // student PII (name, email, phone)
+	// PermissionExportFacultyStudents allows exporting students tied to a faculty.
+	// Faculty staff are always limited to their own faculty.
+	PermissionExportFacultyStudents Permission = "dashboard:export:faculty"
+	// PermissionManageFacultySurveys allows managing a faculty's own questionnaires and reading their results.
+	// Faculty staff are always limited to their own faculty.
+	PermissionManageFacultySurveys Permission = "survey:manage"
+)
+
+var rolePermissions = map[Role][]Permission{
+	Admin: {PermissionViewDashboard, PermissionExportStudents, PermissionExportFacultyStudents, PermissionManageFacultySurveys},
+	Staff: {PermissionViewDashboard, PermissionExportStudents, PermissionExportFacultyStudents, PermissionManageFacultySurveys},
+}
+
+// IsFacultyStaff reports whether the user is staff belonging to a single faculty rather than the central team.
+func (u *User) IsFacultyStaff() bool {
+	return u.Role == Staff && (u.IsCentralStaff == nil || !*u.IsCentralStaff)
+}
+
+// FacultyScope returns the faculty the user's dashboard data is restricted to.
+// The second value is false when the user may see every faculty.
+func (u *User) FacultyScope() (string, bool) {
+	if !u.IsFacultyStaff() {
+		return "", false
+	}
+	if u.Faculty == nil {
+		return "", true
+	}
+	return *u.Faculty, true
+}
+
+// HasPermission reports whether the user's role grants the permission.
+// Faculty staff cannot export PII for the whole event.
+func (u *User) HasPermission(permission Permission) bool {
+	if permission == PermissionExportStudents && u.IsFacultyStaff() {
+		return false
+	}
+	for _, p := range rolePermissions[u.Role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
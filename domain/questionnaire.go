@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type QuestionType string
+
+const (
+	QuestionRating       QuestionType = "rating"        // integer between ScaleMin and ScaleMax
+	QuestionText         QuestionType = "text"          // free text
+	QuestionSingleChoice QuestionType = "single_choice" // one of Options
+	QuestionMultiChoice  QuestionType = "multi_choice"  // any of Options, or free values when Options is empty
+)
+
+// Questionnaire is one version of the evaluation form, typically one per event. Versions are never edited once created,
+// since submitted answers refer to them; a new version is created and activated instead.
+// A questionnaire with a Faculty is that faculty's own survey; each faculty and the event have their own active version
+// and number their versions from 1.
+type Questionnaire struct {
+	ID                int        `json:"id" gorm:"primaryKey autoIncrement"`
+	Version           int        `json:"version" gorm:"not null"` // unique per owner, see migration 0009
+	Title             string     `json:"title"`
+	Faculty           *string    `json:"faculty,omitempty" gorm:"index"` // nil for the event-wide evaluation
+	IsActive          bool       `json:"isActive" gorm:"not null;default:false"`
+	RequireAttendance bool       `json:"requireAttendance" gorm:"not null;default:false"` // only students scanned at the gate or a booth may answer
+	CreatedAt         time.Time  `json:"createdAt"`
+	Questions         []Question `json:"questions" gorm:"foreignKey:QuestionnaireID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+type Question struct {
+	ID              int             `json:"id" gorm:"primaryKey autoIncrement"`
+	QuestionnaireID int             `json:"-" gorm:"not null;uniqueIndex:idx_question_key"`
+	Key             string          `json:"key" gorm:"not null;uniqueIndex:idx_question_key"`
+	Type            QuestionType    `json:"type" gorm:"not null"`
+	Position        int             `json:"position"`
+	Required        bool            `json:"required"`
+	LabelTH         string          `json:"labelTh"`
+	LabelEN         string          `json:"labelEn"`
+	ScaleMin        *int            `json:"scaleMin,omitempty"`
+	ScaleMax        *int            `json:"scaleMax,omitempty"`
+	Options         *pq.StringArray `json:"options,omitempty" gorm:"type:text[]"`
+}
+
+// QuestionnaireSettingsRequest changes the settings of a questionnaire that may be edited after creation
+type QuestionnaireSettingsRequest struct {
+	RequireAttendance *bool `json:"requireAttendance"`
+}
+
+// EvaluationAnswer is the answer to one question. Only the field matching the question type is set.
+type EvaluationAnswer struct {
+	ID           int             `json:"-" gorm:"primaryKey autoIncrement"`
+	EvaluationID int             `json:"-" gorm:"not null;index"`
+	QuestionID   int             `json:"-" gorm:"not null"`
+	QuestionKey  string          `json:"question" gorm:"not null"`
+	Score        *int            `json:"score,omitempty"`
+	Text         *string         `json:"text,omitempty"`
+	Choices      *pq.StringArray `json:"choices,omitempty" gorm:"type:text[]"`
+	Tags         *pq.StringArray `json:"tags,omitempty" gorm:"type:text[]"` // set by admins reviewing free-text answers
+
+	Question Question `json:"-" gorm:"foreignKey:QuestionID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+}
+
+// ReservedQuestionKeys cannot be used as question keys since they clash with evaluation fields
+var ReservedQuestionKeys = []string{"id", "studentId", "questionnaireId", "answers"}
+
+// DefaultQuestionnaire is version 1 of the form, matching the columns of StudentEvaluation
+// that existed before questionnaires were configurable.
+func DefaultQuestionnaire() Questionnaire {
+	rating := func(key, th, en string, position int) Question {
+		low, high := 1, 5
+		return Question{Key: key, Type: QuestionRating, Position: position, Required: true, LabelTH: th, LabelEN: en, ScaleMin: &low, ScaleMax: &high}
+	}
+	return Questionnaire{
+		Version:           1,
+		Title:             "CU Open House evaluation",
+		IsActive:          true,
+		RequireAttendance: true,
+		Questions: []Question{
+			{Key: "newSources", Type: QuestionMultiChoice, Position: 1, LabelTH: "ได้รับข่าวสารงานจากช่องทางใดบ้าง", LabelEN: "Where did you hear about the event?"},
+			rating("overallActivity", "ความพึงพอใจต่อกิจกรรมโดยรวม", "Overall satisfaction with the activities", 2),
+			rating("interestActivity", "กิจกรรมมีความน่าสนใจ", "The activities were interesting", 3),
+			rating("receivedFacultyInfoClearly", "ได้รับข้อมูลของคณะอย่างชัดเจน", "I received clear information about the faculties", 4),
+			rating("wouldRecommendCUOpenHouseNextTime", "จะแนะนำ CU Open House ให้ผู้อื่นในครั้งถัดไป", "I would recommend CU Open House next time", 5),
+			{Key: "favoriteBooth", Type: QuestionText, Position: 6, LabelTH: "บูธที่ชื่นชอบ", LabelEN: "Favourite booth"},
+			rating("activityDiversity", "ความหลากหลายของกิจกรรม", "Variety of activities", 7),
+			rating("perceivedCrowdDensity", "ความหนาแน่นของผู้เข้าร่วมงาน", "Crowd density", 8),
+			rating("hasFullBoothAccess", "สามารถเข้าชมบูธได้ครบตามต้องการ", "I could visit every booth I wanted", 9),
+			rating("facilityConvenienceRating", "ความสะดวกของสิ่งอำนวยความสะดวก", "Convenience of facilities", 10),
+			rating("campusNavigationRating", "ความสะดวกในการเดินทางภายในมหาวิทยาลัย", "Ease of getting around campus", 11),
+			rating("hesitationLevelAfterDisaster", "ความลังเลในการเข้าร่วมงานหลังเหตุภัยพิบัติ", "Hesitation to attend after the disaster", 12),
+			rating("lineOASignupRating", "ความสะดวกในการลงทะเบียนผ่าน LINE OA", "Ease of signing up via LINE OA", 13),
+			rating("designBeautyRating", "ความสวยงามของการออกแบบงาน", "Design and look of the event", 14),
+			{Key: "websiteImprovementSuggestions", Type: QuestionText, Position: 15, LabelTH: "ข้อเสนอแนะเพื่อปรับปรุงเว็บไซต์", LabelEN: "Suggestions to improve the website"},
+		},
+	}
+}
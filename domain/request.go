@@ -1,5 +1,14 @@
 package domain
 
+import "encoding/json"
+
+// RoleRequest is the body of PATCH /api/users/role/{id}
 type RoleRequest struct {
-	Role string `json:"role"`
+	Role string `json:"role" form:"role" validate:"required,oneof=member student staff admin"`
+}
+
+// EvaluationRequest is the body of evaluation and faculty survey submissions, with the answers keyed
+// by question key. Each answer is checked against its questionnaire by the usecase.
+type EvaluationRequest struct {
+	Answers map[string]json.RawMessage `json:"answers" validate:"required"`
 }
@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// FacultySurveyResponse is a student's answers to a faculty's own questionnaire
+type FacultySurveyResponse struct {
+	ID              int                   `json:"id" gorm:"primaryKey autoIncrement"`
+	StudentId       string                `json:"studentId" gorm:"not null;uniqueIndex:idx_survey_response"`
+	QuestionnaireID int                   `json:"questionnaireId" gorm:"not null;uniqueIndex:idx_survey_response"`
+	Faculty         string                `json:"faculty" gorm:"not null;index"`
+	CreatedAt       time.Time             `json:"createdAt"`
+	Answers         []FacultySurveyAnswer `json:"answers" gorm:"foreignKey:ResponseID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+
+	Questionnaire Questionnaire `gorm:"foreignKey:QuestionnaireID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT" json:"-"`
+	Student       User          `gorm:"foreignKey:StudentId;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+// FacultySurveyAnswer is the answer to one question of a faculty survey. Only the field matching the question type is set.
+type FacultySurveyAnswer struct {
+	ID          int             `json:"-" gorm:"primaryKey autoIncrement"`
+	ResponseID  int             `json:"-" gorm:"not null;index"`
+	QuestionID  int             `json:"-" gorm:"not null"`
+	QuestionKey string          `json:"question" gorm:"not null"`
+	Score       *int            `json:"score,omitempty"`
+	Text        *string         `json:"text,omitempty"`
+	Choices     *pq.StringArray `json:"choices,omitempty" gorm:"type:text[]"`
+
+	Question Question `json:"-" gorm:"foreignKey:QuestionID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+}
+
+// PendingSurvey is a faculty survey the student may still answer
+type PendingSurvey struct {
+	Faculty       string        `json:"faculty"`
+	Questionnaire Questionnaire `json:"questionnaire"`
+}
+
+// FacultySurveyResults summarises the responses to one version of a faculty survey
+type FacultySurveyResults struct {
+	QuestionnaireID int                     `json:"questionnaireId"`
+	Version         int                     `json:"version"`
+	Title           string                  `json:"title"`
+	Faculty         string                  `json:"faculty"`
+	Responses       int                     `json:"responses"`
+	Questions       []QuestionStats         `json:"questions"` // rating questions only
+	Answers         []FacultySurveyResponse `json:"answers"`
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"log/slog"
 	"time"
 
 	"github.com/lib/pq"
@@ -19,7 +20,7 @@ type User struct {
 	ID              string          `json:"id" gorm:"primaryKey"`
 	UID             string          `json:"uid" gorm:"unique"`
 	Name            string          `json:"name"`
-	Role            Role            `json:"role"`
+	Role            Role            `json:"role" gorm:"index:idx_users_role_registered_at,priority:1"`
 	Email           string          `json:"email"`
 	Phone           string          `json:"phone" gorm:"unique"` // Make phone unique
 	BirthDate       *time.Time      `json:"birthDate"`
@@ -33,8 +34,8 @@ type User struct {
 	SecondInterest  *string         `json:"secondInterest"`
 	ThirdInterest   *string         `json:"thirdInterest"`
 	Objective       *string         `json:"objective"`
-	RegisteredAt    *time.Time      `json:"registerAt"`
-	LastEntered     *time.Time      `json:"lastEntered"` // Timestamp for the last QR scan
+	RegisteredAt    *time.Time      `json:"registerAt" gorm:"index:idx_users_role_registered_at,priority:2"`
+	LastEntered     *time.Time      `json:"lastEntered" gorm:"index:idx_users_last_entered,where:last_entered IS NOT NULL"` // Timestamp for the last QR scan
 
 	// For staff/admin only
 	Faculty        *string `json:"faculty"`
@@ -46,10 +47,19 @@ type User struct {
 
 type StudentTransaction struct {
 	ID                    string    `json:"id" gorm:"primaryKey"`
-	StudentRegistrationID string    `json:"studentId"` // Foreign key index
-	Faculty               string    `json:"faculty"`
-	RegisteredAt          time.Time `json:"registeredAt"`
+	StudentRegistrationID string    `json:"studentId" gorm:"index:idx_student_transactions_student_faculty,priority:1"` // Foreign key index
+	Faculty               string    `json:"faculty" gorm:"index:idx_student_transactions_student_faculty,priority:2"`
+	RegisteredAt          time.Time `json:"registeredAt" gorm:"index"`
 
 	// Relationship
 	Student User `gorm:"foreignKey:StudentRegistrationID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 }
+
+// LogValue keeps personal data such as name, phone and email out of logs when a user is logged. The UID is
+// left out too, as it is printed on the user's certificates and identifies them outside the system.
+func (u User) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("id", u.ID),
+		slog.String("role", string(u.Role)),
+	)
+}
@@ -0,0 +1,196 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// UserSort is a field users can be listed by
+type UserSort string
+
+const (
+	UserSortRegisteredAt UserSort = "registeredAt"
+	UserSortLastEntered  UserSort = "lastEntered"
+	UserSortName         UserSort = "name"
+	UserSortID           UserSort = "id"
+)
+
+// UserFilter selects the users of a listing. Empty fields match every user.
+type UserFilter struct {
+	Search string // case-insensitive part of the name or school, and of the contacts if SearchContacts is set
+	Name   string // case-insensitive part of the name only
+	Role   Role
+
+	// SearchContacts extends Search to the phone, email and UID. Only callers allowed to export
+	// students may set it, so faculty staff cannot look up a student by phone or email.
+	SearchContacts bool
+}
+
+// UserOrder is the order of a listing. Ties are broken by ID and users without a value come last.
+type UserOrder struct {
+	Sort UserSort
+	Desc bool
+}
+
+// ParseUserOrder parses a sort parameter such as "name" or "-registeredAt", where "-" means
+// descending. An empty parameter lists the newest registrations first.
+func ParseUserOrder(sort string) (UserOrder, error) {
+	if sort == "" {
+		return UserOrder{Sort: UserSortRegisteredAt, Desc: true}, nil
+	}
+	order := UserOrder{Sort: UserSort(strings.TrimPrefix(sort, "-")), Desc: strings.HasPrefix(sort, "-")}
+	switch order.Sort {
+	case UserSortRegisteredAt, UserSortLastEntered, UserSortName, UserSortID:
+		return order, nil
+	default:
+		return UserOrder{}, ErrInvalidUserSort
+	}
+}
+
+// UserCursor is the position of the last user of a page: its sort value and its ID. It records
+// the order it was made for, as it means nothing in another one.
+type UserCursor struct {
+	Sort  UserSort `json:"s"`
+	Desc  bool     `json:"d,omitempty"`
+	Value *string  `json:"v"` // nil when the user has no value, such as never having entered
+	ID    string   `json:"id"`
+}
+
+// NewUserCursor returns the cursor after user in the given order
+func NewUserCursor(order UserOrder, user User) UserCursor {
+	cursor := UserCursor{Sort: order.Sort, Desc: order.Desc, ID: user.ID}
+	switch order.Sort {
+	case UserSortRegisteredAt:
+		cursor.Value = formatTime(user.RegisteredAt)
+	case UserSortLastEntered:
+		cursor.Value = formatTime(user.LastEntered)
+	case UserSortName:
+		cursor.Value = &user.Name
+	case UserSortID:
+		cursor.Value = &user.ID
+	}
+	return cursor
+}
+
+// ParseUserCursor decodes a cursor returned by String
+func ParseUserCursor(s string) (UserCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return UserCursor{}, ErrInvalidCursor
+	}
+	var cursor UserCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return UserCursor{}, ErrInvalidCursor
+	}
+	if _, err := cursor.SortValue(); err != nil {
+		return UserCursor{}, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// String encodes the cursor for the nextCursor field of a page
+func (c UserCursor) String() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// SortValue returns the sort value typed as the column it is compared with, or nil if there is none
+func (c UserCursor) SortValue() (interface{}, error) {
+	if c.Value == nil {
+		return nil, nil
+	}
+	switch c.Sort {
+	case UserSortRegisteredAt, UserSortLastEntered:
+		return time.Parse(time.RFC3339Nano, *c.Value)
+	default:
+		return *c.Value, nil
+	}
+}
+
+func formatTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.Format(time.RFC3339Nano)
+	return &s
+}
+
+// UserPage is one page of a user listing
+type UserPage struct {
+	Users      []User `json:"users"`
+	Total      int64  `json:"total"`                // users matching the filter on all pages
+	NextCursor string `json:"nextCursor,omitempty"` // absent on the last page
+}
+
+// UserListItem is a user without the phone, email and UID. Staff who may not export students
+// get these in listings, so they cannot page through everyone's contacts.
+type UserListItem struct {
+	ID              string          `json:"id"`
+	Name            string          `json:"name"`
+	Role            Role            `json:"role"`
+	BirthDate       *time.Time      `json:"birthDate"`
+	Status          *string         `json:"status"`
+	OtherStatus     *string         `json:"otherStatus"`
+	Province        *string         `json:"province"`
+	School          *string         `json:"school"`
+	SelectedSources *pq.StringArray `json:"selectedSources"`
+	OtherSource     *string         `json:"otherSource"`
+	FirstInterest   *string         `json:"firstInterest"`
+	SecondInterest  *string         `json:"secondInterest"`
+	ThirdInterest   *string         `json:"thirdInterest"`
+	Objective       *string         `json:"objective"`
+	RegisteredAt    *time.Time      `json:"registerAt"`
+	LastEntered     *time.Time      `json:"lastEntered"`
+	Faculty         *string         `json:"faculty"`
+	StudentID       *string         `json:"studentId"`
+	Nickname        *string         `json:"nickname"`
+	Year            *int            `json:"year"`
+	IsCentralStaff  *bool           `json:"isCentralStaff"`
+}
+
+// UserListPage is a UserPage without contact details
+type UserListPage struct {
+	Users      []UserListItem `json:"users"`
+	Total      int64          `json:"total"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+}
+
+// WithoutContacts drops the phone, email and UID of every user on the page
+func (p UserPage) WithoutContacts() UserListPage {
+	items := make([]UserListItem, len(p.Users))
+	for i, u := range p.Users {
+		items[i] = u.WithoutContacts()
+	}
+	return UserListPage{Users: items, Total: p.Total, NextCursor: p.NextCursor}
+}
+
+// WithoutContacts returns the user without the phone, email and UID
+func (u User) WithoutContacts() UserListItem {
+	return UserListItem{
+		ID:              u.ID,
+		Name:            u.Name,
+		Role:            u.Role,
+		BirthDate:       u.BirthDate,
+		Status:          u.Status,
+		OtherStatus:     u.OtherStatus,
+		Province:        u.Province,
+		School:          u.School,
+		SelectedSources: u.SelectedSources,
+		OtherSource:     u.OtherSource,
+		FirstInterest:   u.FirstInterest,
+		SecondInterest:  u.SecondInterest,
+		ThirdInterest:   u.ThirdInterest,
+		Objective:       u.Objective,
+		RegisteredAt:    u.RegisteredAt,
+		LastEntered:     u.LastEntered,
+		Faculty:         u.Faculty,
+		StudentID:       u.StudentID,
+		Nickname:        u.Nickname,
+		Year:            u.Year,
+		IsCentralStaff:  u.IsCentralStaff,
+	}
+}
@@ -0,0 +1,210 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DateLayout is the format of dates such as birthDate in requests
+const DateLayout = "2006-01-02"
+
+// StaffRegisterRequest is the body of POST /api/staff/register, as JSON or multipart form.
+// The route is public, so it cannot grant a role: see AddStaffRequest.
+type StaffRegisterRequest struct {
+	ID        string  `json:"id" form:"id" validate:"required"`
+	Name      string  `json:"name" form:"name" validate:"required"`
+	Phone     string  `json:"phone" form:"phone" validate:"required,phone"`
+	Email     string  `json:"email" form:"email" validate:"required,email"`
+	Nickname  *string `json:"nickname" form:"nickname"`
+	StudentID *string `json:"studentId" form:"studentId"`
+	Faculty   *string `json:"faculty" form:"faculty"`
+	Year      *int    `json:"year" form:"year" validate:"omitempty,min=1"`
+}
+
+// Normalize drops an empty year, as sent by form clients
+func (r *StaffRegisterRequest) Normalize() {
+	r.Year = nilIfZero(r.Year)
+}
+
+// User returns the staff member to register. They stay a member until an admin adds them as staff.
+func (r *StaffRegisterRequest) User() *User {
+	return &User{
+		ID:        r.ID,
+		Name:      r.Name,
+		Role:      Member,
+		Email:     r.Email,
+		Phone:     r.Phone,
+		Nickname:  r.Nickname,
+		StudentID: r.StudentID,
+		Faculty:   r.Faculty,
+		Year:      r.Year,
+	}
+}
+
+// AddStaffRequest is the body of PATCH /api/admin/addstaff/{phone} and PATCH /api/admin/staff/{id}. The admin
+// decides whether the staff member works for one faculty, which limits the dashboard to it, or for the central team.
+type AddStaffRequest struct {
+	Faculty        *string `json:"faculty" form:"faculty" validate:"required_unless=IsCentralStaff true"`
+	IsCentralStaff bool    `json:"isCentralStaff" form:"isCentralStaff"`
+}
+
+// StudentRegisterRequest is the body of POST /api/student/register, as JSON or multipart form
+type StudentRegisterRequest struct {
+	ID              string   `json:"id" form:"id" validate:"required"`
+	Name            string   `json:"name" form:"name" validate:"required"`
+	Phone           string   `json:"phone" form:"phone" validate:"required,phone"`
+	Email           string   `json:"email" form:"email" validate:"required,email"`
+	BirthDate       *string  `json:"birthDate" form:"birthDate" validate:"omitempty,datetime=2006-01-02"`
+	Status          *string  `json:"status" form:"status"`
+	OtherStatus     *string  `json:"otherStatus" form:"otherStatus"`
+	Province        *string  `json:"province" form:"province"`
+	School          *string  `json:"school" form:"school"`
+	SelectedSources []string `json:"selectedSources" form:"selectedSources"`
+	OtherSource     *string  `json:"otherSource" form:"otherSource"`
+	FirstInterest   *string  `json:"firstInterest" form:"firstInterest"`
+	SecondInterest  *string  `json:"secondInterest" form:"secondInterest"`
+	ThirdInterest   *string  `json:"thirdInterest" form:"thirdInterest"`
+	Objective       *string  `json:"objective" form:"objective"`
+}
+
+// Normalize splits comma-separated sources and drops an empty birth date, as sent by form clients
+func (r *StudentRegisterRequest) Normalize() {
+	r.SelectedSources = splitList(r.SelectedSources)
+	r.BirthDate = nilIfEmpty(r.BirthDate)
+}
+
+// User returns the student to register. The request must have been validated.
+func (r *StudentRegisterRequest) User() *User {
+	return &User{
+		ID:              r.ID,
+		Name:            r.Name,
+		Role:            Student,
+		Email:           r.Email,
+		Phone:           r.Phone,
+		BirthDate:       parseDate(r.BirthDate),
+		Status:          r.Status,
+		OtherStatus:     r.OtherStatus,
+		Province:        r.Province,
+		School:          r.School,
+		SelectedSources: stringArray(r.SelectedSources),
+		OtherSource:     r.OtherSource,
+		FirstInterest:   r.FirstInterest,
+		SecondInterest:  r.SecondInterest,
+		ThirdInterest:   r.ThirdInterest,
+		Objective:       r.Objective,
+	}
+}
+
+// UpdateUserRequest is the body of PATCH /api/users/{id}. Only the fields
+// present are changed; the role, staff scope, UID and timestamps cannot be set through it.
+type UpdateUserRequest struct {
+	Name            *string  `json:"name" form:"name" validate:"omitempty,min=1"`
+	Phone           *string  `json:"phone" form:"phone" validate:"omitempty,phone"`
+	Email           *string  `json:"email" form:"email" validate:"omitempty,email"`
+	BirthDate       *string  `json:"birthDate" form:"birthDate" validate:"omitempty,datetime=2006-01-02"`
+	Status          *string  `json:"status" form:"status"`
+	OtherStatus     *string  `json:"otherStatus" form:"otherStatus"`
+	Province        *string  `json:"province" form:"province"`
+	School          *string  `json:"school" form:"school"`
+	SelectedSources []string `json:"selectedSources" form:"selectedSources"`
+	OtherSource     *string  `json:"otherSource" form:"otherSource"`
+	FirstInterest   *string  `json:"firstInterest" form:"firstInterest"`
+	SecondInterest  *string  `json:"secondInterest" form:"secondInterest"`
+	ThirdInterest   *string  `json:"thirdInterest" form:"thirdInterest"`
+	Objective       *string  `json:"objective" form:"objective"`
+	StudentID       *string  `json:"studentId" form:"studentId"`
+	Nickname        *string  `json:"nickname" form:"nickname"`
+	Year            *int     `json:"year" form:"year" validate:"omitempty,min=1"`
+}
+
+// Normalize splits comma-separated sources and drops an empty birth date and year, as sent by form clients
+func (r *UpdateUserRequest) Normalize() {
+	r.SelectedSources = splitList(r.SelectedSources)
+	r.BirthDate = nilIfEmpty(r.BirthDate)
+	r.Year = nilIfZero(r.Year)
+}
+
+// User returns the changes to apply; unset fields are zero and left untouched by the update.
+// The request must have been validated.
+func (r *UpdateUserRequest) User() *User {
+	user := &User{
+		BirthDate:       parseDate(r.BirthDate),
+		Status:          r.Status,
+		OtherStatus:     r.OtherStatus,
+		Province:        r.Province,
+		School:          r.School,
+		SelectedSources: stringArray(r.SelectedSources),
+		OtherSource:     r.OtherSource,
+		FirstInterest:   r.FirstInterest,
+		SecondInterest:  r.SecondInterest,
+		ThirdInterest:   r.ThirdInterest,
+		Objective:       r.Objective,
+		StudentID:       r.StudentID,
+		Nickname:        r.Nickname,
+		Year:            r.Year,
+	}
+	if r.Name != nil {
+		user.Name = *r.Name
+	}
+	if r.Phone != nil {
+		user.Phone = *r.Phone
+	}
+	if r.Email != nil {
+		user.Email = *r.Email
+	}
+	return user
+}
+
+// splitList splits comma-separated items, trims them and drops the empty ones. Form clients send
+// lists either as repeated fields or as one "a,b" or "[a,b]" value.
+func splitList(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	items := []string{}
+	for _, value := range values {
+		value = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(value), "["), "]")
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+func nilIfEmpty(value *string) *string {
+	if value == nil || strings.TrimSpace(*value) == "" {
+		return nil
+	}
+	return value
+}
+
+// nilIfZero treats 0 as unset; the form decoder turns empty fields into zero values
+func nilIfZero(value *int) *int {
+	if value == nil || *value == 0 {
+		return nil
+	}
+	return value
+}
+
+func stringArray(values []string) *pq.StringArray {
+	if len(values) == 0 {
+		return nil
+	}
+	array := pq.StringArray(values)
+	return &array
+}
+
+func parseDate(value *string) *time.Time {
+	if value == nil {
+		return nil
+	}
+	date, err := time.Parse(DateLayout, *value)
+	if err != nil {
+		return nil
+	}
+	return &date
+}
@@ -0,0 +1,119 @@
+package export
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/lib/pq"
+)
+
+// Column is one exportable field of a student row
+type Column struct {
+	Key    string // query parameter and JSON Lines key
+	Header string // CSV/XLSX header
+	Value  func(row *domain.StudentExportRow) string
+}
+
+// Columns lists every column that can be requested, in their default order
+var Columns = []Column{
+	{"id", "ID", func(r *domain.StudentExportRow) string { return r.ID }},
+	{"uid", "UID", func(r *domain.StudentExportRow) string { return r.UID }},
+	{"name", "Name", func(r *domain.StudentExportRow) string { return r.Name }},
+	{"email", "Email", func(r *domain.StudentExportRow) string { return r.Email }},
+	{"phone", "Phone", func(r *domain.StudentExportRow) string { return r.Phone }},
+	{"birthDate", "Birth Date", func(r *domain.StudentExportRow) string { return formatDate(r.BirthDate) }},
+	{"status", "Status", func(r *domain.StudentExportRow) string { return formatString(r.Status) }},
+	{"otherStatus", "Other Status", func(r *domain.StudentExportRow) string { return formatString(r.OtherStatus) }},
+	{"province", "Province", func(r *domain.StudentExportRow) string { return formatString(r.Province) }},
+	{"school", "School", func(r *domain.StudentExportRow) string { return formatString(r.School) }},
+	{"selectedSources", "Selected Sources", func(r *domain.StudentExportRow) string { return formatArray(r.SelectedSources) }},
+	{"otherSource", "Other Source", func(r *domain.StudentExportRow) string { return formatString(r.OtherSource) }},
+	{"firstInterest", "First Interest", func(r *domain.StudentExportRow) string { return formatString(r.FirstInterest) }},
+	{"secondInterest", "Second Interest", func(r *domain.StudentExportRow) string { return formatString(r.SecondInterest) }},
+	{"thirdInterest", "Third Interest", func(r *domain.StudentExportRow) string { return formatString(r.ThirdInterest) }},
+	{"objective", "Objective", func(r *domain.StudentExportRow) string { return formatString(r.Objective) }},
+	{"registeredAt", "Registered At", func(r *domain.StudentExportRow) string { return formatTime(r.RegisteredAt) }},
+	{"lastEntered", "Last Entered", func(r *domain.StudentExportRow) string { return formatTime(r.LastEntered) }},
+
+	// Derived columns
+	{"entered", "Entered", func(r *domain.StudentExportRow) string { return strconv.FormatBool(r.LastEntered != nil) }},
+	{"facultiesVisited", "Faculties Visited", func(r *domain.StudentExportRow) string { return formatString(r.FacultiesVisited) }},
+	{"evaluated", "Evaluated", func(r *domain.StudentExportRow) string { return strconv.FormatBool(r.Evaluated) }},
+}
+
+// DefaultColumns is used when the caller does not pick any columns
+var DefaultColumns = []string{
+	"id", "name", "email", "phone", "firstInterest", "secondInterest", "thirdInterest", "registeredAt",
+}
+
+// ParseColumns resolves a comma-separated list of column keys. An empty list selects DefaultColumns.
+// A column may only be listed once, since JSON Lines keys its values by column.
+func ParseColumns(keys string) ([]Column, error) {
+	selected := DefaultColumns
+	if strings.TrimSpace(keys) != "" {
+		selected = strings.Split(keys, ",")
+	}
+
+	columns := make([]Column, 0, len(selected))
+	seen := make(map[string]bool, len(selected))
+	for _, key := range selected {
+		column, ok := findColumn(strings.TrimSpace(key))
+		if !ok {
+			return nil, domain.ErrUnknownExportColumn.WithDetails(domain.FieldError{
+				Field:   "columns",
+				Code:    domain.FieldInvalidOption,
+				Message: fmt.Sprintf("unknown column %q", key),
+			})
+		}
+		if seen[column.Key] {
+			return nil, domain.ErrDuplicateExportColumn.WithDetails(domain.FieldError{
+				Field:   "columns",
+				Code:    domain.FieldDuplicate,
+				Message: fmt.Sprintf("column %q is listed more than once", column.Key),
+			})
+		}
+		seen[column.Key] = true
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+func findColumn(key string) (Column, bool) {
+	for _, column := range Columns {
+		if column.Key == key {
+			return column, true
+		}
+	}
+	return Column{}, false
+}
+
+func formatString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+func formatArray(a *pq.StringArray) string {
+	if a == nil {
+		return ""
+	}
+	return strings.Join(*a, ",")
+}
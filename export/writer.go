@@ -0,0 +1,237 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+)
+
+// Format is the file format of an export
+type Format string
+
+const (
+	CSV   Format = "csv"
+	XLSX  Format = "xlsx"
+	JSONL Format = "jsonl"
+)
+
+// ParseFormat validates a format name. An empty name selects CSV.
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(name))) {
+	case "", CSV:
+		return CSV, nil
+	case XLSX:
+		return XLSX, nil
+	case JSONL:
+		return JSONL, nil
+	default:
+		return "", domain.ErrUnknownExportFormat.WithDetails(domain.FieldError{
+			Field:   "format",
+			Code:    domain.FieldInvalidOption,
+			Message: fmt.Sprintf("unknown format %q", name),
+		})
+	}
+}
+
+// ContentType returns the MIME type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case XLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case JSONL:
+		return "application/x-ndjson"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// Extension returns the file extension of the format, without the dot
+func (f Format) Extension() string {
+	return string(f)
+}
+
+// Compressible reports whether the output benefits from gzip. XLSX is already a zip archive.
+func (f Format) Compressible() bool {
+	return f != XLSX
+}
+
+// RowWriter writes export rows one at a time so an export never has to be held in memory
+type RowWriter interface {
+	Write(row *domain.StudentExportRow) error
+	// Close flushes buffered output. It does not close the underlying io.Writer.
+	Close() error
+}
+
+// NewRowWriter creates a RowWriter for the format and writes the header, if the format has one.
+func NewRowWriter(format Format, w io.Writer, columns []Column) (RowWriter, error) {
+	switch format {
+	case CSV:
+		return newCSVWriter(w, columns)
+	case XLSX:
+		return newXLSXWriter(w, columns)
+	case JSONL:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		return &jsonlWriter{enc: enc, columns: columns}, nil
+	default:
+		return nil, domain.ErrUnknownExportFormat.WithDetails(domain.FieldError{
+			Field:   "format",
+			Code:    domain.FieldInvalidOption,
+			Message: fmt.Sprintf("unknown format %q", format),
+		})
+	}
+}
+
+// utf8BOM makes Excel open the CSV as UTF-8 so Thai text is not garbled
+const utf8BOM = "\ufeff"
+
+type csvWriter struct {
+	w       *csv.Writer
+	columns []Column
+}
+
+func newCSVWriter(w io.Writer, columns []Column) (*csvWriter, error) {
+	if _, err := io.WriteString(w, utf8BOM); err != nil {
+		return nil, err
+	}
+	cw := &csvWriter{w: csv.NewWriter(w), columns: columns}
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Header
+	}
+	if err := cw.w.Write(header); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *csvWriter) Write(row *domain.StudentExportRow) error {
+	record := make([]string, len(cw.columns))
+	for i, column := range cw.columns {
+		record[i] = escapeFormula(column.Value(row))
+	}
+	return cw.w.Write(record)
+}
+
+// escapeFormula prefixes a cell that a spreadsheet would run as a formula with ', so it is shown as text.
+// Values come from public registration fields such as the name and school.
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+type jsonlWriter struct {
+	enc     *json.Encoder
+	columns []Column
+}
+
+func (jw *jsonlWriter) Write(row *domain.StudentExportRow) error {
+	record := make(map[string]string, len(jw.columns))
+	for _, column := range jw.columns {
+		record[column.Key] = column.Value(row)
+	}
+	return jw.enc.Encode(record)
+}
+
+func (jw *jsonlWriter) Close() error {
+	return nil
+}
+
+// xlsxWriter writes a single-sheet workbook using inline strings, so rows can be streamed straight into the
+// zip entry without a shared string table. An inline string is never evaluated, even when it starts with =.
+type xlsxWriter struct {
+	zw      *zip.Writer
+	sheet   *bufio.Writer
+	columns []Column
+}
+
+var xlsxStaticParts = []struct{ name, body string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Students" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+func newXLSXWriter(w io.Writer, columns []Column) (*xlsxWriter, error) {
+	zw := zip.NewWriter(w)
+	for _, part := range xlsxStaticParts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	// The sheet must be the last entry since zip entries cannot be interleaved
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	xw := &xlsxWriter{zw: zw, sheet: bufio.NewWriter(f), columns: columns}
+	xw.sheet.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Header
+	}
+	if err := xw.writeCells(header); err != nil {
+		return nil, err
+	}
+	return xw, nil
+}
+
+func (xw *xlsxWriter) writeCells(values []string) error {
+	xw.sheet.WriteString("<row>")
+	for _, value := range values {
+		xw.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		if err := xml.EscapeText(xw.sheet, []byte(value)); err != nil {
+			return err
+		}
+		xw.sheet.WriteString("</t></is></c>")
+	}
+	_, err := xw.sheet.WriteString("</row>")
+	return err
+}
+
+func (xw *xlsxWriter) Write(row *domain.StudentExportRow) error {
+	values := make([]string, len(xw.columns))
+	for i, column := range xw.columns {
+		values[i] = column.Value(row)
+	}
+	return xw.writeCells(values)
+}
+
+func (xw *xlsxWriter) Close() error {
+	xw.sheet.WriteString("</sheetData></worksheet>")
+	if err := xw.sheet.Flush(); err != nil {
+		return err
+	}
+	return xw.zw.Close()
+}
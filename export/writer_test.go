@@ -0,0 +1,265 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+var testRows = []domain.StudentExportRow{
+	{User: domain.User{ID: "1", Name: "สมชาย ใจดี", School: ptr("=HYPERLINK(\"http://evil\")")}},
+	{User: domain.User{ID: "2", Name: "+66 Somchai", School: ptr("-1+1")}},
+	{User: domain.User{ID: "3", Name: "@SUM(A1)", School: ptr("\tTab")}},
+	{User: domain.User{ID: "4", Name: "\rReturn", School: ptr("โรงเรียน <เตรียม> & co")}},
+}
+
+// write exports testRows in the format with the columns
+func write(t *testing.T, format Format, keys string) []byte {
+	t.Helper()
+	columns, err := ParseColumns(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w, err := NewRowWriter(format, &buf, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range testRows {
+		if err := w.Write(&testRows[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSVStartsWithBOMAndHeader(t *testing.T) {
+	out := write(t, CSV, "id,name,school")
+	if !bytes.HasPrefix(out, []byte(utf8BOM)) {
+		t.Fatalf("output does not start with a UTF-8 BOM: %q", out[:min(len(out), 8)])
+	}
+	records, err := csv.NewReader(bytes.NewReader(out[len(utf8BOM):])).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(records[0], ","); got != "ID,Name,School" {
+		t.Errorf("header %q, want ID,Name,School", got)
+	}
+	if len(records) != len(testRows)+1 {
+		t.Errorf("%d records, want a header and %d rows", len(records), len(testRows))
+	}
+	if records[1][1] != "สมชาย ใจดี" {
+		t.Errorf("Thai name %q was not kept as is", records[1][1])
+	}
+}
+
+func TestCSVEscapesFormulas(t *testing.T) {
+	out := write(t, CSV, "name,school")
+	records, err := csv.NewReader(bytes.NewReader(out[len(utf8BOM):])).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"สมชาย ใจดี", "'=HYPERLINK(\"http://evil\")"},
+		{"'+66 Somchai", "'-1+1"},
+		{"'@SUM(A1)", "'\tTab"},
+		{"'\rReturn", "โรงเรียน <เตรียม> & co"},
+	}
+	for i, row := range want {
+		for j, cell := range row {
+			if records[i+1][j] != cell {
+				t.Errorf("row %d column %d = %q, want %q", i+1, j, records[i+1][j], cell)
+			}
+		}
+	}
+}
+
+func TestEscapeFormula(t *testing.T) {
+	tests := map[string]string{
+		"":        "",
+		"=1+1":    "'=1+1",
+		"+1":      "'+1",
+		"-1":      "'-1",
+		"@A1":     "'@A1",
+		"\tx":     "'\tx",
+		"\rx":     "'\rx",
+		"a=1":     "a=1",
+		" =1":     " =1",
+		"0812345": "0812345",
+		"'quoted": "'quoted",
+	}
+	for value, want := range tests {
+		if got := escapeFormula(value); got != want {
+			t.Errorf("escapeFormula(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestXLSXIsAZipOfInlineStrings(t *testing.T) {
+	out := write(t, XLSX, "id,name,school")
+	zr, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatalf("not a valid zip: %v", err)
+	}
+
+	parts := map[string]*zip.File{}
+	for _, f := range zr.File {
+		parts[f.Name] = f
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if parts[name] == nil {
+			t.Fatalf("missing part %s", name)
+		}
+	}
+	// A shared string table would have to hold every distinct value until the sheet is done
+	if parts["xl/sharedStrings.xml"] != nil {
+		t.Error("workbook has a shared string table")
+	}
+	for _, name := range []string{"[Content_Types].xml", "xl/_rels/workbook.xml.rels"} {
+		if body := readPart(t, parts[name]); strings.Contains(body, "sharedStrings") {
+			t.Errorf("%s refers to a shared string table: %s", name, body)
+		}
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				T  string `xml:"t,attr"`
+				F  string `xml:"f"`
+				V  string `xml:"v"`
+				Is string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal([]byte(readPart(t, parts["xl/worksheets/sheet1.xml"])), &sheet); err != nil {
+		t.Fatal(err)
+	}
+	if len(sheet.Rows) != len(testRows)+1 {
+		t.Fatalf("%d rows, want a header and %d rows", len(sheet.Rows), len(testRows))
+	}
+	var got [][]string
+	for _, row := range sheet.Rows {
+		var values []string
+		for _, cell := range row.Cells {
+			if cell.T != "inlineStr" || cell.F != "" || cell.V != "" {
+				t.Errorf("cell type %q, formula %q, value %q; want an inline string", cell.T, cell.F, cell.V)
+			}
+			values = append(values, cell.Is)
+		}
+		got = append(got, values)
+	}
+	want := [][]string{
+		{"ID", "Name", "School"},
+		{"1", "สมชาย ใจดี", "=HYPERLINK(\"http://evil\")"},
+		{"2", "+66 Somchai", "-1+1"},
+		{"3", "@SUM(A1)", "\tTab"},
+		{"4", "\rReturn", "โรงเรียน <เตรียม> & co"},
+	}
+	for i := range want {
+		if strings.Join(got[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("row %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func readPart(t *testing.T, f *zip.File) string {
+	t.Helper()
+	r, err := f.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	body, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("%s: %v", f.Name, err)
+	}
+	return string(body)
+}
+
+func TestJSONLWritesOneObjectPerRow(t *testing.T) {
+	out := write(t, JSONL, "id,name,school")
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	var lines int
+	for scanner.Scan() {
+		var record map[string]string
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("line %d: %v", lines+1, err)
+		}
+		row := testRows[lines]
+		if len(record) != 3 || record["id"] != row.ID || record["name"] != row.Name || record["school"] != *row.School {
+			t.Errorf("line %d = %v, want the id, name and unescaped school of %s", lines+1, record, row.ID)
+		}
+		lines++
+	}
+	if lines != len(testRows) {
+		t.Errorf("%d lines, want %d", lines, len(testRows))
+	}
+	if bytes.Contains(out, []byte(`\u003c`)) {
+		t.Errorf("HTML characters were escaped: %s", out)
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	tests := []struct {
+		keys string
+		want string
+		err  *domain.Error
+	}{
+		{"", strings.Join(DefaultColumns, ","), nil},
+		{"  ", strings.Join(DefaultColumns, ","), nil},
+		{"id,name", "id,name", nil},
+		{"id, name ,evaluated", "id,name,evaluated", nil},
+		{"id,password", "", domain.ErrUnknownExportColumn},
+		{"id,", "", domain.ErrUnknownExportColumn},
+		{"id,id", "", domain.ErrDuplicateExportColumn},
+		{"name, id,name", "", domain.ErrDuplicateExportColumn},
+	}
+	for _, tt := range tests {
+		columns, err := ParseColumns(tt.keys)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("ParseColumns(%q) error %v, want %v", tt.keys, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseColumns(%q): %v", tt.keys, err)
+			continue
+		}
+		keys := make([]string, len(columns))
+		for i, column := range columns {
+			keys[i] = column.Key
+		}
+		if got := strings.Join(keys, ","); got != tt.want {
+			t.Errorf("ParseColumns(%q) = %s, want %s", tt.keys, got, tt.want)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := map[string]Format{"": CSV, "csv": CSV, " XLSX ": XLSX, "jsonl": JSONL}
+	for name, want := range tests {
+		if got, err := ParseFormat(name); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("pdf"); !errors.Is(err, domain.ErrUnknownExportFormat) {
+		t.Errorf("ParseFormat(pdf) error %v, want %v", err, domain.ErrUnknownExportFormat)
+	}
+}
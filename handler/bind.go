@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/validation"
+)
+
+// normalizer is implemented by requests that clean up their fields before validation
+type normalizer interface {
+	Normalize()
+}
+
+// bind decodes the request body into a request DTO, from JSON or a form, and validates it.
+// Unknown fields, values of the wrong type and failed validations are all reported as field errors.
+func bind(c *fiber.Ctx, request interface{}) error {
+	if err := c.BodyParser(request); err != nil {
+		return decodeError(err)
+	}
+	if err := unknownFields(c, request); err != nil {
+		return err
+	}
+	if n, ok := request.(normalizer); ok {
+		n.Normalize()
+	}
+	return validation.Struct(request)
+}
+
+// decodeError translates an error of BodyParser into a field error where the field is known
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return invalidType(typeErr.Field, typeErr.Type)
+	}
+
+	var multi fiber.MultiError
+	if errors.As(err, &multi) {
+		verr := &domain.ValidationError{}
+		for key, err := range multi {
+			var conv fiber.ConversionError
+			if !errors.As(err, &conv) {
+				return domain.InvalidInput("Invalid form")
+			}
+			verr.Fields = append(verr.Fields, invalidType(key, conv.Type).Fields...)
+		}
+		// Map order is random; keep responses stable
+		sort.Slice(verr.Fields, func(i, j int) bool { return verr.Fields[i].Field < verr.Fields[j].Field })
+		return verr
+	}
+
+	var ferr *fiber.Error
+	if errors.As(err, &ferr) && ferr.Code == fiber.StatusUnprocessableEntity {
+		return domain.InvalidInput("Unsupported content type, send JSON or a multipart form")
+	}
+	return domain.InvalidInput("Invalid input")
+}
+
+// unknownFields reports the top-level fields of the body that the request DTO does not have, so a misspelled
+// or read-only field is not silently dropped
+func unknownFields(c *fiber.Ctx, request interface{}) error {
+	var keys []string
+	contentType := strings.ToLower(string(c.Request().Header.ContentType()))
+	switch {
+	case strings.HasPrefix(contentType, fiber.MIMEApplicationJSON):
+		var body map[string]json.RawMessage
+		if err := json.Unmarshal(c.Body(), &body); err != nil {
+			return domain.InvalidInput("Invalid input")
+		}
+		for key := range body {
+			keys = append(keys, key)
+		}
+	case strings.HasPrefix(contentType, fiber.MIMEMultipartForm):
+		form, err := c.MultipartForm()
+		if err != nil {
+			return domain.InvalidInput("Invalid form")
+		}
+		for key := range form.Value {
+			keys = append(keys, key)
+		}
+		for key := range form.File {
+			keys = append(keys, key)
+		}
+	case strings.HasPrefix(contentType, fiber.MIMEApplicationForm):
+		c.Request().PostArgs().VisitAll(func(key, _ []byte) {
+			keys = append(keys, string(key))
+		})
+	}
+
+	known := fieldKeys(reflect.TypeOf(request), "json")
+	if !strings.HasPrefix(contentType, fiber.MIMEApplicationJSON) {
+		known = fieldKeys(reflect.TypeOf(request), "form")
+	}
+	verr := &domain.ValidationError{}
+	for _, key := range keys {
+		// Form lists may be sent as selectedSources[] or selectedSources[0]
+		name := key
+		if i := strings.IndexByte(name, '['); i > 0 {
+			name = name[:i]
+		}
+		if !known[name] {
+			verr.Add(key, domain.FieldUnknown, "is not a field of this request")
+		}
+	}
+	sort.Slice(verr.Fields, func(i, j int) bool { return verr.Fields[i].Field < verr.Fields[j].Field })
+	return verr.OrNil()
+}
+
+// fieldKeys returns the keys a struct is decoded from under the tag, json or form
+func fieldKeys(t reflect.Type, tag string) map[string]bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	keys := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.SplitN(t.Field(i).Tag.Get(tag), ",", 2)[0]
+		if name != "" && name != "-" {
+			keys[name] = true
+		}
+	}
+	return keys
+}
+
+func invalidType(field string, t reflect.Type) *domain.ValidationError {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	message := "has the wrong type"
+	switch t.Kind() {
+	case reflect.String:
+		message = "must be a string"
+	case reflect.Bool:
+		message = "must be true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		message = "must be an integer"
+	case reflect.Float32, reflect.Float64:
+		message = "must be a number"
+	case reflect.Slice, reflect.Array:
+		message = "must be a list"
+	case reflect.Map, reflect.Struct:
+		message = "must be an object"
+	}
+
+	verr := &domain.ValidationError{}
+	verr.Add(field, domain.FieldInvalidType, message)
+	return verr
+}
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+)
+
+// bindRequest posts body with the content type to a route that binds a new request made by newRequest,
+// and returns the error of bind
+func bindRequest(t *testing.T, newRequest func() interface{}, contentType string, body string) error {
+	t.Helper()
+	var bindErr error
+	app := fiber.New()
+	app.Post("/", func(c *fiber.Ctx) error {
+		bindErr = bind(c, newRequest())
+		return nil
+	})
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	if _, err := app.Test(req, -1); err != nil {
+		t.Fatal(err)
+	}
+	return bindErr
+}
+
+// multipartBody encodes the fields as a multipart form
+func multipartBody(t *testing.T, fields map[string]string) (string, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := w.WriteField(key, fields[key]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return w.FormDataContentType(), buf.String()
+}
+
+// rejected returns "field:code" for every rejected field of err, sorted, or the error code if it has no fields
+func rejected(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var verr *domain.ValidationError
+	if errors.As(err, &verr) {
+		codes := make([]string, len(verr.Fields))
+		for i, f := range verr.Fields {
+			codes[i] = f.Field + ":" + f.Code
+		}
+		sort.Strings(codes)
+		return codes
+	}
+	var derr *domain.Error
+	if errors.As(err, &derr) {
+		return []string{derr.Code}
+	}
+	t.Fatalf("unexpected error %v", err)
+	return nil
+}
+
+const validStaff = `"id":"1","name":"a","phone":"0812345678","email":"a@b.co"`
+
+func TestBindJSON(t *testing.T) {
+	staff := func() interface{} { return new(domain.StaffRegisterRequest) }
+	student := func() interface{} { return new(domain.StudentRegisterRequest) }
+	update := func() interface{} { return new(domain.UpdateUserRequest) }
+	addStaff := func() interface{} { return new(domain.AddStaffRequest) }
+	role := func() interface{} { return new(domain.RoleRequest) }
+	evaluation := func() interface{} { return new(domain.EvaluationRequest) }
+
+	tests := []struct {
+		name       string
+		newRequest func() interface{}
+		body       string
+		want       []string
+	}{
+		{"staff", staff, `{` + validStaff + `,"year":2}`, nil},
+		{"staff required", staff, `{"nickname":"a"}`, []string{"email:required", "id:required", "name:required", "phone:required"}},
+		{"staff out of range", staff, `{` + validStaff + `,"year":-1}`, []string{"year:out_of_range"}},
+		{"staff wrong type", staff, `{` + validStaff + `,"year":"two"}`, []string{"year:invalid_type"}},
+		{"staff unknown fields", staff, `{` + validStaff + `,"role":"admin","isCentralStaff":true}`, []string{"isCentralStaff:unknown_field", "role:unknown_field"}},
+
+		{"student", student, `{` + validStaff + `,"selectedSources":[" line ","",  "facebook"]}`, nil},
+		{"student required", student, `{}`, []string{"email:required", "id:required", "name:required", "phone:required"}},
+		{"student bad birth date", student, `{` + validStaff + `,"birthDate":"2008-13-01"}`, []string{"birthDate:invalid_type"}},
+		{"student wrong type", student, `{` + validStaff + `,"selectedSources":"line"}`, []string{"selectedSources:invalid_type"}},
+		{"student unknown field", student, `{` + validStaff + `,"uid":"x"}`, []string{"uid:unknown_field"}},
+
+		{"update", update, `{"name":"b","year":3}`, nil},
+		{"update out of range", update, `{"year":-2}`, []string{"year:out_of_range"}},
+		{"update empty name", update, `{"name":""}`, []string{"name:invalid"}},
+		{"update unknown field", update, `{"role":"admin"}`, []string{"role:unknown_field"}},
+
+		{"add faculty staff", addStaff, `{"faculty":"engineering"}`, nil},
+		{"add staff required", addStaff, `{"isCentralStaff":false}`, []string{"faculty:required"}},
+		{"add staff unknown field", addStaff, `{"isCentralStaff":true,"role":"admin"}`, []string{"role:unknown_field"}},
+
+		{"role", role, `{"role":"staff"}`, nil},
+		{"role required", role, `{}`, []string{"role:required"}},
+		{"role invalid option", role, `{"role":"superuser"}`, []string{"role:invalid_option"}},
+		{"role unknown field", role, `{"role":"staff","id":"1"}`, []string{"id:unknown_field"}},
+
+		{"evaluation", evaluation, `{"answers":{"overallActivity":5}}`, nil},
+		{"evaluation required", evaluation, `{}`, []string{"answers:required"}},
+		{"evaluation wrong type", evaluation, `{"answers":[5]}`, []string{"answers:invalid_type"}},
+		{"evaluation unknown field", evaluation, `{"answers":{},"overallActivity":5}`, []string{"overallActivity:unknown_field"}},
+
+		{"malformed JSON", staff, `{"id":`, []string{"invalid_input"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rejected(t, bindRequest(t, tt.newRequest, fiber.MIMEApplicationJSON, tt.body))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rejected %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindForm(t *testing.T) {
+	valid := map[string]string{"id": "1", "name": "a", "phone": "0812345678", "email": "a@b.co"}
+	with := func(extra map[string]string) map[string]string {
+		fields := map[string]string{}
+		for k, v := range valid {
+			fields[k] = v
+		}
+		for k, v := range extra {
+			fields[k] = v
+		}
+		return fields
+	}
+	staff := func() interface{} { return new(domain.StaffRegisterRequest) }
+	student := func() interface{} { return new(domain.StudentRegisterRequest) }
+	role := func() interface{} { return new(domain.RoleRequest) }
+
+	tests := []struct {
+		name       string
+		newRequest func() interface{}
+		fields     map[string]string
+		want       []string
+	}{
+		{"staff with an empty year", staff, with(map[string]string{"year": ""}), nil},
+		{"staff required", staff, map[string]string{"nickname": "a"}, []string{"email:required", "id:required", "name:required", "phone:required"}},
+		{"staff out of range", staff, with(map[string]string{"year": "-1"}), []string{"year:out_of_range"}},
+		{"staff wrong type", staff, with(map[string]string{"year": "two"}), []string{"year:invalid_type"}},
+		{"staff unknown field", staff, with(map[string]string{"role": "admin"}), []string{"role:unknown_field"}},
+		{"student sources list", student, with(map[string]string{"selectedSources[]": "line,facebook"}), nil},
+		{"student unknown field", student, with(map[string]string{"uid": "x"}), []string{"uid:unknown_field"}},
+		{"role invalid option", role, map[string]string{"role": "root"}, []string{"role:invalid_option"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contentType, body := multipartBody(t, tt.fields)
+			got := rejected(t, bindRequest(t, tt.newRequest, contentType, body))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rejected %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// URL-encoded forms are checked the same way
+	got := rejected(t, bindRequest(t, role, fiber.MIMEApplicationForm, "role=staff&extra=1"))
+	if want := []string{"extra:unknown_field"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("url-encoded form rejected %v, want %v", got, want)
+	}
+}
+
+func TestBindUnsupportedContentType(t *testing.T) {
+	err := bindRequest(t, func() interface{} { return new(domain.RoleRequest) }, "text/plain", "role=staff")
+	if got := rejected(t, err); !reflect.DeepEqual(got, []string{"invalid_input"}) {
+		t.Errorf("rejected %v, want invalid input", got)
+	}
+}
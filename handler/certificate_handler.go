@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/middleware"
+	"github.com/isd-sgcu/oph-67-backend/usecase"
+)
+
+type CertificateHandler struct {
+	Usecase *usecase.CertificateUsecase
+}
+
+func NewCertificateHandler(usecase *usecase.CertificateUsecase) *CertificateHandler {
+	return &CertificateHandler{Usecase: usecase}
+}
+
+// GetCertificate returns the authenticated student's certificate of an event as a PDF.
+func (h *CertificateHandler) GetCertificate(c *fiber.Ctx) error {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		return domain.ErrUnauthorized
+	}
+
+	cert, err := h.Usecase.Issue(c.UserContext(), user.ID, c.Params("event"))
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", cert.FileName))
+	return c.Send(cert.PDF)
+}
+
+// VerifyCertificate checks the token printed in a certificate's QR code and shows who it was issued to.
+func (h *CertificateHandler) VerifyCertificate(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		return domain.InvalidInput("token is required")
+	}
+	result, err := h.Usecase.Verify(c.UserContext(), token)
+	if err != nil {
+		return err
+	}
+	return c.JSON(result)
+}
+
+// GetIssuedCertificates lists issued certificates. ?event= filters by event and ?revoked=true|false by revocation,
+// so ?revoked=true is the revocation list.
+func (h *CertificateHandler) GetIssuedCertificates(c *fiber.Ctx) error {
+	filter := domain.CertificateFilter{Event: c.Query("event")}
+	if revoked := c.Query("revoked"); revoked != "" {
+		value := c.QueryBool("revoked")
+		filter.Revoked = &value
+	}
+	certificates, err := h.Usecase.GetCertificates(c.UserContext(), filter)
+	if err != nil {
+		return err
+	}
+	return c.JSON(certificates)
+}
+
+// RevokeCertificate revokes an issued certificate.
+func (h *CertificateHandler) RevokeCertificate(c *fiber.Ctx) error {
+	admin, ok := middleware.CurrentUser(c)
+	if !ok {
+		return domain.ErrUnauthorized
+	}
+	req := new(domain.RevokeCertificateRequest)
+	if err := c.BodyParser(req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		return domain.InvalidInput("reason is required")
+	}
+
+	cert, err := h.Usecase.Revoke(c.UserContext(), c.Params("serial"), req.Reason, admin.ID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(cert)
+}
+
+// GetCertificateTemplates returns the certificate template of every event.
+func (h *CertificateHandler) GetCertificateTemplates(c *fiber.Ctx) error {
+	templates, err := h.Usecase.GetTemplates(c.UserContext())
+	if err != nil {
+		return err
+	}
+	return c.JSON(templates)
+}
+
+// GetCertificateTemplate returns the certificate template of one event.
+func (h *CertificateHandler) GetCertificateTemplate(c *fiber.Ctx) error {
+	template, err := h.Usecase.GetTemplate(c.UserContext(), c.Params("event"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(template)
+}
+
+// SaveCertificateTemplate creates or replaces the certificate template of an event.
+func (h *CertificateHandler) SaveCertificateTemplate(c *fiber.Ctx) error {
+	template := new(domain.CertificateTemplate)
+	if err := c.BodyParser(template); err != nil {
+		return domain.InvalidInput("Invalid input")
+	}
+	template.Event = c.Params("event")
+
+	if err := h.Usecase.SaveTemplate(c.UserContext(), template); err != nil {
+		return err
+	}
+	return c.JSON(template)
+}
@@ -1,131 +1,206 @@
 package handler
 
 import (
+	"bufio"
 	"compress/gzip"
-	"encoding/csv"
+	"context"
+	"io"
+	"log/slog"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/export"
+	"github.com/isd-sgcu/oph-67-backend/middleware"
 	"github.com/isd-sgcu/oph-67-backend/usecase"
 )
 
 type DashBoardHandler struct {
-	Usecase *usecase.DashboardUseCase
+	Usecase       *usecase.DashboardUseCase
+	StreamTimeout time.Duration // limit of a streamed export, which outlives the request timeout
 }
 
-func NewDashBoardUseCase(usecase *usecase.DashboardUseCase) *DashBoardHandler {
-	return &DashBoardHandler{Usecase: usecase}
+func NewDashBoardUseCase(usecase *usecase.DashboardUseCase, streamTimeout time.Duration) *DashBoardHandler {
+	return &DashBoardHandler{Usecase: usecase, StreamTimeout: streamTimeout}
 }
 
 // GetFacultyCount returns the number of students interested in each faculty.
 func (h *DashBoardHandler) GetFacultyCount(c *fiber.Ctx) error {
-	results, err := h.Usecase.GetFacultyCount()
+	results, err := h.Usecase.GetFacultyCount(c.UserContext(), facultyScope(c))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 	return c.JSON(results)
 }
 
 // GetSourceCount returns the number of students who selected each source.
 func (h *DashBoardHandler) GetSourceCount(c *fiber.Ctx) error {
-	results, err := h.Usecase.GetSourceCount()
+	results, err := h.Usecase.GetSourceCount(c.UserContext(), facultyScope(c))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 	return c.JSON(results)
 }
 
 // GetAgeGroupCount returns the number of students in each age group.
 func (h *DashBoardHandler) GetAgeGroupCount(c *fiber.Ctx) error {
-	results, err := h.Usecase.GetAgeGroupCount()
+	results, err := h.Usecase.GetAgeGroupCount(c.UserContext(), facultyScope(c))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 	return c.JSON(results)
 }
 
 // GetFacultyTodayCount returns the number of students interested in each faculty today.
 func (h *DashBoardHandler) GetFacultyTodayCount(c *fiber.Ctx) error {
-	results, err := h.Usecase.GetFacultyTodayCount()
+	results, err := h.Usecase.GetFacultyTodayCount(c.UserContext(), facultyScope(c))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 	return c.JSON(results)
 }
 
 // GetStatusStudent returns the number of students in each status.
 func (h *DashBoardHandler) GetStatusStudent(c *fiber.Ctx) error {
-	results, err := h.Usecase.GetStatusStudent()
+	results, err := h.Usecase.GetStatusStudent(c.UserContext(), facultyScope(c))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 	return c.JSON(results)
 }
 
+// ExportAllStudents streams every student. Query parameters:
+// format=csv|xlsx|jsonl (default csv) and columns=id,name,... (see export.Columns).
 func (h *DashBoardHandler) ExportAllStudents(c *fiber.Ctx) error {
-	students, err := h.Usecase.GetAllStudent()
+	return h.streamExport(c, "students_export", domain.StudentExportFilter{})
+}
+
+// ExportFacultyStudents exports the students tied to one faculty, by interest (default) or by visit.
+// Faculty staff always get their own faculty; central staff and admins choose with ?faculty=.
+func (h *DashBoardHandler) ExportFacultyStudents(c *fiber.Ctx) error {
+	faculty, err := scopedFaculty(c, c.Query("faculty"))
+	if err != nil {
+		return err
+	}
+	if faculty == "" {
+		return domain.ErrFacultyRequired
+	}
+
+	by := domain.FacultyListBasis(c.Query("by", string(domain.ByInterest)))
+	if by != domain.ByInterest && by != domain.ByVisit {
+		return domain.ErrInvalidFacultyListBasis
+	}
+
+	filter := domain.StudentExportFilter{Faculty: faculty, By: by}
+	return h.streamExport(c, "faculty_students_"+string(by)+"_export", filter)
+}
+
+// streamExport validates the format and columns, then streams the export as the response body.
+// Rows are written while the response is being sent, so errors after this point can only be logged.
+func (h *DashBoardHandler) streamExport(c *fiber.Ctx, filename string, filter domain.StudentExportFilter) error {
+	format, err := export.ParseFormat(c.Query("format"))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch student data",
-		})
-	}
-
-	c.Set("Content-Type", "text/csv")
-	c.Set("Content-Disposition", "attachment; filename=students_export.csv")
-	c.Set("Content-Encoding", "gzip")
-	gz := gzip.NewWriter(c.Response().BodyWriter())
-	defer gz.Close()
-	writer := csv.NewWriter(gz)
-
-	defer writer.Flush()
-
-	header := []string{
-		"ID",
-		"Name",
-		"Email",
-		"Phone",
-		"First Interest",
-		"Second Interest",
-		"Third Interest",
-	}
-	if err := writer.Write(header); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to generate CSV",
-		})
-	}
-
-	// เขียนข้อมูลทีละแถว
-	for _, student := range students {
-		row := []string{
-			student.ID,
-			student.Name,
-			student.Email,
-			student.Phone,
-			getSafeString(&student.FirstInterest),
-			getSafeString(&student.SecondInterest),
-			getSafeString(&student.ThirdInterest),
+		return err
+	}
+	columns, err := export.ParseColumns(c.Query("columns"))
+	if err != nil {
+		return err
+	}
+
+	c.Set("Content-Type", format.ContentType())
+	c.Set("Content-Disposition", "attachment; filename="+filename+"."+format.Extension())
+	if format.Compressible() {
+		c.Set("Content-Encoding", "gzip")
+	}
+
+	// The body is streamed after the handler returns, when the request context is already done. Keep its
+	// values (request ID, trace) but give the export its own deadline, so a stuck client cannot hold a
+	// database connection forever.
+	dashboardUsecase := h.Usecase
+	requestCtx := context.WithoutCancel(c.UserContext())
+	timeout := h.StreamTimeout
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithTimeout(requestCtx, timeout)
+		defer cancel()
+
+		var out io.Writer = w
+		var gz *gzip.Writer
+		if format.Compressible() {
+			gz = gzip.NewWriter(w)
+			out = gz
 		}
-		if err := writer.Write(row); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to write CSV row",
-			})
+		if err := dashboardUsecase.ExportStudents(ctx, out, filter, format, columns); err != nil {
+			slog.ErrorContext(ctx, "Failed to export students", "error", err)
 		}
-	}
+		if gz != nil {
+			gz.Close()
+		}
+	})
 
 	return nil
 }
 
-// Helper function สำหรับจัดการค่า Null
-func getSafeString(s *string) string {
-	if s != nil {
-		return *s
+// scopedFaculty applies the caller's faculty scope to the requested faculty.
+// Faculty staff get their own faculty and may not ask for another; everyone else gets what they asked for.
+func scopedFaculty(c *fiber.Ctx, requested string) (string, error) {
+	scope := facultyScope(c)
+	if scope == nil {
+		return requested, nil
+	}
+	if *scope == "" {
+		return "", domain.ErrNoFacultyAssigned
+	}
+	if requested != "" && requested != *scope {
+		return "", domain.ErrOtherFacultyData
+	}
+	return *scope, nil
+}
+
+// facultyScope returns the faculty the authenticated user is restricted to, or nil for central staff and admins
+func facultyScope(c *fiber.Ctx) *string {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		return nil
 	}
-	return ""
+	if faculty, scoped := user.FacultyScope(); scoped {
+		return &faculty
+	}
+	return nil
 }
 
 func (h *DashBoardHandler) GetAttendedCount(c *fiber.Ctx) error {
-	results, err := h.Usecase.GetAttendedCount()
+	results, err := h.Usecase.GetAttendedCount(c.UserContext(), facultyScope(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(results)
+}
+
+// GetProvinceCount returns the top provinces by registrations, with the rest grouped as "other".
+func (h *DashBoardHandler) GetProvinceCount(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", usecase.DefaultProvinceLimit)
+	results, err := h.Usecase.GetProvinceCount(c.UserContext(), facultyScope(c), limit)
+	if err != nil {
+		return err
+	}
+	return c.JSON(results)
+}
+
+// GetProvinceGeo returns the province breakdown keyed by Thai province code.
+func (h *DashBoardHandler) GetProvinceGeo(c *fiber.Ctx) error {
+	results, err := h.Usecase.GetProvinceGeo(c.UserContext(), facultyScope(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(results)
+}
+
+// GetSchoolCount returns the top schools by registrations, with the rest grouped as "other".
+func (h *DashBoardHandler) GetSchoolCount(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", usecase.DefaultSchoolLimit)
+	results, err := h.Usecase.GetSchoolCount(c.UserContext(), facultyScope(c), limit)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 	return c.JSON(results)
 }
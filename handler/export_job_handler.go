@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/middleware"
+	"github.com/isd-sgcu/oph-67-backend/usecase"
+)
+
+type ExportJobHandler struct {
+	Usecase *usecase.ExportJobUsecase
+}
+
+func NewExportJobHandler(usecase *usecase.ExportJobUsecase) *ExportJobHandler {
+	return &ExportJobHandler{Usecase: usecase}
+}
+
+// SubmitExportJob queues a student export. An empty faculty exports everyone and needs the
+// full export permission; faculty staff are always pinned to their own faculty.
+func (h *ExportJobHandler) SubmitExportJob(c *fiber.Ctx) error {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		return domain.ErrUnauthorized
+	}
+
+	var req domain.ExportJobRequest
+	if err := c.BodyParser(&req); err != nil {
+		return domain.InvalidInput("Invalid input")
+	}
+
+	faculty, err := scopedFaculty(c, req.Faculty)
+	if err != nil {
+		return err
+	}
+	req.Faculty = faculty
+
+	permission := domain.PermissionExportStudents
+	if req.Faculty != "" {
+		permission = domain.PermissionExportFacultyStudents
+	}
+	if !user.HasPermission(permission) {
+		return domain.ErrForbidden
+	}
+
+	job, err := h.Usecase.Submit(c.UserContext(), user.ID, req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// GetExportJob returns the status of an export job and, once done, a signed download link.
+// Only the requester and admins can see a job.
+func (h *ExportJobHandler) GetExportJob(c *fiber.Ctx) error {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		return domain.ErrUnauthorized
+	}
+
+	job, err := h.Usecase.Get(c.UserContext(), c.Params("id"), user)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(job)
+}
+
+// DownloadExport serves a finished export file. The link itself is the credential.
+func (h *ExportJobHandler) DownloadExport(c *fiber.Ctx) error {
+	expires := int64(c.QueryInt("expires"))
+	job, err := h.Usecase.Download(c.UserContext(), c.Params("id"), c.Query("kid"), expires, c.Query("signature"))
+	if err != nil {
+		return err
+	}
+
+	return c.Download(job.FilePath, h.Usecase.FileName(job))
+}
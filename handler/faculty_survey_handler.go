@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/middleware"
+	"github.com/isd-sgcu/oph-67-backend/usecase"
+)
+
+type FacultySurveyHandler struct {
+	Usecase *usecase.FacultySurveyUsecase
+}
+
+func NewFacultySurveyHandler(usecase *usecase.FacultySurveyUsecase) *FacultySurveyHandler {
+	return &FacultySurveyHandler{Usecase: usecase}
+}
+
+// GetPendingSurveys returns the faculty surveys the authenticated student can still answer.
+func (h *FacultySurveyHandler) GetPendingSurveys(c *fiber.Ctx) error {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		return domain.ErrUnauthorized
+	}
+	pending, err := h.Usecase.GetPending(c.UserContext(), user.ID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(pending)
+}
+
+// SubmitSurvey stores the authenticated student's answers to a faculty survey.
+// The body has the same shape as an evaluation: {"answers": {...}} or the answers at the top level.
+func (h *FacultySurveyHandler) SubmitSurvey(c *fiber.Ctx) error {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		return domain.ErrUnauthorized
+	}
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return domain.InvalidInput("Invalid questionnaire ID")
+	}
+	answers, err := parseAnswers(c)
+	if err != nil {
+		return err
+	}
+
+	response, err := h.Usecase.Submit(c.UserContext(), user.ID, id, answers)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// GetFacultyQuestionnaires returns every version of a faculty's survey.
+// Faculty staff always get their own faculty; central staff and admins choose with ?faculty=.
+func (h *FacultySurveyHandler) GetFacultyQuestionnaires(c *fiber.Ctx) error {
+	faculty, err := surveyFaculty(c)
+	if err != nil {
+		return err
+	}
+	questionnaires, err := h.Usecase.GetQuestionnaires(c.UserContext(), faculty)
+	if err != nil {
+		return err
+	}
+	return c.JSON(questionnaires)
+}
+
+// CreateFacultyQuestionnaire stores a new, inactive version of a faculty's survey.
+func (h *FacultySurveyHandler) CreateFacultyQuestionnaire(c *fiber.Ctx) error {
+	faculty, err := surveyFaculty(c)
+	if err != nil {
+		return err
+	}
+	questionnaire := new(domain.Questionnaire)
+	if err := c.BodyParser(questionnaire); err != nil {
+		return domain.InvalidInput("Invalid input")
+	}
+
+	if err := h.Usecase.CreateQuestionnaire(c.UserContext(), faculty, questionnaire); err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(questionnaire)
+}
+
+// ActivateFacultyQuestionnaire makes a version the faculty's active survey.
+func (h *FacultySurveyHandler) ActivateFacultyQuestionnaire(c *fiber.Ctx) error {
+	faculty, err := surveyFaculty(c)
+	if err != nil {
+		return err
+	}
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return domain.InvalidInput("Invalid questionnaire ID")
+	}
+	if err := h.Usecase.Activate(c.UserContext(), faculty, id); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetFacultySurveyResults returns the responses to one version of a faculty's survey.
+func (h *FacultySurveyHandler) GetFacultySurveyResults(c *fiber.Ctx) error {
+	faculty, err := surveyFaculty(c)
+	if err != nil {
+		return err
+	}
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return domain.InvalidInput("Invalid questionnaire ID")
+	}
+	results, err := h.Usecase.GetResults(c.UserContext(), faculty, id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(results)
+}
+
+// surveyFaculty resolves the faculty whose surveys are managed
+func surveyFaculty(c *fiber.Ctx) (string, error) {
+	faculty, err := scopedFaculty(c, c.Query("faculty"))
+	if err != nil {
+		return "", err
+	}
+	if faculty == "" {
+		return "", domain.ErrFacultyRequired
+	}
+	return faculty, nil
+}
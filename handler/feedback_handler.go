@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/usecase"
+)
+
+const (
+	defaultFeedbackPageSize = 50
+	defaultKeywordLimit     = 30
+)
+
+type FeedbackHandler struct {
+	Usecase *usecase.FeedbackUsecase
+}
+
+func NewFeedbackHandler(usecase *usecase.FeedbackUsecase) *FeedbackHandler {
+	return &FeedbackHandler{Usecase: usecase}
+}
+
+// GetSuggestions lists free-text answers, optionally filtered by tag and a case-insensitive search.
+func (h *FeedbackHandler) GetSuggestions(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", defaultFeedbackPageSize)
+	offset := c.QueryInt("offset", 0)
+	if limit <= 0 || offset < 0 {
+		return domain.InvalidInput("Invalid limit or offset")
+	}
+
+	page, err := h.Usecase.GetTextAnswers(c.UserContext(), c.Query("question", domain.SuggestionQuestion), c.Query("tag"), c.Query("q"), limit, offset)
+	if err != nil {
+		return err
+	}
+	return c.JSON(page)
+}
+
+// TagSuggestion replaces the tags of one answer.
+func (h *FeedbackHandler) TagSuggestion(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return domain.InvalidInput("Invalid answer ID")
+	}
+	req := new(domain.TagRequest)
+	if err := c.BodyParser(req); err != nil {
+		return domain.InvalidInput("Invalid input")
+	}
+
+	tags, err := h.Usecase.TagAnswer(c.UserContext(), id, req.Tags)
+	if err != nil {
+		return err
+	}
+	return c.JSON(domain.TagRequest{Tags: tags})
+}
+
+// GetKeywords returns the most frequent keywords and phrases of a free-text question.
+func (h *FeedbackHandler) GetKeywords(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", defaultKeywordLimit)
+	if limit <= 0 {
+		return domain.InvalidInput("Invalid limit")
+	}
+
+	frequencies, err := h.Usecase.GetKeywords(c.UserContext(), c.Query("question", domain.SuggestionQuestion), limit)
+	if err != nil {
+		return err
+	}
+	return c.JSON(frequencies)
+}
+
+// GetBooths returns the booth catalog answers are grouped under.
+func (h *FeedbackHandler) GetBooths(c *fiber.Ctx) error {
+	booths, err := h.Usecase.GetBooths(c.UserContext())
+	if err != nil {
+		return err
+	}
+	return c.JSON(booths)
+}
+
+// CreateBooth adds a booth to the catalog.
+func (h *FeedbackHandler) CreateBooth(c *fiber.Ctx) error {
+	booth := new(domain.Booth)
+	if err := c.BodyParser(booth); err != nil || booth.Name == "" {
+		return domain.InvalidInput("name is required")
+	}
+	booth.ID = 0
+
+	if err := h.Usecase.CreateBooth(c.UserContext(), booth); err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(booth)
+}
+
+// GetBoothGroups groups the spellings of a booth question under catalog entries.
+func (h *FeedbackHandler) GetBoothGroups(c *fiber.Ctx) error {
+	groups, err := h.Usecase.GroupBoothAnswers(c.UserContext(), c.Query("question", domain.FavoriteBoothQuestion))
+	if err != nil {
+		return err
+	}
+	return c.JSON(groups)
+}
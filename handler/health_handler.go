@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/usecase"
+)
+
+type HealthHandler struct {
+	Usecase *usecase.HealthUsecase
+}
+
+func NewHealthHandler(usecase *usecase.HealthUsecase) *HealthHandler {
+	return &HealthHandler{Usecase: usecase}
+}
+
+// Liveness reports that the process is up and serving requests; it does not touch dependencies.
+func (h *HealthHandler) Liveness(c *fiber.Ctx) error {
+	return c.JSON(domain.HealthResponse{Status: domain.HealthOK})
+}
+
+// Readiness reports whether the instance can take traffic, with 503 and the failing checks if not.
+func (h *HealthHandler) Readiness(c *fiber.Ctx) error {
+	response, ready := h.Usecase.Readiness(c.UserContext())
+	if !ready {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(response)
+	}
+	return c.JSON(response)
+}
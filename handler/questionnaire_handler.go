@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/usecase"
+)
+
+type QuestionnaireHandler struct {
+	Usecase *usecase.QuestionnaireUsecase
+}
+
+func NewQuestionnaireHandler(usecase *usecase.QuestionnaireUsecase) *QuestionnaireHandler {
+	return &QuestionnaireHandler{Usecase: usecase}
+}
+
+// GetActiveQuestionnaire returns the form new evaluations are validated against.
+func (h *QuestionnaireHandler) GetActiveQuestionnaire(c *fiber.Ctx) error {
+	questionnaire, err := h.Usecase.GetActive(c.UserContext())
+	if err != nil {
+		return err
+	}
+	return c.JSON(questionnaire)
+}
+
+// GetAllQuestionnaires returns every version of the form, newest first.
+func (h *QuestionnaireHandler) GetAllQuestionnaires(c *fiber.Ctx) error {
+	questionnaires, err := h.Usecase.GetAll(c.UserContext())
+	if err != nil {
+		return err
+	}
+	return c.JSON(questionnaires)
+}
+
+// GetQuestionnaireById returns one version of the form.
+func (h *QuestionnaireHandler) GetQuestionnaireById(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return domain.InvalidInput("Invalid questionnaire ID")
+	}
+	questionnaire, err := h.Usecase.GetById(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(questionnaire)
+}
+
+// CreateQuestionnaire stores a new, inactive version of the form.
+func (h *QuestionnaireHandler) CreateQuestionnaire(c *fiber.Ctx) error {
+	questionnaire := new(domain.Questionnaire)
+	if err := c.BodyParser(questionnaire); err != nil {
+		return domain.InvalidInput("Invalid input")
+	}
+
+	if err := h.Usecase.Create(c.UserContext(), questionnaire); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(questionnaire)
+}
+
+// ActivateQuestionnaire makes a version the active form.
+func (h *QuestionnaireHandler) ActivateQuestionnaire(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return domain.InvalidInput("Invalid questionnaire ID")
+	}
+	if err := h.Usecase.Activate(c.UserContext(), id); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// UpdateQuestionnaireSettings changes whether a version only accepts students who entered the event.
+func (h *QuestionnaireHandler) UpdateQuestionnaireSettings(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return domain.InvalidInput("Invalid questionnaire ID")
+	}
+	settings := new(domain.QuestionnaireSettingsRequest)
+	if err := c.BodyParser(settings); err != nil || settings.RequireAttendance == nil {
+		return domain.InvalidInput("requireAttendance is required")
+	}
+	if err := h.Usecase.SetRequireAttendance(c.UserContext(), id, *settings.RequireAttendance); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -1,12 +1,13 @@
 package handler
 
 import (
-	"strings"
+	"encoding/json"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/middleware"
 	"github.com/isd-sgcu/oph-67-backend/usecase"
-	"github.com/isd-sgcu/oph-67-backend/utils"
+	"github.com/isd-sgcu/oph-67-backend/validation"
 )
 
 type StudentEvaluationHandler struct {
@@ -17,60 +18,59 @@ func NewStudentEvaluationHandler(usecase *usecase.StudentEvaluationUsecase) *Stu
 	return &StudentEvaluationHandler{Usecase: usecase}
 }
 
-// CreateStudentEvaluation creates a new student evaluation.
+// CreateStudentEvaluation creates a new student evaluation from answers keyed by question key.
+// The body is either {"answers": {...}} or, for version 1 clients, the answers at the top level.
 func (h *StudentEvaluationHandler) CreateStudentEvaluation(c *fiber.Ctx) error {
-	var evaluation domain.StudentEvaluation
 	// Get student ID from authenticated user
-	authHeader := c.Get("Authorization")
-	if authHeader == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Error: "Missing token"})
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		return domain.ErrUnauthorized
 	}
-	userId, err := utils.DecodeToken(strings.TrimPrefix(authHeader, "Bearer "), utils.GetEnv("SECRET_JWT_KEY", ""))
+	answers, err := parseAnswers(c)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Error: "Invalid token"})
-	}
-	if err := c.BodyParser(&evaluation); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
-	}
-
-	err = h.Usecase.CreateStudentEvaluation(&domain.StudentEvaluation{
-		StudentId:                         userId,
-		NewSources:                        evaluation.NewSources,
-		OverallActivity:                   evaluation.OverallActivity,
-		InterestActivity:                  evaluation.InterestActivity,
-		ReceivedFacultyInfoClearly:        evaluation.ReceivedFacultyInfoClearly,
-		WouldRecommendCUOpenHouseNextTime: evaluation.WouldRecommendCUOpenHouseNextTime,
-		FavoriteBooth:                     evaluation.FavoriteBooth,
-		ActivityDiversity:                 evaluation.ActivityDiversity,
-		PerceivedCrowdDensity:             evaluation.PerceivedCrowdDensity,
-		HasFullBoothAccess:                evaluation.HasFullBoothAccess,
-		FacilityConvenienceRating:         evaluation.FacilityConvenienceRating,
-		CampusNavigationRating:            evaluation.CampusNavigationRating,
-		HesitationLevelAfterDisaster:      evaluation.HesitationLevelAfterDisaster,
-		LineOASignupRating:                evaluation.LineOASignupRating,
-		DesignBeautyRating:                evaluation.DesignBeautyRating,
-		WebsiteImprovementSuggestions:     evaluation.WebsiteImprovementSuggestions,
-	})
+		return err
+	}
+
+	evaluation, err := h.Usecase.CreateStudentEvaluation(c.UserContext(), user.ID, answers)
 	if err != nil {
-		if err == domain.ErrStudentEvaluationAlreadyExists {
-			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Student evaluation already exists"})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(evaluation)
 }
 
+// parseAnswers reads and validates the answers map of the request body
+func parseAnswers(c *fiber.Ctx) (map[string]json.RawMessage, error) {
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(c.Body(), &body); err != nil {
+		return nil, domain.InvalidInput("Body must be a JSON object")
+	}
+
+	request := new(domain.EvaluationRequest)
+	if _, ok := body["answers"]; ok {
+		if err := json.Unmarshal(c.Body(), request); err != nil {
+			return nil, decodeError(err)
+		}
+	} else {
+		// Version 1 clients send the answers at the top level
+		request.Answers = body
+	}
+	if err := validation.Struct(request); err != nil {
+		return nil, err
+	}
+	return request.Answers, nil
+}
+
 // GetStudentEvaluationByStudentId retrieves a student evaluation by student ID.
 func (h *StudentEvaluationHandler) GetStudentEvaluationByStudentId(c *fiber.Ctx) error {
 	studentId := c.Params("id")
 	if studentId == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Student ID is required"})
+		return domain.InvalidInput("Student ID is required")
 	}
 
-	evaluation, err := h.Usecase.GetStudentEvaluationByStudentId(studentId)
+	evaluation, err := h.Usecase.GetStudentEvaluationByStudentId(c.UserContext(), studentId)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Student evaluation not found"})
+		return err
 	}
 
 	return c.JSON(evaluation)
@@ -78,30 +78,28 @@ func (h *StudentEvaluationHandler) GetStudentEvaluationByStudentId(c *fiber.Ctx)
 
 // GetAllStudentEvaluations retrieves all student evaluations.
 func (h *StudentEvaluationHandler) GetAllStudentEvaluations(c *fiber.Ctx) error {
-	evaluations, err := h.Usecase.GetAllStudentEvaluations()
+	evaluations, err := h.Usecase.GetAllStudentEvaluations(c.UserContext())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 	return c.JSON(evaluations)
 }
 
-// UpdateStudentEvaluation updates an existing student evaluation.
+// UpdateStudentEvaluation replaces the answers of an existing student evaluation.
 func (h *StudentEvaluationHandler) UpdateStudentEvaluation(c *fiber.Ctx) error {
 	studentId := c.Params("id")
 	if studentId == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Student ID is required"})
+		return domain.InvalidInput("Student ID is required")
 	}
 
-	var evaluation domain.StudentEvaluation
-	if err := c.BodyParser(&evaluation); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	answers, err := parseAnswers(c)
+	if err != nil {
+		return err
 	}
 
-	evaluation.StudentId = studentId
-
-	err := h.Usecase.UpdateStudentEvaluation(&evaluation)
+	evaluation, err := h.Usecase.UpdateStudentEvaluation(c.UserContext(), studentId, answers)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
 	return c.JSON(evaluation)
@@ -111,13 +109,33 @@ func (h *StudentEvaluationHandler) UpdateStudentEvaluation(c *fiber.Ctx) error {
 func (h *StudentEvaluationHandler) DeleteStudentEvaluation(c *fiber.Ctx) error {
 	studentId := c.Params("id")
 	if studentId == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Student ID is required"})
+		return domain.InvalidInput("Student ID is required")
 	}
 
-	err := h.Usecase.DeleteStudentEvaluation(studentId)
+	err := h.Usecase.DeleteStudentEvaluation(c.UserContext(), studentId)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
+
+// GetEvaluationAnalytics returns rating distributions, means, medians and the NPS-style score.
+// ?by=status or ?by=faculty breaks them down by visitor status or by faculties visited;
+// faculty staff only see the students scanned at their own faculty, whatever the breakdown.
+func (h *StudentEvaluationHandler) GetEvaluationAnalytics(c *fiber.Ctx) error {
+	groupBy := domain.EvaluationGroupBy(c.Query("by"))
+
+	results, err := h.Usecase.GetEvaluationAnalytics(c.UserContext(), groupBy, facultyScope(c))
+	if err != nil {
+		return err
+	}
+
+	if groupBy == domain.GroupByNone {
+		if len(results) == 0 {
+			return c.JSON(domain.EvaluationAnalytics{Questions: []domain.QuestionStats{}})
+		}
+		return c.JSON(results[0])
+	}
+	return c.JSON(results)
+}
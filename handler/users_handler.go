@@ -1,16 +1,18 @@
 package handler
 
 import (
-	"errors"
-	"strconv"
+	"fmt"
 	"strings"
-	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/middleware"
 	"github.com/isd-sgcu/oph-67-backend/usecase"
-	"github.com/isd-sgcu/oph-67-backend/utils"
-	"github.com/lib/pq"
+)
+
+const (
+	defaultUserPageSize = 50
+	maxUserPageSize     = 200
 )
 
 // UserHandler represents the handler for user-related endpoints
@@ -26,8 +28,8 @@ func NewUserHandler(usecase *usecase.UserUsecase, evalUsecase *usecase.StudentEv
 
 // Register Staff godoc
 // @Summary Register a new user
-// @Description Register a new user in the system
-// @Accept  multipart/form-data
+// @Description Register a new user in the system. They are a member until an admin adds them as staff.
+// @Accept  multipart/form-data,json
 // @Produce  json
 // @Param id formData string true "ID"
 // @Param name formData string true "Name"
@@ -37,87 +39,21 @@ func NewUserHandler(usecase *usecase.UserUsecase, evalUsecase *usecase.StudentEv
 // @Param Year	formData  int  true "true"
 // @Param Nickname formData string true "Nickname"
 // @Param StudentID formData string true "StudentID"
-// @Param IsCentralStaff formData boolean true "IsCentralStaff"
 // @Success 201 {object} domain.TokenResponse
 // @Failure 400 {object} domain.ErrorResponse "Invalid input"
 // @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 422 {object} domain.ErrorResponse "Rejected fields"
 // @Failure 500 {object} domain.ErrorResponse "Failed to create user"
 // @Router /api/staff/register [post]
 func (h *UserHandler) StaffRegister(c *fiber.Ctx) error {
-	form, err := c.MultipartForm()
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Error: "Invalid input"})
+	request := new(domain.StaffRegisterRequest)
+	if err := bind(c, request); err != nil {
+		return err
 	}
 
-	// Helper function to get required form values
-	getFormValue := func(key string) (string, bool) {
-		if v, ok := form.Value[key]; ok && len(v) > 0 {
-			return v[0], true
-		}
-		return "", false
-	}
-
-	// Helper function for optional values
-	getOptionalValue := func(key string) *string {
-		if v, ok := form.Value[key]; ok && len(v) > 0 {
-			return &v[0]
-		}
-		return nil
-	}
-
-	// Initialize user data
-	userData := make(map[string]string)
-
-	// Validate phone number
-	if phone, ok := getFormValue("phone"); ok {
-		if !utils.IsValidPhone(phone) {
-			return c.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Error: "Invalid phone number format"})
-		}
-		userData["phone"] = phone
-	} else {
-		return c.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Error: "Phone is required"})
-	}
-
-	// Validate required fields
-	requiredFields := []string{"id", "name", "phone", "email"}
-	for _, field := range requiredFields {
-		if value, ok := getFormValue(field); ok {
-			userData[field] = value
-		} else {
-			return c.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Error: field + " is required"})
-		}
-	}
-
-	user := &domain.User{
-		ID:        userData["id"],
-		Name:      userData["name"],
-		Nickname:  getOptionalValue("nickname"),
-		StudentID: getOptionalValue("studentId"),
-		Role:      domain.Staff,
-		Email:     userData["email"],
-		Phone:     userData["phone"],
-		Faculty:   getOptionalValue("faculty"),
-		Year: func() *int {
-			if yearStr := getOptionalValue("year"); yearStr != nil {
-				if year, err := strconv.Atoi(*yearStr); err == nil {
-					return &year
-				}
-			}
-			return nil
-		}(),
-		IsCentralStaff: func() *bool {
-			if isCentralStaffStr := getOptionalValue("isCentralStaff"); isCentralStaffStr != nil {
-				if isCentralStaff, err := strconv.ParseBool(*isCentralStaffStr); err == nil {
-					return &isCentralStaff
-				}
-			}
-			return nil
-		}(),
-	}
-
-	tokenResponse, err := h.Usecase.Register(user)
+	tokenResponse, err := h.Usecase.Register(c.UserContext(), request.User())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Error: "Failed to create user"})
+		return err
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(tokenResponse)
@@ -126,7 +62,7 @@ func (h *UserHandler) StaffRegister(c *fiber.Ctx) error {
 // Register Student godoc
 // @Summary Register a new user
 // @Description Register a new user in the system
-// @Accept  multipart/form-data
+// @Accept  multipart/form-data,json
 // @Produce  json
 // @Param id formData string true "ID"
 // @Param name formData string true "Name"
@@ -146,164 +82,139 @@ func (h *UserHandler) StaffRegister(c *fiber.Ctx) error {
 // @Success 201 {object} domain.TokenResponse
 // @Failure 400 {object} domain.ErrorResponse "Invalid input"
 // @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 422 {object} domain.ErrorResponse "Rejected fields"
 // @Failure 500 {object} domain.ErrorResponse "Failed to create user"
 // @Router /api/student/register [post]
 func (h *UserHandler) StudentRegister(c *fiber.Ctx) error {
-	form, err := c.MultipartForm()
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Error: "Invalid input"})
+	request := new(domain.StudentRegisterRequest)
+	if err := bind(c, request); err != nil {
+		return err
 	}
 
-	// Helper function to get required form values
-	getFormValue := func(key string) (string, bool) {
-		if v, ok := form.Value[key]; ok && len(v) > 0 {
-			return v[0], true
-		}
-		return "", false
-	}
-
-	// Helper function for optional values
-	getOptionalValue := func(key string) *string {
-		if v, ok := form.Value[key]; ok && len(v) > 0 {
-			return &v[0]
-		}
-		return nil
-	}
-
-	// Initialize user data
-	userData := make(map[string]string)
-
-	// Validate phone number
-	if phone, ok := getFormValue("phone"); ok {
-		if !utils.IsValidPhone(phone) {
-			return c.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Error: "Invalid phone number format"})
-		}
-		userData["phone"] = phone
-	} else {
-		return c.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Error: "Phone is required"})
-	}
-
-	// Validate required fields
-	requiredFields := []string{"id", "name", "phone", "email"}
-	for _, field := range requiredFields {
-		if value, ok := getFormValue(field); ok {
-			userData[field] = value
-		} else {
-			return c.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Error: field + " is required"})
-		}
-	}
-
-	selectedSources := getOptionalValue("selectedSources")
-	var selectedSourcesArray *pq.StringArray
-
-	if selectedSources != nil && *selectedSources != "" {
-		arr := pq.StringArray(strings.Split(*selectedSources, ","))
-		selectedSourcesArray = &arr
-	} else {
-		selectedSourcesArray = nil // or &pq.StringArray{} if you prefer an empty array
-	}
-
-	user := &domain.User{
-		ID:    userData["id"],
-		Name:  userData["name"],
-		Role:  domain.Student,
-		Email: userData["email"],
-		Phone: userData["phone"],
-		BirthDate: func() *time.Time {
-			if birthDate := getOptionalValue("birthDate"); birthDate != nil {
-				if parsedDate, err := time.Parse("2006-01-02", *birthDate); err == nil {
-					return &parsedDate
-				}
-			}
-			return nil
-		}(),
-
-		Status:          getOptionalValue("status"),
-		OtherStatus:     getOptionalValue("otherStatus"),
-		Province:        getOptionalValue("province"),
-		School:          getOptionalValue("school"),
-		SelectedSources: selectedSourcesArray,
-		OtherSource:     getOptionalValue("otherSource"),
-		FirstInterest:   getOptionalValue("firstInterest"),
-		SecondInterest:  getOptionalValue("secondInterest"),
-		ThirdInterest:   getOptionalValue("thirdInterest"),
-		Objective:       getOptionalValue("objective"),
-	}
-
-	tokenResponse, err := h.Usecase.Register(user)
+	tokenResponse, err := h.Usecase.Register(c.UserContext(), request.User())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Error: "Failed to create user"})
+		return err
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(tokenResponse)
 }
 
 // GetAll godoc
-// @Summary Get all student
-// @Description Retrieve a list of all users with optional filtering
+// @Summary List users
+// @Description List users a page at a time, filtered and sorted in the database. Pass the nextCursor of a page as cursor to get the next one.
 // @Produce  json
+// @Param q query string false "Search the name and school, and the phone, email and UID for staff allowed to export students"
 // @Param name query string false "Filter by name"
 // @Param role query string false "Filter by role"
+// @Param sort query string false "registeredAt, lastEntered, name or id; prefix with - for descending (default -registeredAt)"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Param limit query int false "Page size, 1 to 200 (default 50)"
 // @security BearerAuth
-// @Success 200 {array} domain.User
+// @Success 200 {object} domain.UserPage "With contacts, for staff allowed to export students"
+// @Success 200 {object} domain.UserListPage "Without phone, email and UID, for faculty staff"
+// @Failure 400 {object} domain.ErrorResponse "Invalid query"
 // @Failure 500 {object} domain.ErrorResponse "Failed to fetch users"
 // @Router /api/users [get]
 func (h *UserHandler) GetAll(c *fiber.Ctx) error {
-	// Get query parameters
-	filter := c.Query("name")
-	role := c.Query("role")
+	limit := c.QueryInt("limit", defaultUserPageSize)
+	if limit <= 0 || limit > maxUserPageSize {
+		return domain.InvalidInput(fmt.Sprintf("limit must be between 1 and %d", maxUserPageSize))
+	}
+	order, err := domain.ParseUserOrder(c.Query("sort"))
+	if err != nil {
+		return err
+	}
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		return domain.ErrUnauthorized
+	}
+	// Contacts are student PII, so only staff allowed to export students may search or see them
+	withContacts := user.HasPermission(domain.PermissionExportStudents)
+	filter := domain.UserFilter{
+		Search:         c.Query("q"),
+		Name:           c.Query("name"),
+		Role:           domain.Role(c.Query("role")),
+		SearchContacts: withContacts,
+	}
 
-	users, err := h.Usecase.GetAll(filter, domain.Role(role))
+	page, err := h.Usecase.List(c.UserContext(), filter, order, c.Query("cursor"), limit)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Error: "Failed to fetch users"})
+		return err
 	}
 
-	return c.Status(fiber.StatusOK).JSON(users)
+	if !withContacts {
+		return c.Status(fiber.StatusOK).JSON(page.WithoutContacts())
+	}
+	return c.Status(fiber.StatusOK).JSON(page)
 }
 
 // GetById godoc
 // @Summary Get user by ID
-// @Description Retrieve a user by its ID
+// @Description Retrieve a user by its ID. Users get their own account; admins and staff allowed to export
+// @Description students get anyone's. Other staff get the user without phone, email and UID.
 // @Accept  json
 // @Produce  json
 // @security BearerAuth
 // @Param id path string true "User ID"
 // @Success 200 {object} domain.User
+// @Failure 403 {object} domain.ErrorResponse "Not the caller's account"
 // @Failure 404 {object} domain.ErrorResponse "User not found"
 // @Failure 500 {object} domain.ErrorResponse "Failed to fetch user"
 // @Router /api/users/{id} [get]
 func (h *UserHandler) GetById(c *fiber.Ctx) error {
+	caller, ok := middleware.CurrentUser(c)
+	if !ok {
+		return domain.ErrUnauthorized
+	}
 	id := c.Params("id")
-	user, err := h.Usecase.GetById(id)
+	// Same rule as the listing: contacts are student PII
+	withContacts := caller.ID == id || caller.HasPermission(domain.PermissionExportStudents)
+	if !withContacts && caller.Role != domain.Staff {
+		return domain.ErrForbidden
+	}
+
+	user, err := h.Usecase.GetById(c.UserContext(), id)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(domain.ErrorResponse{Error: "User not found"})
+		return err
+	}
+	if !withContacts {
+		return c.Status(fiber.StatusOK).JSON(user.WithoutContacts())
 	}
 	return c.Status(fiber.StatusOK).JSON(user)
 }
 
 // Update godoc
 // @Summary Update user by ID
-// @Description Update a user by its ID
+// @Description Update the caller's own profile; admins may update any user
 // @Accept  json
 // @Produce  json
 // @security BearerAuth
 // @Param id path string true "User ID"
-// @Param user body domain.User true "User data"
+// @Param user body domain.UpdateUserRequest true "User data"
 // @Success 204
 // @Failure 400 {object} domain.ErrorResponse "Invalid input"
 // @Failure 401 {object} domain.ErrorResponse "Unauthorized"
 // @Failure 403 {object} domain.ErrorResponse "Forbidden"
 // @Failure 404 {object} domain.ErrorResponse "User not found"
+// @Failure 422 {object} domain.ErrorResponse "Rejected fields"
 // @Failure 500 {object} domain.ErrorResponse "Failed to update user"
 // @Router /api/users/{id} [patch]
 func (h *UserHandler) Update(c *fiber.Ctx) error {
 	id := c.Params("id")
-	user := new(domain.User)
-	if err := c.BodyParser(user); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Error: "Invalid input"})
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		return domain.ErrUnauthorized
 	}
-	if err := h.Usecase.Update(id, user); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Error: "Failed to update user"})
+	if user.ID != id && user.Role != domain.Admin {
+		return domain.ErrForbidden
+	}
+
+	request := new(domain.UpdateUserRequest)
+	if err := bind(c, request); err != nil {
+		return err
+	}
+	if err := h.Usecase.Update(c.UserContext(), id, request.User()); err != nil {
+		return err
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
@@ -324,23 +235,19 @@ func (h *UserHandler) ScanQR(c *fiber.Ctx) error {
 	studentId := c.Params("id")
 	authHeader := c.Get("Authorization")
 	if authHeader == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Error: "Missing token"})
+		return domain.ErrUnauthorized.WithMessage("Missing token")
 	}
 
 	// Extract staff ID from JWT token
-	staffId, err := utils.DecodeToken(strings.TrimPrefix(authHeader, "Bearer "), utils.GetEnv("SECRET_JWT_KEY", ""))
+	staffId, err := h.Usecase.DecodeToken(strings.TrimPrefix(authHeader, "Bearer "))
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Error: "Unauthorized"})
+		return domain.ErrInvalidToken
 	}
 
 	// Call use case with both student and staff IDs
-	user, err := h.Usecase.ScanQR(studentId, staffId)
+	user, err := h.Usecase.ScanQR(c.UserContext(), studentId, staffId)
 	if err != nil {
-		if errors.Is(err, domain.ErrUserAlreadyEntered) {
-			t := user.LastEntered.String()
-			return c.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Error: "User has already entered", Message: &t})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Error: "Failed to scan QR"})
+		return err
 	}
 
 	return c.Status(fiber.StatusOK).JSON(user)
@@ -360,52 +267,17 @@ func (h *UserHandler) ScanQR(c *fiber.Ctx) error {
 // @Failure 401 {object} domain.ErrorResponse "Unauthorized"
 // @Failure 403 {object} domain.ErrorResponse "Forbidden"
 // @Failure 404 {object} domain.ErrorResponse "User not found"
+// @Failure 422 {object} domain.ErrorResponse "Rejected fields"
 // @Failure 500 {object} domain.ErrorResponse "Failed to update user role"
 // @Router /api/users/role/{id} [patch]
 func (h *UserHandler) UpdateRole(c *fiber.Ctx) error {
 	id := c.Params("id")
 	role := new(domain.RoleRequest)
-	if err := c.BodyParser(role); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Error: "Invalid input"})
-	}
-	if err := h.Usecase.UpdateRole(id, domain.Role(role.Role)); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Error: "Failed to update this role user"})
-	}
-
-	return c.SendStatus(fiber.StatusNoContent)
-}
-
-// Update account info godoc
-// @Summary Update Account Info
-// @Description Update a user by its ID
-// @Accept  json
-// @Produce  json
-// @security BearerAuth
-// @Param user body domain.User true "User data"
-// @Success 204
-// @Failure 400 {object} domain.ErrorResponse "Invalid input"
-// @Failure 401 {object} domain.ErrorResponse "Unauthorized"
-// @Failure 403 {object} domain.ErrorResponse "Forbidden"
-// @Failure 404 {object} domain.ErrorResponse "User not found"
-// @Failure 500 {object} domain.ErrorResponse "Failed to update user role"
-// @Router /api/users [patch]
-func (h *UserHandler) UpdateMyAccountInfo(c *fiber.Ctx) error {
-	user := new(domain.User)
-	tokenHeader := c.Get("Authorization")
-	if !strings.HasPrefix(tokenHeader, "Bearer ") {
-		return c.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Error: "Unauthorized"})
-	}
-	token := strings.TrimPrefix(tokenHeader, "Bearer ")
-
-	id, err := utils.DecodeToken(token, utils.GetEnv("SECRET_JWT_KEY", ""))
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Error: "Unauthorized " + err.Error()})
-	}
-	if err := c.BodyParser(user); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Error: "Invalid input"})
+	if err := bind(c, role); err != nil {
+		return err
 	}
-	if err := h.Usecase.Update(id, user); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Error: "Failed to update this role user"})
+	if err := h.Usecase.UpdateRole(c.UserContext(), id, domain.Role(role.Role)); err != nil {
+		return err
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
@@ -423,33 +295,38 @@ func (h *UserHandler) UpdateMyAccountInfo(c *fiber.Ctx) error {
 // @Router /api/users/qr/{id} [get]
 func (h *UserHandler) GetQRURL(c *fiber.Ctx) error {
 	id := c.Params("id")
-	qrURL, err := h.Usecase.GetQRURL(id)
+	qrURL, err := h.Usecase.GetQRURL(c.UserContext(), id)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(domain.ErrorResponse{Error: "User not found"})
+		return err
 	}
 	return c.Status(fiber.StatusOK).JSON(domain.QrResponse{QrURL: qrURL})
 }
 
 // GetCertToken godoc
 // @Summary Get Cert Token
-// @Description Retrieve a cert token for a user
+// @Description Retrieve a name-only cert token for a user. Deprecated: download the certificate from
+// @Description /api/certificates/{event}, whose token has a serial and can be revoked.
 // @Produce  json
 // @security BearerAuth
 // @Param id path string true "User ID"
 // @Success 200 {object} domain.CertTokenResponse
 // @Failure 404 {object} domain.ErrorResponse "Evaluation data not found"
 // @Failure 500 {object} domain.ErrorResponse "Failed to get cert token"
+// @Deprecated
 // @Router /api/users/certToken/{id} [get]
 func (h *UserHandler) GetCertToken(c *fiber.Ctx) error {
+	c.Set("Deprecation", "true")
+	c.Set(fiber.HeaderLink, `</api/certificates/{event}>; rel="successor-version"`)
+
 	id := c.Params("id")
 	// check if user did evaluation form
-	_, err := h.EvaluationUsecase.GetStudentEvaluationByStudentId(id)
+	_, err := h.EvaluationUsecase.GetStudentEvaluationByStudentId(c.UserContext(), id)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(domain.ErrorResponse{Error: "Evaluation data not found"})
+		return err
 	}
-	certToken, err := h.Usecase.GetCertToken(id)
+	certToken, err := h.Usecase.GetCertToken(c.UserContext(), id)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Error: "Failed to get cert token"})
+		return err
 	}
 	return c.Status(fiber.StatusOK).JSON(domain.CertTokenResponse{UserID: id, CertToken: certToken})
 }
@@ -468,8 +345,8 @@ func (h *UserHandler) GetCertToken(c *fiber.Ctx) error {
 // @Router /api/users/{id} [delete]
 func (h *UserHandler) RemoveStaff(c *fiber.Ctx) error {
 	id := c.Params("id")
-	if err := h.Usecase.RemoveStaff(id); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Error: "Failed to delete user"})
+	if err := h.Usecase.RemoveStaff(c.UserContext(), id); err != nil {
+		return err
 	}
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -488,8 +365,8 @@ func (h *UserHandler) RemoveStaff(c *fiber.Ctx) error {
 // @Router /api/admin/users/{id} [delete]
 func (h *UserHandler) Delete(c *fiber.Ctx) error {
 	id := c.Params("id")
-	if err := h.Usecase.Delete(id); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Error: "Failed to delete user"})
+	if err := h.Usecase.Delete(c.UserContext(), id); err != nil {
+		return err
 	}
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -508,12 +385,12 @@ func (h *UserHandler) SignIn(c *fiber.Ctx) error {
 	// recieve id from body in plain text
 	id := new(domain.SignInRequest)
 	if err := c.BodyParser(id); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Error: "Invalid input"})
+		return domain.InvalidInput("Invalid input")
 	}
 
-	tokenResponse, err := h.Usecase.SignIn(id.ID)
+	tokenResponse, err := h.Usecase.SignIn(c.UserContext(), id.ID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Error: "Failed to signin"})
+		return err
 	}
 
 	return c.Status(fiber.StatusOK).JSON(tokenResponse)
@@ -522,20 +399,57 @@ func (h *UserHandler) SignIn(c *fiber.Ctx) error {
 // Add Staff godoc
 // @Summary Add Staff
 // @security BearerAuth
-// @Description Add Staff By phone number
+// @Description Add Staff By phone number, either to the central team or to a faculty. Without a body the user joins the central team.
+// @Accept  json
 // @Produce  json
 // @Param phone path string true "User Phone"
+// @Param request body domain.AddStaffRequest false "Faculty or central team"
 // @Success 204
 // @Failure 400 {object} domain.ErrorResponse "User is already a staff"
+// @Failure 422 {object} domain.ErrorResponse "Rejected fields"
 // @Failure 500 {object} domain.ErrorResponse "Failed to add staff"
 // @Router /api/users/addstaff/{phone} [patch]
 func (h *UserHandler) AddStaff(c *fiber.Ctx) error {
 	phone := c.Params("phone")
-	if err := h.Usecase.AddStaff(phone); err != nil {
-		if errors.Is(err, domain.ErrUserAlreadyStaff) {
-			return c.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Error: "User is already a staff"})
+	// Admin tools written before staff scopes send no body; they keep adding central staff
+	request := &domain.AddStaffRequest{IsCentralStaff: true}
+	if len(c.Body()) > 0 {
+		request = new(domain.AddStaffRequest)
+		if err := bind(c, request); err != nil {
+			return err
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Error: "Failed to add staff"})
+	}
+	if err := h.Usecase.AddStaff(c.UserContext(), phone, request.Faculty, request.IsCentralStaff); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Update Staff godoc
+// @Summary Update staff scope
+// @security BearerAuth
+// @Description Move a staff member to the central team or to a faculty
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param request body domain.AddStaffRequest true "Faculty or central team"
+// @Success 204
+// @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 403 {object} domain.ErrorResponse "Forbidden"
+// @Failure 404 {object} domain.ErrorResponse "User not found"
+// @Failure 409 {object} domain.ErrorResponse "User is not a staff"
+// @Failure 422 {object} domain.ErrorResponse "Rejected fields"
+// @Failure 500 {object} domain.ErrorResponse "Failed to update staff"
+// @Router /api/admin/staff/{id} [patch]
+func (h *UserHandler) UpdateStaff(c *fiber.Ctx) error {
+	id := c.Params("id")
+	request := new(domain.AddStaffRequest)
+	if err := bind(c, request); err != nil {
+		return err
+	}
+	if err := h.Usecase.SetStaffScope(c.UserContext(), id, request.Faculty, request.IsCentralStaff); err != nil {
+		return err
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
@@ -3,29 +3,88 @@ package infrastructure
 import (
 	"fmt"
 	"log"
+	"log/slog"
+	"time"
 
 	"github.com/isd-sgcu/oph-67-backend/config"
-	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/logging"
+	"github.com/isd-sgcu/oph-67-backend/migration"
+	"github.com/isd-sgcu/oph-67-backend/tracing"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-func ConnectDatabase(cfg *config.Config) *gorm.DB {
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		cfg.DBHost, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBPort)
+// maxConnectDelay caps the wait between connection attempts at startup
+const maxConnectDelay = 10 * time.Second
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+func ConnectDatabase(cfg *config.Config, logger *slog.Logger) *gorm.DB {
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=disable",
+		cfg.Database.Host, cfg.Database.User, cfg.Database.Password, cfg.Database.Name, cfg.Database.Port)
+	if cfg.Database.StatementTimeout > 0 {
+		// Sent as a session parameter, so the server aborts runaway statements even if the client is gone
+		dsn += fmt.Sprintf(" statement_timeout=%d", cfg.Database.StatementTimeout.Milliseconds())
+	}
+
+	// The database may start after the server (e.g. with docker compose), so retry with backoff
+	deadline := time.Now().Add(cfg.Database.ConnectTimeout)
+	delay := 500 * time.Millisecond
+	var db *gorm.DB
+	var err error
+	for {
+		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
+			Logger: logging.NewGormLogger(logger, cfg.Log.SlowQuery),
+			// Unique violations come back as gorm.ErrDuplicatedKey, which usecases answer with a 409
+			TranslateError: true,
+		})
+		if err == nil {
+			break
+		}
+		if time.Now().Add(delay).After(deadline) {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		logger.Warn("Database not reachable, retrying", "delay", delay, "error", err)
+		time.Sleep(delay)
+		delay = min(delay*2, maxConnectDelay)
 	}
 
-	log.Println("Successfully connected to the database")
+	if err := db.Use(tracing.NewGormPlugin()); err != nil {
+		log.Fatalf("Failed to install query tracing: %v", err)
+	}
 
-	// Automatically migrate the schema, creating tables if they don't exist
-	err = db.AutoMigrate(&domain.StudentTransaction{}, &domain.User{}, &domain.StudentEvaluation{}) // Add your domain models here
+	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatalf("Failed to auto migrate: %v", err)
+		log.Fatalf("Failed to get database handle: %v", err)
 	}
+	sqlDB.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.Database.ConnMaxIdleTime)
+
+	logger.Info("Successfully connected to the database")
 
 	return db
 }
+
+// NewMigrator returns a migrator for the embedded SQL migrations on db
+func NewMigrator(db *gorm.DB) *migration.Migrator {
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("Failed to get database handle: %v", err)
+	}
+	migrator, err := migration.NewMigrator(sqlDB)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+	return migrator
+}
+
+// MigrateDatabase applies every pending migration. Replicas starting together wait on the migration lock.
+func MigrateDatabase(db *gorm.DB, logger *slog.Logger) {
+	applied, err := NewMigrator(db).Up()
+	if err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	for _, m := range applied {
+		logger.Info("Applied migration", "migration", m.String())
+	}
+}
@@ -0,0 +1,199 @@
+// Package keyring holds the keys that sign access tokens and download links (HMAC-SHA256) and certificates (ed25519).
+//
+// Each kind of key has one active key, used for signing, and any number of previous keys that are still accepted
+// when verifying, so rotating a key does not invalidate tokens signed before the rotation. Every key has an ID
+// which is embedded in the tokens it signs.
+//
+// Keys are loaded either from a directory laid out as
+//
+//	<dir>/jwt/active       ID of the active JWT key
+//	<dir>/jwt/<id>.key     JWT secret
+//	<dir>/cert/active      ID of the active certificate key
+//	<dir>/cert/<id>.key    ed25519 private key, URLsafe base64
+//	<dir>/cert/<id>.pub    ed25519 public key of a retired key, URLsafe base64
+//	<dir>/cert/legacy      optional ed25519 public key that signed name-only certificate tokens
+//	<dir>/link/active      ID of the active download link key
+//	<dir>/link/<id>.key    download link secret
+//
+// or from the keys in the configuration (see Load). Missing, malformed or weak keys are an error.
+package keyring
+
+import (
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/isd-sgcu/oph-67-backend/utils"
+)
+
+// MinSecretLength is the minimum length of a JWT secret in bytes
+const MinSecretLength = 32
+
+// placeholders are example values that must never be used as real keys
+var placeholders = []string{"secret", "secret-example", "changeme", "change-me", "your-secret-key"}
+
+type Keyring struct {
+	JWT  *HMACKeys
+	Cert *Ed25519Keys
+	Link *HMACKeys
+}
+
+// HMACKeys are the secrets access tokens or download links are signed with
+type HMACKeys struct {
+	ActiveID string
+	secrets  map[string]string
+}
+
+// Active returns the ID and secret new tokens are signed with
+func (k *HMACKeys) Active() (string, string) {
+	return k.ActiveID, k.secrets[k.ActiveID]
+}
+
+// Secrets returns every accepted secret by key ID
+func (k *HMACKeys) Secrets() map[string]string {
+	return k.secrets
+}
+
+// Secret returns the accepted secret with the key ID
+func (k *HMACKeys) Secret(id string) (string, bool) {
+	secret, ok := k.secrets[id]
+	return secret, ok
+}
+
+// IDs returns the IDs of every accepted key
+func (k *HMACKeys) IDs() []string {
+	return sortedKeys(k.secrets)
+}
+
+// Ed25519Keys are the keys certificates are signed with
+type Ed25519Keys struct {
+	ActiveID string
+	private  string // URLsafe base64 private key of the active key
+	public   map[string]ed25519.PublicKey
+	legacy   []ed25519.PublicKey // keys accepted for name-only tokens, which carry no key ID
+}
+
+// ErrClaimsLikeName is returned when a name-only token would sign something that reads as certificate claims
+var ErrClaimsLikeName = errors.New("name looks like certificate claims")
+
+// Sign signs message with the active key, embedding its ID in the token
+func (k *Ed25519Keys) Sign(message string) (string, error) {
+	return utils.GenerateKeyedED25519Signature(k.ActiveID, k.private, message)
+}
+
+// SignLegacy signs a bare name with the active key in the format without key ID, as the deprecated
+// /api/users/certToken route does. The same key signs JSON claims, so names that could be read as claims are refused.
+func (k *Ed25519Keys) SignLegacy(name string) (string, error) {
+	if utils.LooksLikeCertificateClaims(name) {
+		return "", ErrClaimsLikeName
+	}
+	return utils.GenerateED25519Signature(k.private, name)
+}
+
+// LegacyPublicKeys returns the keys name-only tokens are checked against: the active key and the configured legacy key
+func (k *Ed25519Keys) LegacyPublicKeys() []ed25519.PublicKey {
+	return k.legacy
+}
+
+// PublicKeys returns every accepted public key by key ID
+func (k *Ed25519Keys) PublicKeys() map[string]ed25519.PublicKey {
+	return k.public
+}
+
+// IDs returns the IDs of every accepted key
+func (k *Ed25519Keys) IDs() []string {
+	return sortedKeys(k.public)
+}
+
+func newHMACKeys(kind string, activeID string, secrets map[string]string) (*HMACKeys, error) {
+	if activeID == "" {
+		return nil, fmt.Errorf("%s: no active key", kind)
+	}
+	if _, ok := secrets[activeID]; !ok {
+		return nil, fmt.Errorf("%s: active key %q not found", kind, activeID)
+	}
+	for id, secret := range secrets {
+		if err := checkKeyID(id); err != nil {
+			return nil, fmt.Errorf("%s: %w", kind, err)
+		}
+		if err := checkSecret(secret); err != nil {
+			return nil, fmt.Errorf("%s key %q: %w", kind, id, err)
+		}
+	}
+	return &HMACKeys{ActiveID: activeID, secrets: secrets}, nil
+}
+
+func newEd25519Keys(activeID string, private string, retired map[string]ed25519.PublicKey, legacy ed25519.PublicKey) (*Ed25519Keys, error) {
+	if activeID == "" {
+		return nil, errors.New("cert: no active key")
+	}
+	if err := checkKeyID(activeID); err != nil {
+		return nil, fmt.Errorf("cert: %w", err)
+	}
+	public, err := checkPrivateKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("cert key %q: %w", activeID, err)
+	}
+
+	keys := make(map[string]ed25519.PublicKey, len(retired)+1)
+	for id, key := range retired {
+		if err := checkKeyID(id); err != nil {
+			return nil, fmt.Errorf("cert: %w", err)
+		}
+		keys[id] = key
+	}
+	keys[activeID] = public
+
+	legacyKeys := []ed25519.PublicKey{public}
+	if legacy != nil && !legacy.Equal(public) {
+		legacyKeys = append(legacyKeys, legacy)
+	}
+	return &Ed25519Keys{ActiveID: activeID, private: private, public: keys, legacy: legacyKeys}, nil
+}
+
+// checkKeyID rejects IDs that cannot be embedded in a token or used as a file name
+func checkKeyID(id string) error {
+	if id == "" || strings.ContainsAny(id, "./\\ \t\n=,") {
+		return fmt.Errorf("invalid key ID %q", id)
+	}
+	return nil
+}
+
+// checkSecret rejects short, placeholder and single-character secrets
+func checkSecret(secret string) error {
+	if len(secret) < MinSecretLength {
+		return fmt.Errorf("secret must be at least %d bytes", MinSecretLength)
+	}
+	for _, placeholder := range placeholders {
+		if strings.EqualFold(secret, placeholder) {
+			return errors.New("secret is a placeholder value")
+		}
+	}
+	if strings.Count(secret, secret[:1]) == len(secret) {
+		return errors.New("secret repeats a single character")
+	}
+	return nil
+}
+
+// checkPrivateKey checks that a URLsafe base64 ed25519 private key is well formed and returns its public key
+func checkPrivateKey(private string) (ed25519.PublicKey, error) {
+	if private == "" {
+		return nil, errors.New("private key is missing")
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(private)
+	if err != nil {
+		return nil, errors.New("private key is not URLsafe base64")
+	}
+	if len(raw) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("private key must be %d bytes", ed25519.PrivateKeySize)
+	}
+	key := ed25519.PrivateKey(raw)
+	derived := ed25519.NewKeyFromSeed(key.Seed())
+	if subtle.ConstantTimeCompare(derived, key) != 1 {
+		return nil, errors.New("private key does not match its public half")
+	}
+	return key.Public().(ed25519.PublicKey), nil
+}
@@ -0,0 +1,216 @@
+package keyring
+
+import (
+	"crypto/ed25519"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/isd-sgcu/oph-67-backend/config"
+	"github.com/isd-sgcu/oph-67-backend/utils"
+)
+
+const (
+	KindJWT  = "jwt"
+	KindCert = "cert"
+	KindLink = "link"
+)
+
+// Load reads the keys from cfg.Dir when it is set, otherwise from the keys given in cfg
+func Load(cfg config.KeysConfig) (*Keyring, error) {
+	if cfg.Dir != "" {
+		return LoadDir(cfg.Dir)
+	}
+
+	secrets, err := parsePairs(cfg.JWTPreviousKeys)
+	if err != nil {
+		return nil, fmt.Errorf("jwtPreviousKeys: %w", err)
+	}
+	secrets[cfg.JWTKeyID] = cfg.JWTSecret
+	jwtKeys, err := newHMACKeys(KindJWT, cfg.JWTKeyID, secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	linkSecrets, err := parsePairs(cfg.LinkPreviousKeys)
+	if err != nil {
+		return nil, fmt.Errorf("linkPreviousKeys: %w", err)
+	}
+	linkSecrets[cfg.LinkKeyID] = cfg.LinkSecret
+	linkKeys, err := newHMACKeys(KindLink, cfg.LinkKeyID, linkSecrets)
+	if err != nil {
+		return nil, err
+	}
+
+	retired, err := utils.ParseED25519PublicKeys(cfg.CertPublicKeys)
+	if err != nil {
+		return nil, fmt.Errorf("certPublicKeys: %w", err)
+	}
+	legacy, err := parseLegacyKey(cfg.CertLegacyPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("certLegacyPublicKey: %w", err)
+	}
+	certKeys, err := newEd25519Keys(cfg.CertKeyID, cfg.CertPrivateKey, retired, legacy)
+	if err != nil {
+		return nil, err
+	}
+	return newKeyring(jwtKeys, certKeys, linkKeys)
+}
+
+// LoadDir reads the keys from a key directory (see the package documentation)
+func LoadDir(dir string) (*Keyring, error) {
+	jwtID, err := readActive(dir, KindJWT)
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := readFiles(filepath.Join(dir, KindJWT), ".key")
+	if err != nil {
+		return nil, err
+	}
+	jwtKeys, err := newHMACKeys(KindJWT, jwtID, secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	linkID, err := readActive(dir, KindLink)
+	if err != nil {
+		return nil, err
+	}
+	linkSecrets, err := readFiles(filepath.Join(dir, KindLink), ".key")
+	if err != nil {
+		return nil, err
+	}
+	linkKeys, err := newHMACKeys(KindLink, linkID, linkSecrets)
+	if err != nil {
+		return nil, err
+	}
+
+	certID, err := readActive(dir, KindCert)
+	if err != nil {
+		return nil, err
+	}
+	privates, err := readFiles(filepath.Join(dir, KindCert), ".key")
+	if err != nil {
+		return nil, err
+	}
+	publics, err := readFiles(filepath.Join(dir, KindCert), ".pub")
+	if err != nil {
+		return nil, err
+	}
+
+	retired := make(map[string]ed25519.PublicKey)
+	for id, private := range privates {
+		if id == certID {
+			continue
+		}
+		key, err := checkPrivateKey(private)
+		if err != nil {
+			return nil, fmt.Errorf("cert key %q: %w", id, err)
+		}
+		retired[id] = key
+	}
+	for id, public := range publics {
+		keys, err := utils.ParseED25519PublicKeys(id + "=" + public)
+		if err != nil {
+			return nil, fmt.Errorf("cert key %q: %w", id, err)
+		}
+		retired[id] = keys[id]
+	}
+
+	var legacy ed25519.PublicKey
+	data, err := os.ReadFile(filepath.Join(dir, KindCert, "legacy"))
+	if err == nil {
+		legacy, err = parseLegacyKey(strings.TrimSpace(string(data)))
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("cert legacy key: %w", err)
+	}
+
+	certKeys, err := newEd25519Keys(certID, privates[certID], retired, legacy)
+	if err != nil {
+		return nil, err
+	}
+	return newKeyring(jwtKeys, certKeys, linkKeys)
+}
+
+// newKeyring rejects link secrets that are also JWT secrets, which would let anyone holding a link key sign access tokens
+func newKeyring(jwtKeys *HMACKeys, certKeys *Ed25519Keys, linkKeys *HMACKeys) (*Keyring, error) {
+	for linkID, linkSecret := range linkKeys.Secrets() {
+		for jwtID, jwtSecret := range jwtKeys.Secrets() {
+			if subtle.ConstantTimeCompare([]byte(linkSecret), []byte(jwtSecret)) == 1 {
+				return nil, fmt.Errorf("link key %q: same secret as jwt key %q", linkID, jwtID)
+			}
+		}
+	}
+	return &Keyring{JWT: jwtKeys, Cert: certKeys, Link: linkKeys}, nil
+}
+
+func readActive(dir string, kind string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, kind, "active"))
+	if err != nil {
+		return "", fmt.Errorf("%s: read active key ID: %w", kind, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// readFiles reads every file with the extension in dir, keyed by file name without the extension
+func readFiles(dir string, ext string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ext {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files[strings.TrimSuffix(entry.Name(), ext)] = strings.TrimSpace(string(data))
+	}
+	return files, nil
+}
+
+// parseLegacyKey parses the URLsafe base64 public key of name-only certificate tokens; empty means none
+func parseLegacyKey(encoded string) (ed25519.PublicKey, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	keys, err := utils.ParseED25519PublicKeys("legacy=" + encoded)
+	if err != nil {
+		return nil, err
+	}
+	return keys["legacy"], nil
+}
+
+// parsePairs parses "id=value,id=value"
+func parsePairs(spec string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, value, ok := strings.Cut(entry, "=")
+		if !ok || id == "" {
+			return nil, errors.New("entries must be id=value")
+		}
+		pairs[id] = value
+	}
+	return pairs, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,91 @@
+package keyring
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Rotating a key takes two steps, so that no replica receives a token signed with a key it does not know yet:
+//
+//  1. Publish writes a new key next to the others. Once every replica has restarted, all of them accept it.
+//  2. Activate makes it the key new tokens are signed with. Restart every replica again to start signing with it.
+//
+// Previous keys stay in the directory, so tokens they signed keep verifying.
+
+// Publish creates a new key of the kind in a key directory without activating it and returns its ID.
+// The first key of a kind is activated at once, since no token can have been signed with the kind yet.
+func Publish(dir string, kind string, now time.Time) (string, error) {
+	id := now.UTC().Format("20060102T150405Z")
+
+	var key string
+	switch kind {
+	case KindJWT, KindLink:
+		secret := make([]byte, 48)
+		if _, err := rand.Read(secret); err != nil {
+			return "", err
+		}
+		key = base64.RawURLEncoding.EncodeToString(secret)
+	case KindCert:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return "", err
+		}
+		key = base64.RawURLEncoding.EncodeToString(private)
+	default:
+		return "", unknownKind(kind)
+	}
+
+	kindDir := filepath.Join(dir, kind)
+	if err := os.MkdirAll(kindDir, 0o700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(kindDir, id+".key")
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("key %q already exists", id)
+	}
+	if err := os.WriteFile(path, []byte(key+"\n"), 0o600); err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(filepath.Join(kindDir, "active")); errors.Is(err, os.ErrNotExist) {
+		return id, writeActive(kindDir, id)
+	}
+	return id, nil
+}
+
+// Activate makes a published key the active key of its kind. It must only be run once every replica accepts
+// the key, that is after all of them restarted following Publish.
+func Activate(dir string, kind string, id string) error {
+	switch kind {
+	case KindJWT, KindLink, KindCert:
+	default:
+		return unknownKind(kind)
+	}
+	if err := checkKeyID(id); err != nil {
+		return err
+	}
+	kindDir := filepath.Join(dir, kind)
+	if _, err := os.Stat(filepath.Join(kindDir, id+".key")); err != nil {
+		return fmt.Errorf("%s key %q is not published: %w", kind, id, err)
+	}
+	return writeActive(kindDir, id)
+}
+
+// writeActive replaces the active file atomically so a starting replica never reads a partial ID
+func writeActive(kindDir string, id string) error {
+	tmp := filepath.Join(kindDir, "active.tmp")
+	if err := os.WriteFile(tmp, []byte(id+"\n"), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(kindDir, "active"))
+}
+
+func unknownKind(kind string) error {
+	return fmt.Errorf("unknown key kind %q, expected %s, %s or %s", kind, KindJWT, KindCert, KindLink)
+}
@@ -0,0 +1,88 @@
+package keyring
+
+import (
+	"testing"
+	"time"
+)
+
+// publishAll sets up a key directory with one active key of every kind
+func publishAll(t *testing.T, dir string, now time.Time) {
+	t.Helper()
+	for _, kind := range []string{KindJWT, KindCert, KindLink} {
+		if _, err := Publish(dir, kind, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPublishAcceptsKeyWithoutSigningWithIt(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	publishAll(t, dir, start)
+	first := start.Format("20060102T150405Z")
+
+	second, err := Publish(dir, KindJWT, start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := Publish(dir, KindCert, start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys.JWT.ActiveID != first {
+		t.Errorf("active JWT key %s, want %s until activated", keys.JWT.ActiveID, first)
+	}
+	if _, ok := keys.JWT.Secret(second); !ok {
+		t.Errorf("published JWT key %s is not accepted", second)
+	}
+	if keys.Cert.ActiveID != first {
+		t.Errorf("active cert key %s, want %s until activated", keys.Cert.ActiveID, first)
+	}
+	if _, ok := keys.Cert.PublicKeys()[cert]; !ok {
+		t.Errorf("published cert key %s is not accepted", cert)
+	}
+}
+
+func TestActivateSignsWithPublishedKey(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	publishAll(t, dir, start)
+	id, err := Publish(dir, KindLink, start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Activate(dir, KindLink, id); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys.Link.ActiveID != id {
+		t.Errorf("active link key %s, want %s", keys.Link.ActiveID, id)
+	}
+	if _, ok := keys.Link.Secret(start.Format("20060102T150405Z")); !ok {
+		t.Error("previous link key is no longer accepted")
+	}
+}
+
+func TestActivateRejectsUnpublishedKeys(t *testing.T) {
+	dir := t.TempDir()
+	publishAll(t, dir, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	if err := Activate(dir, KindJWT, "20990101T000000Z"); err == nil {
+		t.Error("activated a key that was never published")
+	}
+	if err := Activate(dir, KindJWT, "../cert/20250101T000000Z"); err == nil {
+		t.Error("activated a key outside its kind's directory")
+	}
+	if err := Activate(dir, "session", "20250101T000000Z"); err == nil {
+		t.Error("activated a key of an unknown kind")
+	}
+}
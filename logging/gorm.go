@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// gormLogger sends gorm's logs to slog. Queries are logged without their parameters, so values
+// such as phone numbers never reach the log.
+type gormLogger struct {
+	logger        *slog.Logger
+	slowThreshold time.Duration
+}
+
+// NewGormLogger logs failed queries as errors, queries slower than slowThreshold as warnings
+// and every query at debug level
+func NewGormLogger(l *slog.Logger, slowThreshold time.Duration) logger.Interface {
+	return gormLogger{logger: l, slowThreshold: slowThreshold}
+}
+
+// LogMode is ignored; the slog level decides what is logged
+func (g gormLogger) LogMode(logger.LogLevel) logger.Interface {
+	return g
+}
+
+func (g gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	g.logger.InfoContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (g gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	g.logger.WarnContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (g gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	g.logger.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (g gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && !errors.Is(err, context.Canceled):
+		sql, rows := fc()
+		g.logger.ErrorContext(ctx, "Query failed", "sql", sql, "rows", rows, "duration", elapsed, "error", err)
+	case g.slowThreshold > 0 && elapsed > g.slowThreshold:
+		sql, rows := fc()
+		g.logger.WarnContext(ctx, "Slow query", "sql", sql, "rows", rows, "duration", elapsed)
+	case g.logger.Enabled(ctx, slog.LevelDebug):
+		sql, rows := fc()
+		g.logger.DebugContext(ctx, "Query", "sql", sql, "rows", rows, "duration", elapsed)
+	}
+}
+
+// ParamsFilter drops the query parameters, so logged SQL keeps its $n placeholders
+func (g gormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
@@ -0,0 +1,108 @@
+// Package logging builds the structured logger of the server. Every record logged with a request
+// context carries its request ID, and its trace ID when the trace is recorded. Personal data
+// (phone numbers, emails) is masked.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/isd-sgcu/oph-67-backend/config"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type requestIDKey struct{}
+
+// RequestIDKey is the attribute carrying the request ID in log records
+const RequestIDKey = "requestId"
+
+// TraceIDKey is the attribute carrying the trace ID in log records, to find the trace of a log line
+const TraceIDKey = "traceId"
+
+// New returns a logger writing to w in the configured format and level
+func New(cfg config.LogConfig, w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, fmt.Errorf("log level %q: %w", cfg.Level, err)
+	}
+
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redact}
+	var handler slog.Handler
+	switch cfg.Format {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("log format %q: must be json or text", cfg.Format)
+	}
+	return slog.New(contextHandler{handler}), nil
+}
+
+// WithRequestID returns a context whose log records carry the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID stored in ctx, or "" if there is none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler adds the request and trace IDs of the record's context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String(RequestIDKey, id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsSampled() {
+		r.AddAttrs(slog.String(TraceIDKey, sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// redact masks attributes that hold personal data, wherever they are nested
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() != slog.KindString {
+		return a
+	}
+	switch strings.ToLower(a.Key) {
+	case "phone":
+		a.Value = slog.StringValue(MaskPhone(a.Value.String()))
+	case "email":
+		a.Value = slog.StringValue(MaskEmail(a.Value.String()))
+	}
+	return a
+}
+
+// MaskPhone keeps only the last two digits of a phone number
+func MaskPhone(phone string) string {
+	if len(phone) <= 2 {
+		return strings.Repeat("*", len(phone))
+	}
+	return strings.Repeat("*", len(phone)-2) + phone[len(phone)-2:]
+}
+
+// MaskEmail keeps the first letter and the domain of an email address
+func MaskEmail(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" {
+		return strings.Repeat("*", len(email))
+	}
+	return local[:1] + "***@" + domain
+}
@@ -0,0 +1,123 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/isd-sgcu/oph-67-backend/config"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// record logs one message with logger and returns it decoded
+func record(t *testing.T, log func(logger *slog.Logger)) map[string]interface{} {
+	t.Helper()
+	var buf bytes.Buffer
+	logger, err := New(config.LogConfig{Level: "debug", Format: "json"}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log(logger)
+
+	var rec map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	return rec
+}
+
+func TestNewRejectsUnknownLevelAndFormat(t *testing.T) {
+	for _, cfg := range []config.LogConfig{
+		{Level: "verbose", Format: "json"},
+		{Level: "info", Format: "xml"},
+	} {
+		if _, err := New(cfg, &bytes.Buffer{}); err == nil {
+			t.Errorf("New(%+v) accepted the config", cfg)
+		}
+	}
+}
+
+func TestRecordsCarryRequestAndTraceIDs(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	span := func(flags trace.TraceFlags) context.Context {
+		return trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: traceID, SpanID: spanID, TraceFlags: flags,
+		}))
+	}
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		requestID interface{}
+		traceID   interface{}
+	}{
+		{"no IDs", context.Background(), nil, nil},
+		{"request ID", WithRequestID(context.Background(), "req-1"), "req-1", nil},
+		{"sampled trace", WithRequestID(span(trace.FlagsSampled), "req-2"), "req-2", traceID.String()},
+		{"unsampled trace", span(0), nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := record(t, func(logger *slog.Logger) {
+				// Attributes and groups added beforehand keep the IDs on the record
+				logger.With("component", "test").InfoContext(tt.ctx, "Hello")
+			})
+			if rec[RequestIDKey] != tt.requestID {
+				t.Errorf("%s = %v, want %v", RequestIDKey, rec[RequestIDKey], tt.requestID)
+			}
+			if rec[TraceIDKey] != tt.traceID {
+				t.Errorf("%s = %v, want %v", TraceIDKey, rec[TraceIDKey], tt.traceID)
+			}
+		})
+	}
+}
+
+func TestPersonalDataIsMasked(t *testing.T) {
+	rec := record(t, func(logger *slog.Logger) {
+		logger.WithGroup("request").Info("Signed in", "phone", "0812345678", "Email", "somchai@example.com")
+	})
+	request, _ := rec["request"].(map[string]interface{})
+	if request["phone"] != "********78" {
+		t.Errorf("phone = %v, want ********78", request["phone"])
+	}
+	if request["Email"] != "s***@example.com" {
+		t.Errorf("email = %v, want s***@example.com", request["Email"])
+	}
+}
+
+func TestLoggedUserHasNoPersonalData(t *testing.T) {
+	user := domain.User{
+		ID:    "user-1",
+		UID:   "U-0042",
+		Name:  "Somchai Jaidee",
+		Role:  domain.Student,
+		Phone: "0812345678",
+		Email: "somchai@example.com",
+	}
+	var buf bytes.Buffer
+	for _, format := range []string{"json", "text"} {
+		logger, err := New(config.LogConfig{Level: "info", Format: format}, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		logger.Info("User registered", "user", user)
+		logger.Info("User registered", slog.Any("user", &user))
+	}
+
+	out := buf.String()
+	for _, private := range []string{user.UID, user.Name, user.Phone, "5678", user.Email, "example.com"} {
+		if strings.Contains(out, private) {
+			t.Errorf("log contains %q:\n%s", private, out)
+		}
+	}
+	for _, kept := range []string{user.ID, string(user.Role)} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("log is missing %q:\n%s", kept, out)
+		}
+	}
+}
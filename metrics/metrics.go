@@ -0,0 +1,75 @@
+// Package metrics holds the Prometheus collectors exported on /metrics.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "oph"
+
+// Scan outcomes
+const (
+	ScanAccepted    = "accepted"
+	ScanDuplicate   = "duplicate"
+	ScanUnknownUser = "unknown_user"
+	ScanError       = "error"
+)
+
+// ScanFacultyCentral labels scans made by central staff at the entrance
+const ScanFacultyCentral = "central"
+
+// Registry contains every collector of the server, including Go runtime and process metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status code.",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"method", "route", "status"})
+
+	Scans = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "scans_total",
+		Help:      "QR scans by the scanning staff's faculty and outcome (accepted, duplicate, unknown_user, error).",
+	}, []string{"faculty", "outcome"})
+
+	Registrations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_total",
+		Help:      "New users by role; use rate() or increase() for registrations per minute.",
+	}, []string{"role"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		Scans,
+		Registrations,
+	)
+}
+
+// RegisterDB exports the connection pool statistics of db (open, in use, idle, waits)
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"gorm.io/gorm"
+)
+
+// ErrorHandler is the Fiber error handler. Handlers and middleware return errors instead of writing
+// error responses, and this turns them into a domain.ErrorResponse with the matching status.
+// Errors that are not domain errors are answered with a generic 500 and logged with their cause.
+func ErrorHandler(logger *slog.Logger) fiber.ErrorHandler {
+	return func(c *fiber.Ctx, err error) error {
+		e := AsDomainError(err)
+		if e.Kind == domain.KindInternal {
+			logger.ErrorContext(c.UserContext(), "Unhandled error", "method", c.Method(), "route", c.Route().Path, "error", err)
+		}
+
+		requestID, _ := c.Locals(requestIDLocalsKey).(string)
+		return c.Status(StatusOf(err)).JSON(domain.ErrorResponse{
+			Code:      e.Code,
+			Message:   e.Message,
+			Details:   e.Details,
+			RequestID: requestID,
+		})
+	}
+}
+
+// AsDomainError finds the domain error in err's chain, translating validation, Fiber, not-found
+// and unique violation errors, and falls back to domain.ErrInternal for anything else
+func AsDomainError(err error) *domain.Error {
+	var e *domain.Error
+	if errors.As(err, &e) {
+		return e
+	}
+	var verr *domain.ValidationError
+	if errors.As(err, &verr) {
+		return verr.AsError()
+	}
+	var ferr *fiber.Error
+	if errors.As(err, &ferr) {
+		// Raised by Fiber itself, e.g. unknown routes or bodies over the size limit
+		return &domain.Error{Kind: kindOfStatus(ferr.Code), Code: fiberCode(ferr.Code), Message: ferr.Message}
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.ErrNotFound
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return domain.ErrConflict
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return domain.ErrTimeout
+	}
+	return domain.ErrInternal
+}
+
+// StatusOf returns the HTTP status err is answered with. Middleware that runs before the error
+// handler, such as metrics and tracing, uses it to report the final status.
+func StatusOf(err error) int {
+	var ferr *fiber.Error
+	if errors.As(err, &ferr) {
+		return ferr.Code
+	}
+	switch AsDomainError(err).Kind {
+	case domain.KindInvalidInput:
+		return fiber.StatusBadRequest
+	case domain.KindUnauthorized:
+		return fiber.StatusUnauthorized
+	case domain.KindForbidden:
+		return fiber.StatusForbidden
+	case domain.KindNotFound:
+		return fiber.StatusNotFound
+	case domain.KindConflict:
+		return fiber.StatusConflict
+	case domain.KindValidation:
+		return fiber.StatusUnprocessableEntity
+	case domain.KindUnavailable:
+		return fiber.StatusServiceUnavailable
+	default:
+		return fiber.StatusInternalServerError
+	}
+}
+
+// kindOfStatus classifies the status of a Fiber error
+func kindOfStatus(status int) domain.Kind {
+	switch {
+	case status == fiber.StatusUnauthorized:
+		return domain.KindUnauthorized
+	case status == fiber.StatusForbidden:
+		return domain.KindForbidden
+	case status == fiber.StatusNotFound:
+		return domain.KindNotFound
+	case status == fiber.StatusServiceUnavailable:
+		return domain.KindUnavailable
+	case status >= fiber.StatusInternalServerError:
+		return domain.KindInternal
+	default:
+		return domain.KindInvalidInput
+	}
+}
+
+// fiberCode names the errors Fiber raises on its own
+func fiberCode(status int) string {
+	switch status {
+	case fiber.StatusNotFound:
+		return "route_not_found"
+	case fiber.StatusMethodNotAllowed:
+		return "method_not_allowed"
+	case fiber.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	default:
+		if status >= fiber.StatusInternalServerError {
+			return domain.ErrInternal.Code
+		}
+		return "invalid_input"
+	}
+}
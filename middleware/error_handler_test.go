@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"gorm.io/gorm"
+)
+
+func TestErrorHandler(t *testing.T) {
+	validation := &domain.ValidationError{}
+	validation.Add("phone", domain.FieldRequired, "is required")
+	validation.AddOutOfRange("age", 1, 99)
+
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+		details []domain.FieldError
+	}{
+		{
+			name:    "domain error",
+			err:     domain.ErrForbidden,
+			status:  fiber.StatusForbidden,
+			code:    "forbidden",
+			message: domain.ErrForbidden.Message,
+		},
+		{
+			name:    "wrapped domain error keeps its message, not the cause",
+			err:     fmt.Errorf("scan: %w", domain.ErrNotFound.WithMessage("User not found").Wrap(errors.New("secret cause"))),
+			status:  fiber.StatusNotFound,
+			code:    "not_found",
+			message: "User not found",
+		},
+		{
+			name:    "validation error",
+			err:     validation,
+			status:  fiber.StatusUnprocessableEntity,
+			code:    "validation_failed",
+			message: domain.ErrValidation.Message,
+			details: validation.Fields,
+		},
+		{
+			name:    "fiber error",
+			err:     fiber.ErrRequestEntityTooLarge,
+			status:  fiber.StatusRequestEntityTooLarge,
+			code:    "request_too_large",
+			message: fiber.ErrRequestEntityTooLarge.Message,
+		},
+		{
+			name:    "unknown route",
+			err:     fiber.NewError(fiber.StatusNotFound, "Cannot GET /nope"),
+			status:  fiber.StatusNotFound,
+			code:    "route_not_found",
+			message: "Cannot GET /nope",
+		},
+		{
+			name:    "record not found",
+			err:     fmt.Errorf("get user: %w", gorm.ErrRecordNotFound),
+			status:  fiber.StatusNotFound,
+			code:    "not_found",
+			message: domain.ErrNotFound.Message,
+		},
+		{
+			name:    "unique violation",
+			err:     fmt.Errorf("create booth: %w", gorm.ErrDuplicatedKey),
+			status:  fiber.StatusConflict,
+			code:    "conflict",
+			message: domain.ErrConflict.Message,
+		},
+		{
+			name:    "deadline exceeded",
+			err:     fmt.Errorf("list users: %w", context.DeadlineExceeded),
+			status:  fiber.StatusServiceUnavailable,
+			code:    "timeout",
+			message: domain.ErrTimeout.Message,
+		},
+		{
+			name:    "unknown error",
+			err:     errors.New("pq: relation \"users\" does not exist"),
+			status:  fiber.StatusInternalServerError,
+			code:    "internal",
+			message: domain.ErrInternal.Message,
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if e := AsDomainError(tt.err); e.Code != tt.code {
+				t.Errorf("AsDomainError code = %q, want %q", e.Code, tt.code)
+			}
+			if status := StatusOf(tt.err); status != tt.status {
+				t.Errorf("StatusOf = %d, want %d", status, tt.status)
+			}
+
+			app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler(logger)})
+			app.Use(RequestIDMiddleware())
+			app.Get("/", func(c *fiber.Ctx) error { return tt.err })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(HeaderRequestID, "req-1")
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+
+			// Decode into a map as well, so the JSON field names are checked and not only the Go struct
+			body, _ := io.ReadAll(resp.Body)
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(body, &fields); err != nil {
+				t.Fatalf("body %s: %v", body, err)
+			}
+			for _, key := range []string{"code", "message", "requestId"} {
+				if _, ok := fields[key]; !ok {
+					t.Errorf("body %s has no %q", body, key)
+				}
+			}
+			if _, ok := fields["details"]; ok != (len(tt.details) > 0) {
+				t.Errorf("body %s: details present = %v, want %v", body, ok, len(tt.details) > 0)
+			}
+
+			var got domain.ErrorResponse
+			json.Unmarshal(body, &got)
+			if got.Code != tt.code || got.Message != tt.message || got.RequestID != "req-1" {
+				t.Errorf("body = %+v, want code %q, message %q, requestId req-1", got, tt.code, tt.message)
+			}
+			if len(got.Details) != len(tt.details) {
+				t.Fatalf("details = %+v, want %+v", got.Details, tt.details)
+			}
+			for i, detail := range got.Details {
+				if detail.Field != tt.details[i].Field || detail.Code != tt.details[i].Code || detail.Message != tt.details[i].Message {
+					t.Errorf("details[%d] = %+v, want %+v", i, detail, tt.details[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/metrics"
+)
+
+// MetricsMiddleware counts requests and records their latency by route template (e.g. /api/users/:id),
+// so IDs in paths do not create a series per user
+func MetricsMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			// The error handler has not written the response yet
+			status = StatusOf(err)
+		}
+
+		// The collectors keep their label values, and Fiber reuses the buffer behind c.Method() for the next request
+		labels := []string{strings.Clone(c.Method()), c.Route().Path, strconv.Itoa(status)}
+		metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+		metrics.HTTPDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+)
+
+// UserLocalsKey is the fiber.Ctx Locals key under which AuthMiddleware stores the authenticated domain.User
+const UserLocalsKey = "user"
+
+// CurrentUser returns the user stored by AuthMiddleware, if any.
+func CurrentUser(c *fiber.Ctx) (domain.User, bool) {
+	user, ok := c.Locals(UserLocalsKey).(domain.User)
+	return user, ok
+}
+
+// PermissionMiddleware rejects requests whose user lacks any of the given permissions.
+// It must run after AuthMiddleware.
+func PermissionMiddleware(permissions ...domain.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return domain.ErrUnauthorized
+		}
+
+		for _, permission := range permissions {
+			if !user.HasPermission(permission) {
+				return domain.ErrForbidden
+			}
+		}
+
+		return c.Next()
+	}
+}
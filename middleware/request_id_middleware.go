@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/logging"
+)
+
+const HeaderRequestID = "X-Request-ID"
+
+// requestIDLocalsKey is the fiber.Ctx Locals key holding the request ID
+const requestIDLocalsKey = "requestId"
+
+// maxRequestIDLength bounds IDs accepted from clients or proxies
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware reuses a valid X-Request-ID from the client or proxy or creates one, returns it in the
+// response header and stores it in the request context for logging. ErrorHandler adds it to error bodies.
+func RequestIDMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(HeaderRequestID)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set(HeaderRequestID, id)
+		c.Locals(requestIDLocalsKey, id)
+		c.SetUserContext(logging.WithRequestID(c.UserContext(), id))
+
+		return c.Next()
+	}
+}
+
+// validRequestID accepts IDs that are safe to echo in headers, logs and JSON
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/config"
+	"github.com/isd-sgcu/oph-67-backend/logging"
+)
+
+var generatedRequestID = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		echoed   bool
+	}{
+		{"valid ID is echoed", "edge-7f3a.01_b", true},
+		{"longest valid ID is echoed", strings.Repeat("a", maxRequestIDLength), true},
+		{"missing", "", false},
+		{"too long", strings.Repeat("a", maxRequestIDLength+1), false},
+		{"header injection", "abc\r\nSet-Cookie: x", false},
+		{"space", "abc def", false},
+		{"quote", `abc"def`, false},
+		{"non-ASCII", "ไอดี", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inContext, inLocals string
+			app := fiber.New()
+			app.Use(RequestIDMiddleware())
+			app.Get("/", func(c *fiber.Ctx) error {
+				inContext = logging.RequestID(c.UserContext())
+				inLocals, _ = c.Locals(requestIDLocalsKey).(string)
+				return c.SendStatus(fiber.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header[HeaderRequestID] = []string{tt.incoming}
+			}
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			id := resp.Header.Get(HeaderRequestID)
+			if tt.echoed && id != tt.incoming {
+				t.Errorf("%s = %q, want the incoming %q", HeaderRequestID, id, tt.incoming)
+			}
+			if !tt.echoed && !generatedRequestID.MatchString(id) {
+				t.Errorf("%s = %q, want a generated ID", HeaderRequestID, id)
+			}
+			if inContext != id || inLocals != id {
+				t.Errorf("context ID %q, locals ID %q, want the response ID %q", inContext, inLocals, id)
+			}
+		})
+	}
+}
+
+func TestRequestIDMiddlewareGeneratesUniqueIDs(t *testing.T) {
+	app := fiber.New()
+	app.Use(RequestIDMiddleware())
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+
+	seen := map[string]bool{}
+	for i := 0; i < 10; i++ {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := resp.Header.Get(HeaderRequestID)
+		if seen[id] {
+			t.Fatalf("request ID %q generated twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestRequestLogCarriesRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(config.LogConfig{Level: "info", Format: "json"}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler(logger)})
+	app.Use(RequestIDMiddleware())
+	app.Use(RequestLoggerMiddleware(logger))
+	app.Get("/users/:id", func(c *fiber.Ctx) error {
+		logger.InfoContext(c.UserContext(), "Handled", slog.String("id", c.Params("id")))
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set(HeaderRequestID, "edge-42")
+	if _, err := app.Test(req, -1); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("logged %d records, want the handler's and the request's:\n%s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		if record[logging.RequestIDKey] != "edge-42" {
+			t.Errorf("record %s has %s %v, want edge-42", line, logging.RequestIDKey, record[logging.RequestIDKey])
+		}
+	}
+}
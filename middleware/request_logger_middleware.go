@@ -1,25 +1,49 @@
 package middleware
 
 import (
-	"log"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/logging"
 )
 
-// RequestLoggerMiddleware logs the request method, path, duration, and response status
-func RequestLoggerMiddleware() fiber.Handler {
+// RequestLoggerMiddleware logs the request method, route, duration, and response status
+func RequestLoggerMiddleware(logger *slog.Logger) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		start := time.Now()
-		method := c.Method()          // Get the HTTP method (GET, POST, etc.)
-		path := c.Path()              // Get the request path
-		err := c.Next()               // Continue to the next middleware or handler
-		duration := time.Since(start) // Measure the duration
+		err := c.Next()
 
-		status := c.Response().StatusCode() // Get the HTTP status code of the response
+		status := c.Response().StatusCode()
+		if err != nil {
+			// The error handler has not written the response yet
+			status = StatusOf(err)
+		}
+		level := slog.LevelInfo
+		switch {
+		case status >= fiber.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= fiber.StatusBadRequest:
+			level = slog.LevelWarn
+		}
 
-		// Log the request information
-		log.Printf("[%s] %s %s took %v, Status: %d", time.Now().Format(time.RFC3339), method, path, duration, status)
+		// The query string is left out and phone numbers in the path are masked, as they are personal data
+		path := c.Path()
+		if phone := c.Params("phone"); phone != "" {
+			path = strings.Replace(path, phone, logging.MaskPhone(phone), 1)
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Method()),
+			slog.String("path", path),
+			slog.String("route", c.Route().Path),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		logger.LogAttrs(c.UserContext(), level, "Request", attrs...)
 
 		return err
 	}
@@ -1,32 +1,36 @@
 package middleware
 
 import (
+	"errors"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/isd-sgcu/oph-67-backend/domain"
 	"github.com/isd-sgcu/oph-67-backend/usecase"
-	"github.com/isd-sgcu/oph-67-backend/utils"
 )
 
 func RoleMiddleware(u *usecase.UserUsecase, allowedRoles ...domain.Role) fiber.Handler {
-	var secretKey = utils.GetEnv("SECRET_JWT_KEY", "")
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		if !strings.HasPrefix(authHeader, "Bearer ") {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+			return domain.ErrUnauthorized
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		id, err := utils.DecodeToken(tokenString, secretKey)
+		id, err := u.DecodeToken(tokenString)
 		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired token"})
+			return domain.ErrInvalidToken
 		}
 
-		user, err := u.GetById(id)
+		user, err := u.GetById(c.UserContext(), id)
 		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+			if errors.Is(err, domain.ErrUserNotFound) {
+				// The token outlived its user
+				return domain.ErrInvalidToken.WithMessage("User not found")
+			}
+			return err
 		}
+		c.Locals(UserLocalsKey, user)
 		role := user.Role
 
 		for _, allowedRole := range allowedRoles {
@@ -35,6 +39,6 @@ func RoleMiddleware(u *usecase.UserUsecase, allowedRoles ...domain.Role) fiber.H
 			}
 		}
 
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access forbidden: insufficient role permissions"})
+		return domain.ErrForbidden.WithMessage("Access forbidden: insufficient role permissions")
 	}
 }
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+)
+
+// RequestTimeoutMiddleware gives every request a context with a deadline. Handlers pass c.UserContext()
+// down to the repositories, so queries still running at the deadline are cancelled.
+func RequestTimeoutMiddleware(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		// A handler that failed because its queries were cancelled reports a timeout instead of a server error
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && (err != nil || c.Response().StatusCode() >= fiber.StatusInternalServerError) {
+			return domain.ErrTimeout.Wrap(ctx.Err())
+		}
+		return err
+	}
+}
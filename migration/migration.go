@@ -0,0 +1,110 @@
+// Package migration applies the versioned SQL migrations in sql/ to the database.
+//
+// Each migration is a pair of files NNNN_name.up.sql and NNNN_name.down.sql; a migration
+// without a down script, such as the baseline, cannot be reverted. Applied versions are
+// recorded in schema_migrations, and every run holds a PostgreSQL advisory lock so several
+// replicas can start at the same time without racing each other.
+package migration
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+var (
+	ErrUnknownVersion = errors.New("unknown migration version")
+	ErrMissingDown    = errors.New("migration has no down script and cannot be reverted")
+)
+
+// Migration is one schema change and how to revert it
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration and when it was applied, nil when it is pending
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Load reads the embedded migrations sorted by version
+func Load() ([]Migration, error) {
+	return load(files, "sql")
+}
+
+func load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("%s: migration files must end in .up.sql or .down.sql", name)
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		prefix, label, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("%s: migration files must be named NNNN_name.%s.sql", name, direction)
+		}
+
+		body, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		} else if m.Name != label {
+			return nil, fmt.Errorf("%s: version %d is already used by %q", name, version, m.Name)
+		}
+		script := &m.Up
+		if direction == "down" {
+			script = &m.Down
+		}
+		if *script != "" {
+			// e.g. 0003_x.up.sql and 3_x.up.sql
+			return nil, fmt.Errorf("%s: version %d has another %s script", name, version, direction)
+		}
+		*script = string(body)
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
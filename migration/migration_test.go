@@ -0,0 +1,323 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// mapFS returns the given files under sql/, each holding its own name as its script
+func mapFS(names ...string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for _, name := range names {
+		fsys["sql/"+name] = &fstest.MapFile{Data: []byte("-- " + name)}
+	}
+	return fsys
+}
+
+func versions(migrations []Migration) []int {
+	out := make([]int, 0, len(migrations))
+	for _, mig := range migrations {
+		out = append(out, mig.Version)
+	}
+	return out
+}
+
+func sameVersions(got []Migration, want ...int) bool {
+	return fmt.Sprint(versions(got)) == fmt.Sprint(append([]int{}, want...))
+}
+
+func TestLoadParsesAndOrdersByVersion(t *testing.T) {
+	migrations, err := load(mapFS(
+		"0010_tenth.up.sql", "0010_tenth.down.sql",
+		"0002_second.up.sql", "0002_second.down.sql",
+		"0001_baseline.up.sql",
+		"9_ninth.up.sql",
+	), "sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !sameVersions(migrations, 1, 2, 9, 10) {
+		t.Fatalf("versions = %v, want [1 2 9 10]", versions(migrations))
+	}
+	second := migrations[1]
+	if second.Name != "second" || second.Up != "-- 0002_second.up.sql" || second.Down != "-- 0002_second.down.sql" {
+		t.Errorf("0002 = %+v", second)
+	}
+	if migrations[0].Down != "" {
+		t.Errorf("0001 down = %q, want none", migrations[0].Down)
+	}
+	if got := migrations[2].String(); got != "0009_ninth" {
+		t.Errorf("String() = %q, want 0009_ninth", got)
+	}
+}
+
+func TestLoadRejectsInvalidSets(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  string
+	}{
+		{"duplicate version", []string{"0002_a.up.sql", "0002_b.up.sql"}, `already used by "a"`},
+		{"duplicate up script", []string{"0002_a.up.sql", "2_a.up.sql"}, "has another up script"},
+		{"down without up", []string{"0001_a.up.sql", "0002_b.down.sql"}, "0002_b has no up script"},
+		{"unknown suffix", []string{"0001_a.sql"}, "must end in .up.sql or .down.sql"},
+		{"no name", []string{"0001.up.sql"}, "must be named NNNN_name.up.sql"},
+		{"no version", []string{"first_a.up.sql"}, "must be named NNNN_name.up.sql"},
+		{"version zero", []string{"0000_a.up.sql"}, "must be named NNNN_name.up.sql"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(mapFS(tt.files...), "sql")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbeddedBaselineIsIrreversible(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 || migrations[0].Version != 1 {
+		t.Fatalf("first migration = %v, want 0001", versions(migrations))
+	}
+	if migrations[0].Down != "" {
+		t.Error("0001_baseline has a down script")
+	}
+	for _, mig := range migrations[1:] {
+		if strings.TrimSpace(mig.Down) == "" {
+			t.Errorf("%s has no down script", mig)
+		}
+	}
+}
+
+// plannedSet is 0001 without a down script and 0002 to 0004 with one
+func plannedSet(t *testing.T) []Migration {
+	t.Helper()
+	migrations, err := load(mapFS(
+		"0001_baseline.up.sql",
+		"0002_b.up.sql", "0002_b.down.sql",
+		"0003_c.up.sql", "0003_c.down.sql",
+		"0004_d.up.sql", "0004_d.down.sql",
+	), "sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return migrations
+}
+
+func appliedUpTo(version int) map[int]time.Time {
+	applied := make(map[int]time.Time)
+	for v := 1; v <= version; v++ {
+		applied[v] = time.Date(2024, 11, 16, 9, v, 0, 0, time.UTC)
+	}
+	return applied
+}
+
+func TestPlanTo(t *testing.T) {
+	migrations := plannedSet(t)
+	tests := []struct {
+		name       string
+		applied    map[int]time.Time
+		version    int
+		wantRevert []int
+		wantApply  []int
+		wantErr    error
+	}{
+		{"up from empty", appliedUpTo(0), 4, []int{}, []int{1, 2, 3, 4}, nil},
+		{"up part way", appliedUpTo(1), 3, []int{}, []int{2, 3}, nil},
+		{"fills a gap", map[int]time.Time{1: {}, 3: {}}, 4, []int{}, []int{2, 4}, nil},
+		{"already there", appliedUpTo(3), 3, []int{}, []int{}, nil},
+		{"down newest first", appliedUpTo(4), 2, []int{4, 3}, []int{}, nil},
+		{"down to the baseline", appliedUpTo(4), 1, []int{4, 3, 2}, []int{}, nil},
+		{"down past the baseline", appliedUpTo(4), 0, nil, nil, ErrMissingDown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revert, apply, err := planTo(migrations, tt.applied, tt.version)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if revert != nil || apply != nil {
+					t.Errorf("plan = %v %v, want nothing on error", versions(revert), versions(apply))
+				}
+				return
+			}
+			if !sameVersions(revert, tt.wantRevert...) {
+				t.Errorf("revert = %v, want %v", versions(revert), tt.wantRevert)
+			}
+			if !sameVersions(apply, tt.wantApply...) {
+				t.Errorf("apply = %v, want %v", versions(apply), tt.wantApply)
+			}
+		})
+	}
+}
+
+func TestPlanDown(t *testing.T) {
+	migrations := plannedSet(t)
+	tests := []struct {
+		name    string
+		applied map[int]time.Time
+		n       int
+		want    []int
+		wantErr error
+	}{
+		{"newest", appliedUpTo(4), 1, []int{4}, nil},
+		{"newest first", appliedUpTo(4), 3, []int{4, 3, 2}, nil},
+		{"skips pending", map[int]time.Time{1: {}, 2: {}, 4: {}}, 2, []int{4, 2}, nil},
+		{"nothing applied", appliedUpTo(0), 1, []int{}, nil},
+		{"past the baseline", appliedUpTo(4), 4, nil, ErrMissingDown},
+		{"only the baseline", appliedUpTo(1), 1, nil, ErrMissingDown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revert, err := planDown(migrations, tt.applied, tt.n)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if !strings.Contains(err.Error(), "0001_baseline") {
+					t.Errorf("err = %v, want it to name 0001_baseline", err)
+				}
+				return
+			}
+			if !sameVersions(revert, tt.want...) {
+				t.Errorf("revert = %v, want %v", versions(revert), tt.want)
+			}
+		})
+	}
+}
+
+func TestToRejectsUnknownVersion(t *testing.T) {
+	// The version is checked before the database is touched
+	m := &Migrator{Migrations: plannedSet(t)}
+	if _, err := m.To(7); !errors.Is(err, ErrUnknownVersion) {
+		t.Fatalf("err = %v, want %v", err, ErrUnknownVersion)
+	}
+}
+
+// TestMigratorOnPostgres applies and reverts a small set of migrations on the database in
+// TEST_DATABASE_DSN. It works in a schema of its own, which is dropped afterwards.
+func TestMigratorOnPostgres(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := fmt.Sprintf("migration_test_%d", time.Now().UnixNano())
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	// Every connection resolves schema_migrations and the test tables in the new schema
+	separator := " "
+	if strings.Contains(dsn, "://") {
+		separator = "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+	}
+	db, err := gorm.Open(postgres.Open(dsn+separator+"search_path="+schema), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := fstest.MapFS{
+		"sql/0001_base.up.sql":    {Data: []byte("CREATE TABLE base (id int)")},
+		"sql/0002_extra.up.sql":   {Data: []byte("CREATE TABLE extra (id int)")},
+		"sql/0002_extra.down.sql": {Data: []byte("DROP TABLE extra")},
+	}
+	migrations, err := load(fsys, "sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newMigrator := func() *Migrator { return &Migrator{DB: sqlDB, Migrations: migrations} }
+	tableExists := func(name string) bool {
+		var exists bool
+		if err := sqlDB.QueryRow("SELECT to_regclass($1) IS NOT NULL", schema+"."+name).Scan(&exists); err != nil {
+			t.Fatal(err)
+		}
+		return exists
+	}
+
+	// Both runs wait on the lock, so every migration is applied exactly once
+	var wg sync.WaitGroup
+	results := make([][]Migration, 2)
+	errs := make([]error, 2)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = newMigrator().Up()
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if applied := len(results[0]) + len(results[1]); applied != 2 {
+		t.Fatalf("applied %v and %v, want two migrations in total", versions(results[0]), versions(results[1]))
+	}
+	if !tableExists("base") || !tableExists("extra") {
+		t.Fatal("tables missing after Up")
+	}
+
+	m := newMigrator()
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Errorf("%s is pending after Up", status.Migration)
+		}
+	}
+
+	// Reverting past the baseline fails before 0002 is reverted
+	if _, err := m.Down(2); !errors.Is(err, ErrMissingDown) {
+		t.Fatalf("Down(2) err = %v, want %v", err, ErrMissingDown)
+	}
+	if !tableExists("extra") {
+		t.Fatal("0002 was reverted by a refused Down")
+	}
+
+	reverted, err := m.Down(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameVersions(reverted, 2) || tableExists("extra") {
+		t.Fatalf("Down(1) reverted %v", versions(reverted))
+	}
+
+	pending, err := m.Pending(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameVersions(pending, 2) {
+		t.Errorf("pending = %v, want [2]", versions(pending))
+	}
+}
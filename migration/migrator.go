@@ -0,0 +1,281 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// lockID identifies the advisory lock held while migrating ("oph" in ASCII)
+const lockID = 0x6f7068
+
+const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    bigint PRIMARY KEY,
+	name       text NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now()
+)`
+
+// Migrator applies and reverts migrations on one database
+type Migrator struct {
+	DB         *sql.DB
+	Migrations []Migration
+}
+
+// NewMigrator returns a migrator for the embedded migrations
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{DB: db, Migrations: migrations}, nil
+}
+
+// Up applies every pending migration and returns the ones applied
+func (m *Migrator) Up() ([]Migration, error) {
+	return m.To(m.Latest())
+}
+
+// Down reverts the n most recently applied migrations and returns the ones reverted. Nothing is reverted when
+// one of them has no down script.
+func (m *Migrator) Down(n int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(func(conn *sql.Conn, applied map[int]time.Time) error {
+		revert, err := planDown(m.Migrations, applied, n)
+		if err != nil {
+			return err
+		}
+		for _, mig := range revert {
+			if err := m.revert(conn, mig); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// To applies or reverts migrations until version is the newest applied one; 0 reverts everything.
+// Nothing is reverted when one of the migrations to revert has no down script.
+func (m *Migrator) To(version int) ([]Migration, error) {
+	if version != 0 && m.find(version) < 0 {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	var done []Migration
+	err := m.locked(func(conn *sql.Conn, applied map[int]time.Time) error {
+		revert, apply, err := planTo(m.Migrations, applied, version)
+		if err != nil {
+			return err
+		}
+		for _, mig := range revert {
+			if err := m.revert(conn, mig); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		for _, mig := range apply {
+			if err := m.apply(conn, mig); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// planDown returns the n newest applied migrations, newest first
+func planDown(migrations []Migration, applied map[int]time.Time, n int) ([]Migration, error) {
+	var revert []Migration
+	for i := len(migrations) - 1; i >= 0 && len(revert) < n; i-- {
+		if _, ok := applied[migrations[i].Version]; ok {
+			revert = append(revert, migrations[i])
+		}
+	}
+	return revert, checkReversible(revert)
+}
+
+// planTo returns the applied migrations newer than version, newest first, and the pending ones up to it, oldest first
+func planTo(migrations []Migration, applied map[int]time.Time, version int) (revert []Migration, apply []Migration, err error) {
+	for i := len(migrations) - 1; i >= 0; i-- {
+		if _, ok := applied[migrations[i].Version]; ok && migrations[i].Version > version {
+			revert = append(revert, migrations[i])
+		}
+	}
+	for _, mig := range migrations {
+		if _, ok := applied[mig.Version]; !ok && mig.Version <= version {
+			apply = append(apply, mig)
+		}
+	}
+	if err := checkReversible(revert); err != nil {
+		return nil, nil, err
+	}
+	return revert, apply, nil
+}
+
+// checkReversible fails before anything runs, rather than after reverting the migrations above one without a down script
+func checkReversible(revert []Migration) error {
+	for _, mig := range revert {
+		if strings.TrimSpace(mig.Down) == "" {
+			return fmt.Errorf("%w: %s", ErrMissingDown, mig)
+		}
+	}
+	return nil
+}
+
+// Status lists every known migration with the time it was applied
+func (m *Migrator) Status() ([]Status, error) {
+	var statuses []Status
+	err := m.locked(func(conn *sql.Conn, applied map[int]time.Time) error {
+		for _, mig := range m.Migrations {
+			status := Status{Migration: mig}
+			if at, ok := applied[mig.Version]; ok {
+				status.AppliedAt = &at
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// Pending returns the known migrations not yet applied. Unlike Status it does not wait for the
+// migration lock, so it is cheap enough for readiness checks.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	rows, err := m.DB.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, mig := range m.Migrations {
+		if !applied[mig.Version] {
+			pending = append(pending, mig)
+		}
+	}
+	return pending, nil
+}
+
+// Latest returns the newest known version
+func (m *Migrator) Latest() int {
+	if len(m.Migrations) == 0 {
+		return 0
+	}
+	return m.Migrations[len(m.Migrations)-1].Version
+}
+
+func (m *Migrator) find(version int) int {
+	for i, mig := range m.Migrations {
+		if mig.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// locked runs fn on a dedicated connection holding the migration lock, with the applied versions
+func (m *Migrator) locked(fn func(conn *sql.Conn, applied map[int]time.Time) error) error {
+	ctx := context.Background()
+
+	// Advisory locks belong to a session, so the lock and all migrations must use the same connection
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Waiting for another replica and building indexes may outlast the configured statement timeout
+	if _, err := conn.ExecContext(ctx, "SET statement_timeout = 0"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "RESET statement_timeout")
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", lockID); err != nil {
+			log.Printf("Failed to release migration lock: %v", err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, createTable); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return err
+		}
+		applied[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	return fn(conn, applied)
+}
+
+// apply runs an up script and records it in one transaction
+func (m *Migrator) apply(conn *sql.Conn, mig Migration) error {
+	return inTx(conn, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(mig.Up); err != nil {
+			return fmt.Errorf("apply %s: %w", mig, err)
+		}
+		_, err := tx.Exec("INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", mig.Version, mig.Name)
+		return err
+	})
+}
+
+// revert runs a down script and removes its record in one transaction
+func (m *Migrator) revert(conn *sql.Conn, mig Migration) error {
+	if mig.Down == "" {
+		return fmt.Errorf("%w: %s", ErrMissingDown, mig)
+	}
+	return inTx(conn, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(mig.Down); err != nil {
+			return fmt.Errorf("revert %s: %w", mig, err)
+		}
+		_, err := tx.Exec("DELETE FROM schema_migrations WHERE version = $1", mig.Version)
+		return err
+	})
+}
+
+func inTx(conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,62 @@
+-- Baseline: the schema the server created with gorm AutoMigrate before migrations existed
+-- (users, student_transactions and student_evaluations). Every statement is idempotent so
+-- databases that were set up by AutoMigrate are adopted unchanged; everything added since
+-- lives in the numbered migrations that follow.
+
+CREATE TABLE IF NOT EXISTS users (
+    id               text PRIMARY KEY,
+    uid              text CONSTRAINT uni_users_uid UNIQUE,
+    name             text,
+    role             text,
+    email            text,
+    phone            text CONSTRAINT uni_users_phone UNIQUE,
+    birth_date       timestamptz,
+    status           text,
+    other_status     text,
+    province         text,
+    school           text,
+    selected_sources text[],
+    other_source     text,
+    first_interest   text,
+    second_interest  text,
+    third_interest   text,
+    objective        text,
+    registered_at    timestamptz,
+    last_entered     timestamptz,
+    faculty          text,
+    student_id       text,
+    nickname         text,
+    year             bigint,
+    is_central_staff boolean
+);
+
+CREATE TABLE IF NOT EXISTS student_transactions (
+    id                      text PRIMARY KEY,
+    student_registration_id text,
+    faculty                 text,
+    registered_at           timestamptz,
+    CONSTRAINT fk_student_transactions_student FOREIGN KEY (student_registration_id)
+        REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS student_evaluations (
+    id                                      bigserial PRIMARY KEY,
+    student_id                              text NOT NULL,
+    new_sources                             text[],
+    overall_activity                        bigint,
+    interest_activity                       bigint,
+    received_faculty_info_clearly           bigint,
+    would_recommend_cu_open_house_next_time bigint,
+    favorite_booth                          text,
+    activity_diversity                      bigint,
+    perceived_crowd_density                 bigint,
+    has_full_booth_access                   bigint,
+    facility_convenience_rating             bigint,
+    campus_navigation_rating                bigint,
+    hesitation_level_after_disaster         bigint,
+    line_oa_signup_rating                   bigint,
+    design_beauty_rating                    bigint,
+    website_improvement_suggestions         text,
+    CONSTRAINT fk_student_evaluations_student FOREIGN KEY (student_id)
+        REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS evaluation_answers;
+ALTER TABLE student_evaluations DROP CONSTRAINT IF EXISTS fk_student_evaluations_questionnaire;
+ALTER TABLE student_evaluations DROP COLUMN IF EXISTS questionnaire_id;
+DROP TABLE IF EXISTS questions;
+DROP TABLE IF EXISTS questionnaires;
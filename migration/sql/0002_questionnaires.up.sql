@@ -0,0 +1,64 @@
+-- Versioned evaluation forms. Evaluations point at the version they were submitted with and
+-- keep one answer per question; the fixed columns stay for version 1 clients.
+
+CREATE TABLE IF NOT EXISTS questionnaires (
+    id                 bigserial PRIMARY KEY,
+    version            bigint NOT NULL,
+    title              text,
+    faculty            text,
+    is_active          boolean NOT NULL DEFAULT false,
+    require_attendance boolean NOT NULL DEFAULT false,
+    created_at         timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_questionnaires_faculty ON questionnaires (faculty);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_questionnaires_version ON questionnaires (version);
+
+CREATE TABLE IF NOT EXISTS questions (
+    id               bigserial PRIMARY KEY,
+    questionnaire_id bigint NOT NULL,
+    "key"            text NOT NULL,
+    type             text NOT NULL,
+    position         bigint,
+    required         boolean,
+    label_th         text,
+    label_en         text,
+    scale_min        bigint,
+    scale_max        bigint,
+    options          text[],
+    CONSTRAINT fk_questionnaires_questions FOREIGN KEY (questionnaire_id)
+        REFERENCES questionnaires (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_question_key ON questions (questionnaire_id, "key");
+
+-- Evaluations submitted before questionnaires existed keep a NULL questionnaire_id until
+-- they are copied into version 1 answers at startup.
+ALTER TABLE student_evaluations ADD COLUMN IF NOT EXISTS questionnaire_id bigint;
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'fk_student_evaluations_questionnaire'
+          AND conrelid = 'student_evaluations'::regclass
+    ) THEN
+        ALTER TABLE student_evaluations
+            ADD CONSTRAINT fk_student_evaluations_questionnaire FOREIGN KEY (questionnaire_id)
+            REFERENCES questionnaires (id) ON DELETE RESTRICT ON UPDATE CASCADE;
+    END IF;
+END
+$$;
+
+CREATE TABLE IF NOT EXISTS evaluation_answers (
+    id            bigserial PRIMARY KEY,
+    evaluation_id bigint NOT NULL,
+    question_id   bigint NOT NULL,
+    question_key  text NOT NULL,
+    score         bigint,
+    text          text,
+    choices       text[],
+    tags          text[],
+    CONSTRAINT fk_student_evaluations_answers FOREIGN KEY (evaluation_id)
+        REFERENCES student_evaluations (id) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_evaluation_answers_question FOREIGN KEY (question_id)
+        REFERENCES questions (id) ON DELETE RESTRICT ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_evaluation_answers_evaluation_id ON evaluation_answers (evaluation_id);
@@ -0,0 +1,17 @@
+-- Asynchronous exports; the file itself is written to EXPORT_DIR
+CREATE TABLE IF NOT EXISTS export_jobs (
+    id           text PRIMARY KEY,
+    requested_by text NOT NULL,
+    status       text NOT NULL,
+    format       text,
+    columns      text,
+    faculty      text,
+    "by"         text,
+    error        text,
+    file_path    text,
+    created_at   timestamptz,
+    completed_at timestamptz,
+    expires_at   timestamptz,
+    CONSTRAINT fk_export_jobs_requester FOREIGN KEY (requested_by)
+        REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
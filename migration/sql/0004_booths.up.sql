@@ -0,0 +1,7 @@
+-- Known booths and the spellings students use for them in favoriteBooth
+CREATE TABLE IF NOT EXISTS booths (
+    id      bigserial PRIMARY KEY,
+    name    text NOT NULL CONSTRAINT uni_booths_name UNIQUE,
+    faculty text,
+    aliases text[]
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS faculty_survey_answers;
+DROP TABLE IF EXISTS faculty_survey_responses;
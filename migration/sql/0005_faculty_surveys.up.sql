@@ -0,0 +1,29 @@
+-- Answers to the faculty surveys, which are questionnaires owned by a faculty
+CREATE TABLE IF NOT EXISTS faculty_survey_responses (
+    id               bigserial PRIMARY KEY,
+    student_id       text NOT NULL,
+    questionnaire_id bigint NOT NULL,
+    faculty          text NOT NULL,
+    created_at       timestamptz,
+    CONSTRAINT fk_faculty_survey_responses_questionnaire FOREIGN KEY (questionnaire_id)
+        REFERENCES questionnaires (id) ON DELETE RESTRICT ON UPDATE CASCADE,
+    CONSTRAINT fk_faculty_survey_responses_student FOREIGN KEY (student_id)
+        REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_faculty_survey_responses_faculty ON faculty_survey_responses (faculty);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_survey_response ON faculty_survey_responses (student_id, questionnaire_id);
+
+CREATE TABLE IF NOT EXISTS faculty_survey_answers (
+    id           bigserial PRIMARY KEY,
+    response_id  bigint NOT NULL,
+    question_id  bigint NOT NULL,
+    question_key text NOT NULL,
+    score        bigint,
+    text         text,
+    choices      text[],
+    CONSTRAINT fk_faculty_survey_answers_question FOREIGN KEY (question_id)
+        REFERENCES questions (id) ON DELETE RESTRICT ON UPDATE CASCADE,
+    CONSTRAINT fk_faculty_survey_responses_answers FOREIGN KEY (response_id)
+        REFERENCES faculty_survey_responses (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_faculty_survey_answers_response_id ON faculty_survey_answers (response_id);
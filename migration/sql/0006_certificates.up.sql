@@ -0,0 +1,32 @@
+-- Certificate templates per event and the certificates issued from them
+CREATE TABLE IF NOT EXISTS certificate_templates (
+    id              bigserial PRIMARY KEY,
+    event           text NOT NULL,
+    event_name      text NOT NULL,
+    event_date      timestamptz,
+    title           text NOT NULL,
+    body            text,
+    font_file       text NOT NULL,
+    background_file text,
+    created_at      timestamptz,
+    updated_at      timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_certificate_templates_event ON certificate_templates (event);
+
+CREATE TABLE IF NOT EXISTS certificates (
+    serial            text PRIMARY KEY,
+    user_id           text NOT NULL,
+    uid               text,
+    holder_name       text NOT NULL,
+    event             text NOT NULL,
+    key_id            text NOT NULL,
+    token             text NOT NULL,
+    issued_at         timestamptz NOT NULL,
+    revoked_at        timestamptz,
+    revocation_reason text,
+    revoked_by        text,
+    CONSTRAINT fk_certificates_user FOREIGN KEY (user_id)
+        REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_certificates_revoked_at ON certificates (revoked_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_active_certificate ON certificates (user_id, event) WHERE revoked_at IS NULL;
@@ -0,0 +1,18 @@
+-- Put the duplicate evaluations moved aside by the up migration back, with their answers
+DROP INDEX IF EXISTS idx_student_evaluations_student_id;
+
+ALTER TABLE student_evaluation_duplicates DROP COLUMN moved_at;
+
+INSERT INTO student_evaluations
+SELECT * FROM student_evaluation_duplicates;
+
+INSERT INTO evaluation_answers
+SELECT * FROM evaluation_answer_duplicates;
+
+DROP TABLE student_evaluation_duplicates;
+DROP TABLE evaluation_answer_duplicates;
+
+DROP INDEX IF EXISTS idx_users_last_entered;
+DROP INDEX IF EXISTS idx_users_role_registered_at;
+DROP INDEX IF EXISTS idx_student_transactions_registered_at;
+DROP INDEX IF EXISTS idx_student_transactions_student_faculty;
@@ -0,0 +1,52 @@
+-- Indexes for the QR scan, dashboard and evaluation lookups. The tables are small enough at
+-- event scale that a plain CREATE INDEX (which cannot run CONCURRENTLY inside a migration
+-- transaction) only blocks writes briefly.
+
+-- Faculty scans look up a student's transactions for one faculty
+CREATE INDEX IF NOT EXISTS idx_student_transactions_student_faculty
+    ON student_transactions (student_registration_id, faculty);
+
+-- Today's registrations per faculty on the dashboard
+CREATE INDEX IF NOT EXISTS idx_student_transactions_registered_at
+    ON student_transactions (registered_at);
+
+-- Student listings and exports filter by role and order by registration time
+CREATE INDEX IF NOT EXISTS idx_users_role_registered_at
+    ON users (role, registered_at);
+
+-- Attendance counts only look at users who have been scanned in
+CREATE INDEX IF NOT EXISTS idx_users_last_entered
+    ON users (last_entered) WHERE last_entered IS NOT NULL;
+
+-- A student submits one evaluation. Concurrent submissions could slip past the application
+-- check before this index existed; keep the first one so the index can be built. The later
+-- submissions and their answers are moved to side tables without keys rather than dropped, so
+-- they can be reviewed and restored by hand. The down migration moves them back.
+CREATE TABLE IF NOT EXISTS student_evaluation_duplicates (
+    LIKE student_evaluations,
+    moved_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS evaluation_answer_duplicates (
+    LIKE evaluation_answers
+);
+
+INSERT INTO student_evaluation_duplicates
+SELECT e.*, now()
+FROM student_evaluations e
+WHERE EXISTS (
+    SELECT 1 FROM student_evaluations earlier
+    WHERE earlier.student_id = e.student_id AND earlier.id < e.id
+);
+
+INSERT INTO evaluation_answer_duplicates
+SELECT a.*
+FROM evaluation_answers a
+WHERE a.evaluation_id IN (SELECT id FROM student_evaluation_duplicates);
+
+-- Answers of the moved evaluations are deleted by ON DELETE CASCADE
+DELETE FROM student_evaluations
+WHERE id IN (SELECT id FROM student_evaluation_duplicates);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_student_evaluations_student_id
+    ON student_evaluations (student_id);
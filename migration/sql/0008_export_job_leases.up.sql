@@ -0,0 +1,3 @@
+-- Export workers renew a lease on the job they run, so the sweeper only requeues jobs whose
+-- worker has died instead of every running job on each startup.
+ALTER TABLE export_jobs ADD COLUMN IF NOT EXISTS heartbeat_at timestamptz;
@@ -0,0 +1,3 @@
+-- Fails if two owners already share a version number; renumber them before reverting.
+DROP INDEX IF EXISTS idx_questionnaires_faculty_version;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_questionnaires_version ON questionnaires (version);
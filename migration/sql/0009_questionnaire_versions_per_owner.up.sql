@@ -0,0 +1,6 @@
+-- The event-wide evaluation and each faculty survey number their versions separately, so
+-- version numbers are unique per owner instead of across all questionnaires. faculty is NULL
+-- for the event, which a plain unique index would never treat as a duplicate.
+DROP INDEX IF EXISTS idx_questionnaires_version;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_questionnaires_faculty_version
+    ON questionnaires ((COALESCE(faculty, '')), version);
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"gorm.io/gorm"
+)
+
+type CertificateRepository struct {
+	DB *gorm.DB
+}
+
+func NewCertificateRepository(db *gorm.DB) *CertificateRepository {
+	return &CertificateRepository{DB: db}
+}
+
+func (r *CertificateRepository) Create(ctx context.Context, certificate *domain.Certificate) error {
+	return r.DB.WithContext(ctx).Create(certificate).Error
+}
+
+func (r *CertificateRepository) GetBySerial(ctx context.Context, serial string) (domain.Certificate, error) {
+	var certificate domain.Certificate
+	err := r.DB.WithContext(ctx).Where("serial = ?", serial).First(&certificate).Error
+	return certificate, err
+}
+
+// GetActive returns the unrevoked certificate of a holder for an event
+func (r *CertificateRepository) GetActive(ctx context.Context, userId string, event string) (domain.Certificate, error) {
+	var certificate domain.Certificate
+	err := r.DB.WithContext(ctx).Where("user_id = ? AND event = ? AND revoked_at IS NULL", userId, event).First(&certificate).Error
+	return certificate, err
+}
+
+// GetAll returns issued certificates, newest first
+func (r *CertificateRepository) GetAll(ctx context.Context, filter domain.CertificateFilter) ([]domain.Certificate, error) {
+	query := r.DB.WithContext(ctx).Model(&domain.Certificate{})
+	if filter.Event != "" {
+		query = query.Where("event = ?", filter.Event)
+	}
+	if filter.Revoked != nil {
+		if *filter.Revoked {
+			query = query.Where("revoked_at IS NOT NULL")
+		} else {
+			query = query.Where("revoked_at IS NULL")
+		}
+	}
+
+	var certificates []domain.Certificate
+	err := query.Order("issued_at DESC").Find(&certificates).Error
+	return certificates, err
+}
+
+// Revoke marks an unrevoked certificate as revoked. gorm.ErrRecordNotFound is returned if no unrevoked
+// certificate has the serial.
+func (r *CertificateRepository) Revoke(ctx context.Context, serial string, reason string, revokedBy string, at time.Time) error {
+	result := r.DB.WithContext(ctx).Model(&domain.Certificate{}).
+		Where("serial = ? AND revoked_at IS NULL", serial).
+		Updates(map[string]interface{}{
+			"revoked_at":        at,
+			"revocation_reason": reason,
+			"revoked_by":        revokedBy,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CertificateTemplateRepository struct {
+	DB *gorm.DB
+}
+
+func NewCertificateTemplateRepository(db *gorm.DB) *CertificateTemplateRepository {
+	return &CertificateTemplateRepository{DB: db}
+}
+
+func (r *CertificateTemplateRepository) GetAll(ctx context.Context) ([]domain.CertificateTemplate, error) {
+	var templates []domain.CertificateTemplate
+	err := r.DB.WithContext(ctx).Order("event ASC").Find(&templates).Error
+	return templates, err
+}
+
+func (r *CertificateTemplateRepository) GetByEvent(ctx context.Context, event string) (domain.CertificateTemplate, error) {
+	var template domain.CertificateTemplate
+	err := r.DB.WithContext(ctx).Where("event = ?", event).First(&template).Error
+	return template, err
+}
+
+// Save creates the template of an event or replaces it if the event already has one
+func (r *CertificateTemplateRepository) Save(ctx context.Context, template *domain.CertificateTemplate) error {
+	return r.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "event"}},
+		DoUpdates: clause.AssignmentColumns([]string{"event_name", "event_date", "title", "body", "font_file", "background_file", "updated_at"}),
+	}).Create(template).Error
+}
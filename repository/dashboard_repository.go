@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"context"
+
 	"github.com/isd-sgcu/oph-67-backend/domain"
 	"gorm.io/gorm"
 )
@@ -13,7 +15,17 @@ func NewDashBoardRepository(db *gorm.DB) *DashBoardRepository {
 	return &DashBoardRepository{DB: db}
 }
 
-func (r *DashBoardRepository) GetFacultyCount() ([]domain.FacultyPercent, error) {
+// students returns the users statistics are computed over: everyone, or with a scope only the
+// students who listed that faculty among their interests. Raw queries use it as "FROM (?) AS users".
+func (r *DashBoardRepository) students(ctx context.Context, scope *string) *gorm.DB {
+	query := r.DB.WithContext(ctx).Table("users")
+	if scope != nil {
+		query = query.Scopes(interestedIn(*scope))
+	}
+	return query
+}
+
+func (r *DashBoardRepository) GetFacultyCount(ctx context.Context) ([]domain.FacultyPercent, error) {
 	var results []domain.FacultyPercent
 
 	query := `
@@ -33,26 +45,26 @@ func (r *DashBoardRepository) GetFacultyCount() ([]domain.FacultyPercent, error)
         GROUP BY t.faculty
         ORDER BY (SUM(t.first_count) + SUM(t.second_count) + SUM(t.third_count)) DESC;
     `
-	err := r.DB.Raw(query).Scan(&results).Error
+	err := r.DB.WithContext(ctx).Raw(query).Scan(&results).Error
 	return results, err
 }
 
-func (r *DashBoardRepository) GetSourceCount() ([]domain.SourceCount, error) {
+func (r *DashBoardRepository) GetSourceCount(ctx context.Context, scope *string) ([]domain.SourceCount, error) {
 	var results []domain.SourceCount
 	query :=
 		`SELECT source, COUNT(*) as count FROM (
-            SELECT unnest(selected_sources) as source FROM users WHERE selected_sources IS NOT NULL
+            SELECT unnest(selected_sources) as source FROM (?) AS users WHERE selected_sources IS NOT NULL
         ) AS sources
         GROUP BY source
         ORDER BY count DESC;`
-	err := r.DB.Raw(query).Scan(&results).Error
+	err := r.DB.WithContext(ctx).Raw(query, r.students(ctx, scope)).Scan(&results).Error
 	return results, err
 }
 
-func (r *DashBoardRepository) GetAgeGroupCount() ([]domain.AgeCount, error) {
+func (r *DashBoardRepository) GetAgeGroupCount(ctx context.Context, scope *string) ([]domain.AgeCount, error) {
 	var results []domain.AgeCount
 
-	err := r.DB.Model(&domain.User{}).
+	err := r.students(ctx, scope).
 		Select("EXTRACT(YEAR FROM AGE(birth_date)) AS age, COUNT(*) AS count").
 		Where("birth_date IS NOT NULL").
 		Group("age").
@@ -62,13 +74,13 @@ func (r *DashBoardRepository) GetAgeGroupCount() ([]domain.AgeCount, error) {
 	return results, err
 }
 
-func (r *DashBoardRepository) GetFacultyToday() ([]domain.FacultyRegisterCount, error) {
+func (r *DashBoardRepository) GetFacultyToday(ctx context.Context) ([]domain.FacultyRegisterCount, error) {
 	var result []domain.FacultyRegisterCount
 
 	// 1. เขียน Query หาคณะที่ลงทะเบียนมากที่สุดในวันนี้
-	err := r.DB.Model(&domain.StudentTransaction{}).
+	err := r.DB.WithContext(ctx).Model(&domain.StudentTransaction{}).
 		Select("faculty, COUNT(*) as count").
-		Where("DATE(registered_at) = CURRENT_DATE").
+		Where("registered_at >= CURRENT_DATE AND registered_at < CURRENT_DATE + 1"). // range so the registered_at index applies
 		Group("faculty").
 		Order("count DESC").
 		Scan(&result).Error
@@ -76,67 +88,35 @@ func (r *DashBoardRepository) GetFacultyToday() ([]domain.FacultyRegisterCount,
 	return result, err
 }
 
-func (r *DashBoardRepository) GetStatusStudent() ([]domain.StatusCount, error) {
+func (r *DashBoardRepository) GetStatusStudent(ctx context.Context, scope *string) ([]domain.StatusCount, error) {
 	var results []domain.StatusCount
 	query :=
-		`SELECT status, COUNT(*) as count FROM users
+		`SELECT status, COUNT(*) as count FROM (?) AS users
 		WHERE status IS NOT NULL
 		GROUP BY status
 		ORDER BY count DESC;`
-	err := r.DB.Raw(query).Scan(&results).Error
+	err := r.DB.WithContext(ctx).Raw(query, r.students(ctx, scope)).Scan(&results).Error
 	return results, err
 }
 
-func (r *DashBoardRepository) GetAllStudents() ([]domain.StudentProfile, error) {
-	var students []domain.StudentProfile
-
-	err := r.DB.Model(&domain.User{}).
-		Select(
-			"id",
-			"name",
-			"email",
-			"phone",
-			"first_interest",
-			"second_interest",
-			"third_interest",
-			"registered_at",
-		).
-		Where("role = ?", domain.Student).
-		Scan(&students).
-		Error
-
-	return students, err
+// interestedIn keeps the students who listed the faculty among their interests
+func interestedIn(faculty string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("? IN (first_interest, second_interest, third_interest)", faculty)
+	}
 }
 
-func (r *DashBoardRepository) GetStudentsByFacultyInterest(faculty string) ([]domain.StudentProfile, error) {
-	var students []domain.StudentProfile
-
-	err := r.DB.Model(&domain.User{}).
-		Select(
-			"id",
-			"name",
-			"email",
-			"phone",
-			"first_interest",
-			"second_interest",
-			"third_interest",
-		).
-		Where("role = ?", domain.Student).
-		Where(
-			r.DB.Where("first_interest = ?", faculty).
-				Or("second_interest = ?", faculty).
-				Or("third_interest = ?", faculty),
-		).
-		Scan(&students).
-		Error
-
-	return students, err
+// visited keeps the students scanned in at the faculty's booth
+func visited(faculty string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("EXISTS (SELECT 1 FROM student_transactions t WHERE t.student_registration_id = users.id AND t.faculty = ?)", faculty)
+	}
 }
 
-func (r *DashBoardRepository) GetAttendedCount() ([]domain.AttendedCount, error) {
+func (r *DashBoardRepository) GetAttendedCount(ctx context.Context, scope *string) ([]domain.AttendedCount, error) {
 	var results []domain.AttendedCount
 
-	err := r.DB.Model(&domain.User{}).
+	err := r.students(ctx, scope).
 		Select("COUNT(*) AS count").
 		Where("last_entered IS NOT NULL").
 		Scan(&results).Error
@@ -144,3 +124,66 @@ func (r *DashBoardRepository) GetAttendedCount() ([]domain.AttendedCount, error)
 	return results, err
 
 }
+
+func (r *DashBoardRepository) GetProvinceCount(ctx context.Context, scope *string) ([]domain.ProvinceCount, error) {
+	var results []domain.ProvinceCount
+	query :=
+		`SELECT TRIM(province) AS province,
+			COUNT(*) AS registered,
+			COUNT(last_entered) AS attended
+		FROM (?) AS users
+		WHERE role = ? AND province IS NOT NULL AND TRIM(province) <> ''
+		GROUP BY TRIM(province)
+		ORDER BY registered DESC;`
+	err := r.DB.WithContext(ctx).Raw(query, r.students(ctx, scope), domain.Student).Scan(&results).Error
+	return results, err
+}
+
+func (r *DashBoardRepository) GetSchoolCount(ctx context.Context, scope *string) ([]domain.SchoolCount, error) {
+	var results []domain.SchoolCount
+	query :=
+		`SELECT TRIM(school) AS school,
+			COUNT(*) AS registered,
+			COUNT(last_entered) AS attended
+		FROM (?) AS users
+		WHERE role = ? AND school IS NOT NULL AND TRIM(school) <> ''
+		GROUP BY TRIM(school)
+		ORDER BY registered DESC, school ASC;`
+	err := r.DB.WithContext(ctx).Raw(query, r.students(ctx, scope), domain.Student).Scan(&results).Error
+	return results, err
+}
+
+// StreamStudents calls fn for every student matching the filter, reading one row at a time
+func (r *DashBoardRepository) StreamStudents(ctx context.Context, filter domain.StudentExportFilter, fn func(row *domain.StudentExportRow) error) error {
+	query := r.DB.WithContext(ctx).Model(&domain.User{}).
+		Select(
+			"users.*",
+			"(SELECT string_agg(DISTINCT t.faculty, ',') FROM student_transactions t WHERE t.student_registration_id = users.id) AS faculties_visited",
+			"EXISTS (SELECT 1 FROM student_evaluations e WHERE e.student_id = users.id) AS evaluated",
+		).
+		Where("role = ?", domain.Student)
+
+	switch filter.By {
+	case domain.ByInterest:
+		query = query.Scopes(interestedIn(filter.Faculty))
+	case domain.ByVisit:
+		query = query.Scopes(visited(filter.Faculty))
+	}
+
+	rows, err := query.Order("registered_at ASC").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row domain.StudentExportRow
+		if err := r.DB.WithContext(ctx).ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"gorm.io/gorm"
+)
+
+type ExportJobRepository struct {
+	DB *gorm.DB
+}
+
+func NewExportJobRepository(db *gorm.DB) *ExportJobRepository {
+	return &ExportJobRepository{DB: db}
+}
+
+func (r *ExportJobRepository) Create(ctx context.Context, job *domain.ExportJob) error {
+	return r.DB.WithContext(ctx).Create(job).Error
+}
+
+func (r *ExportJobRepository) GetById(ctx context.Context, id string) (domain.ExportJob, error) {
+	var job domain.ExportJob
+	err := r.DB.WithContext(ctx).Where("id = ?", id).First(&job).Error
+	return job, err
+}
+
+func (r *ExportJobRepository) GetByStatus(ctx context.Context, status domain.ExportJobStatus) ([]domain.ExportJob, error) {
+	var jobs []domain.ExportJob
+	err := r.DB.WithContext(ctx).Where("status = ?", status).Order("created_at ASC").Find(&jobs).Error
+	return jobs, err
+}
+
+// GetExpired returns finished jobs whose retention period has passed
+func (r *ExportJobRepository) GetExpired(ctx context.Context, now time.Time) ([]domain.ExportJob, error) {
+	var jobs []domain.ExportJob
+	err := r.DB.WithContext(ctx).Where("status IN ? AND expires_at <= ?", []domain.ExportJobStatus{domain.ExportJobDone, domain.ExportJobFailed}, now).
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// Claim moves a job from pending to running and starts its lease. It returns false if another worker got it first.
+func (r *ExportJobRepository) Claim(ctx context.Context, id string) (bool, error) {
+	result := r.DB.WithContext(ctx).Model(&domain.ExportJob{}).
+		Where("id = ? AND status = ?", id, domain.ExportJobPending).
+		Updates(map[string]interface{}{"status": domain.ExportJobRunning, "heartbeat_at": time.Now()})
+	return result.RowsAffected == 1, result.Error
+}
+
+// Heartbeat renews the lease on a running job
+func (r *ExportJobRepository) Heartbeat(ctx context.Context, id string, now time.Time) error {
+	return r.DB.WithContext(ctx).Model(&domain.ExportJob{}).
+		Where("id = ? AND status = ?", id, domain.ExportJobRunning).
+		Update("heartbeat_at", now).Error
+}
+
+// Reclaim moves running jobs whose lease was last renewed before staleBefore back to pending
+func (r *ExportJobRepository) Reclaim(ctx context.Context, staleBefore time.Time) (int64, error) {
+	result := r.DB.WithContext(ctx).Model(&domain.ExportJob{}).
+		Where("status = ? AND (heartbeat_at IS NULL OR heartbeat_at < ?)", domain.ExportJobRunning, staleBefore).
+		Update("status", domain.ExportJobPending)
+	return result.RowsAffected, result.Error
+}
+
+func (r *ExportJobRepository) Update(ctx context.Context, job *domain.ExportJob) error {
+	return r.DB.WithContext(ctx).Save(job).Error
+}
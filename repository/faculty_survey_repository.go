@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"gorm.io/gorm"
+)
+
+type FacultySurveyRepository struct {
+	DB *gorm.DB
+}
+
+func NewFacultySurveyRepository(db *gorm.DB) *FacultySurveyRepository {
+	return &FacultySurveyRepository{DB: db}
+}
+
+// CreateResponse stores a response and its answers
+func (r *FacultySurveyRepository) CreateResponse(ctx context.Context, response *domain.FacultySurveyResponse) error {
+	return r.DB.WithContext(ctx).Create(response).Error
+}
+
+// GetRespondedQuestionnaireIDs returns the faculty questionnaires the student has already answered
+func (r *FacultySurveyRepository) GetRespondedQuestionnaireIDs(ctx context.Context, studentId string) ([]int, error) {
+	var ids []int
+	err := r.DB.WithContext(ctx).Model(&domain.FacultySurveyResponse{}).
+		Where("student_id = ?", studentId).
+		Pluck("questionnaire_id", &ids).Error
+	return ids, err
+}
+
+func (r *FacultySurveyRepository) HasResponded(ctx context.Context, studentId string, questionnaireId int) (bool, error) {
+	var count int64
+	err := r.DB.WithContext(ctx).Model(&domain.FacultySurveyResponse{}).
+		Where("student_id = ? AND questionnaire_id = ?", studentId, questionnaireId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetResponses returns every response to a questionnaire, oldest first
+func (r *FacultySurveyRepository) GetResponses(ctx context.Context, questionnaireId int) ([]domain.FacultySurveyResponse, error) {
+	var responses []domain.FacultySurveyResponse
+	err := r.DB.WithContext(ctx).Preload("Answers").
+		Where("questionnaire_id = ?", questionnaireId).
+		Order("created_at ASC").
+		Find(&responses).Error
+	return responses, err
+}
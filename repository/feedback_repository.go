@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+type FeedbackRepository struct {
+	DB *gorm.DB
+}
+
+func NewFeedbackRepository(db *gorm.DB) *FeedbackRepository {
+	return &FeedbackRepository{DB: db}
+}
+
+func (r *FeedbackRepository) textAnswers(ctx context.Context, question string) *gorm.DB {
+	return r.DB.WithContext(ctx).Table("evaluation_answers a").
+		Joins("JOIN student_evaluations e ON e.id = a.evaluation_id").
+		Where("a.question_key = ? AND a.text IS NOT NULL AND TRIM(a.text) <> ''", question)
+}
+
+// GetTextAnswers pages through the free-text answers to a question, optionally filtered by tag and a search term
+func (r *FeedbackRepository) GetTextAnswers(ctx context.Context, question string, tag string, search string, limit int, offset int) ([]domain.TextAnswer, int64, error) {
+	filtered := func() *gorm.DB {
+		query := r.textAnswers(ctx, question)
+		if tag != "" {
+			query = query.Where("? = ANY(a.tags)", tag)
+		}
+		if search != "" {
+			query = query.Where("a.text ILIKE ?", "%"+search+"%")
+		}
+		return query
+	}
+
+	var total int64
+	if err := filtered().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var answers []domain.TextAnswer
+	err := filtered().
+		Select("a.id AS answer_id, a.evaluation_id, e.student_id, a.question_key AS question, a.text, COALESCE(a.tags, '{}') AS tags").
+		Order("a.id DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&answers).Error
+	return answers, total, err
+}
+
+// GetTexts returns every free-text answer to a question
+func (r *FeedbackRepository) GetTexts(ctx context.Context, question string) ([]string, error) {
+	var texts []string
+	err := r.textAnswers(ctx, question).Pluck("a.text", &texts).Error
+	return texts, err
+}
+
+// GetTextCounts counts identical answers to a question after trimming
+func (r *FeedbackRepository) GetTextCounts(ctx context.Context, question string) ([]domain.SpellingCount, error) {
+	var counts []domain.SpellingCount
+	err := r.textAnswers(ctx, question).
+		Select("TRIM(a.text) AS text, COUNT(*) AS count").
+		Group("TRIM(a.text)").
+		Order("count DESC").
+		Scan(&counts).Error
+	return counts, err
+}
+
+func (r *FeedbackRepository) SetAnswerTags(ctx context.Context, id int, tags []string) error {
+	result := r.DB.WithContext(ctx).Model(&domain.EvaluationAnswer{}).Where("id = ?", id).Update("tags", pq.StringArray(tags))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *FeedbackRepository) GetBooths(ctx context.Context) ([]domain.Booth, error) {
+	var booths []domain.Booth
+	err := r.DB.WithContext(ctx).Order("name ASC").Find(&booths).Error
+	return booths, err
+}
+
+func (r *FeedbackRepository) CreateBooth(ctx context.Context, booth *domain.Booth) error {
+	return r.DB.WithContext(ctx).Create(booth).Error
+}
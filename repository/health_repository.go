@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/isd-sgcu/oph-67-backend/migration"
+	"gorm.io/gorm"
+)
+
+type HealthRepository struct {
+	DB       *gorm.DB
+	Migrator *migration.Migrator
+}
+
+func NewHealthRepository(db *gorm.DB, migrator *migration.Migrator) *HealthRepository {
+	return &HealthRepository{DB: db, Migrator: migrator}
+}
+
+func (r *HealthRepository) Ping(ctx context.Context) error {
+	sqlDB, err := r.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// PendingMigrations returns the names of the migrations not yet applied
+func (r *HealthRepository) PendingMigrations(ctx context.Context) ([]string, error) {
+	pending, err := r.Migrator.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(pending))
+	for i, m := range pending {
+		names[i] = m.String()
+	}
+	return names, nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"gorm.io/gorm"
+)
+
+type QuestionnaireRepository struct {
+	DB *gorm.DB
+}
+
+func NewQuestionnaireRepository(db *gorm.DB) *QuestionnaireRepository {
+	return &QuestionnaireRepository{DB: db}
+}
+
+func orderedQuestions(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
+
+// ownedBy restricts a query to the questionnaires of one owner: the event when faculty is nil, or that faculty
+func ownedBy(db *gorm.DB, faculty *string) *gorm.DB {
+	if faculty == nil {
+		return db.Where("faculty IS NULL")
+	}
+	return db.Where("faculty = ?", *faculty)
+}
+
+// Create stores a questionnaire and its questions as the next version of its owner. Creations for the same owner
+// take a transaction-level advisory lock, so concurrent requests get consecutive numbers instead of failing on the
+// unique index on (faculty, version).
+func (r *QuestionnaireRepository) Create(ctx context.Context, questionnaire *domain.Questionnaire) error {
+	owner := "questionnaires:"
+	if questionnaire.Faculty != nil {
+		owner += *questionnaire.Faculty
+	}
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", owner).Error; err != nil {
+			return err
+		}
+		var latest int
+		err := ownedBy(tx.Model(&domain.Questionnaire{}), questionnaire.Faculty).Select("COALESCE(MAX(version), 0)").Scan(&latest).Error
+		if err != nil {
+			return err
+		}
+		questionnaire.Version = latest + 1
+		return tx.Create(questionnaire).Error
+	})
+}
+
+func (r *QuestionnaireRepository) GetAll(ctx context.Context) ([]domain.Questionnaire, error) {
+	var questionnaires []domain.Questionnaire
+	err := r.DB.WithContext(ctx).Preload("Questions", orderedQuestions).Order("version DESC").Find(&questionnaires).Error
+	return questionnaires, err
+}
+
+func (r *QuestionnaireRepository) GetById(ctx context.Context, id int) (domain.Questionnaire, error) {
+	var questionnaire domain.Questionnaire
+	err := r.DB.WithContext(ctx).Preload("Questions", orderedQuestions).Where("id = ?", id).First(&questionnaire).Error
+	return questionnaire, err
+}
+
+// GetByVersion returns a version of the event-wide evaluation
+func (r *QuestionnaireRepository) GetByVersion(ctx context.Context, version int) (domain.Questionnaire, error) {
+	var questionnaire domain.Questionnaire
+	err := r.DB.WithContext(ctx).Preload("Questions", orderedQuestions).Where("version = ? AND faculty IS NULL", version).First(&questionnaire).Error
+	return questionnaire, err
+}
+
+func (r *QuestionnaireRepository) GetActive(ctx context.Context) (domain.Questionnaire, error) {
+	var questionnaire domain.Questionnaire
+	err := r.DB.WithContext(ctx).Preload("Questions", orderedQuestions).Where("is_active = ? AND faculty IS NULL", true).First(&questionnaire).Error
+	return questionnaire, err
+}
+
+// GetByFaculty returns every version of a faculty's survey, newest first
+func (r *QuestionnaireRepository) GetByFaculty(ctx context.Context, faculty string) ([]domain.Questionnaire, error) {
+	var questionnaires []domain.Questionnaire
+	err := r.DB.WithContext(ctx).Preload("Questions", orderedQuestions).Where("faculty = ?", faculty).Order("version DESC").Find(&questionnaires).Error
+	return questionnaires, err
+}
+
+// GetActiveByFaculties returns the active survey of each of the given faculties that has one
+func (r *QuestionnaireRepository) GetActiveByFaculties(ctx context.Context, faculties []string) ([]domain.Questionnaire, error) {
+	var questionnaires []domain.Questionnaire
+	if len(faculties) == 0 {
+		return questionnaires, nil
+	}
+	err := r.DB.WithContext(ctx).Preload("Questions", orderedQuestions).
+		Where("is_active = ? AND faculty IN ?", true, faculties).
+		Order("faculty ASC").
+		Find(&questionnaires).Error
+	return questionnaires, err
+}
+
+// GetLatestVersion returns the newest version number of an owner (see ownedBy), or 0 if it has none
+func (r *QuestionnaireRepository) GetLatestVersion(ctx context.Context, faculty *string) (int, error) {
+	var version int
+	err := ownedBy(r.DB.WithContext(ctx).Model(&domain.Questionnaire{}), faculty).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// Activate makes one questionnaire the active version and deactivates the others of the same owner
+// (the event, or the same faculty)
+func (r *QuestionnaireRepository) Activate(ctx context.Context, id int) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var questionnaire domain.Questionnaire
+		if err := tx.Where("id = ?", id).First(&questionnaire).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&domain.Questionnaire{}).Where("id = ?", id).Update("is_active", true).Error; err != nil {
+			return err
+		}
+
+		others := ownedBy(tx.Model(&domain.Questionnaire{}).Where("id <> ?", id), questionnaire.Faculty)
+		return others.Update("is_active", false).Error
+	})
+}
+
+func (r *QuestionnaireRepository) SetRequireAttendance(ctx context.Context, id int, require bool) error {
+	result := r.DB.WithContext(ctx).Model(&domain.Questionnaire{}).Where("id = ?", id).Update("require_attendance", require)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"context"
+
 	"github.com/isd-sgcu/oph-67-backend/domain"
 	"gorm.io/gorm"
 )
@@ -13,49 +15,72 @@ func NewStudentEvaluationRepository(db *gorm.DB) *StudentEvaluationRepository {
 	return &StudentEvaluationRepository{DB: db}
 }
 
-func (r *StudentEvaluationRepository) CreateStudentEvaluation(evaluation *domain.StudentEvaluation) error {
-	return r.DB.Create(evaluation).Error
+func (r *StudentEvaluationRepository) CreateStudentEvaluation(ctx context.Context, evaluation *domain.StudentEvaluation) error {
+	return r.DB.WithContext(ctx).Create(evaluation).Error
 }
 
-func (r *StudentEvaluationRepository) GetStudentEvaluationByStudentId(studentId string) (*domain.StudentEvaluation, error) {
+func (r *StudentEvaluationRepository) GetStudentEvaluationByStudentId(ctx context.Context, studentId string) (*domain.StudentEvaluation, error) {
 	var evaluation domain.StudentEvaluation
-	err := r.DB.Where("student_id = ?", studentId).First(&evaluation).Error
+	err := r.DB.WithContext(ctx).Preload("Answers").Where("student_id = ?", studentId).First(&evaluation).Error
 	if err != nil {
 		return nil, err
 	}
 	return &evaluation, nil
 }
 
-func (r *StudentEvaluationRepository) UpdateStudentEvaluation(evaluation *domain.StudentEvaluation) error {
-	return r.DB.Save(evaluation).Error
+// UpdateStudentEvaluation saves the evaluation and replaces all of its answers
+func (r *StudentEvaluationRepository) UpdateStudentEvaluation(ctx context.Context, evaluation *domain.StudentEvaluation) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Answers").Save(evaluation).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("evaluation_id = ?", evaluation.ID).Delete(&domain.EvaluationAnswer{}).Error; err != nil {
+			return err
+		}
+		for i := range evaluation.Answers {
+			evaluation.Answers[i].ID = 0
+			evaluation.Answers[i].EvaluationID = evaluation.ID
+		}
+		if len(evaluation.Answers) == 0 {
+			return nil
+		}
+		return tx.Create(&evaluation.Answers).Error
+	})
 }
 
-func (r *StudentEvaluationRepository) DeleteStudentEvaluation(studentId string) error {
-	return r.DB.Where("student_id = ?", studentId).Delete(&domain.StudentEvaluation{}).Error
+func (r *StudentEvaluationRepository) DeleteStudentEvaluation(ctx context.Context, studentId string) error {
+	return r.DB.WithContext(ctx).Where("student_id = ?", studentId).Delete(&domain.StudentEvaluation{}).Error
 }
 
-func (r *StudentEvaluationRepository) GetAllStudentEvaluations() ([]domain.StudentEvaluation, error) {
+func (r *StudentEvaluationRepository) GetAllStudentEvaluations(ctx context.Context) ([]domain.StudentEvaluation, error) {
 	var evaluations []domain.StudentEvaluation
-	err := r.DB.Find(&evaluations).Error
+	err := r.DB.WithContext(ctx).Preload("Answers").Find(&evaluations).Error
 	if err != nil {
 		return nil, err
 	}
 	return evaluations, nil
 }
-func (r *StudentEvaluationRepository) GetStudentEvaluationCount() (int64, error) {
+func (r *StudentEvaluationRepository) GetStudentEvaluationCount(ctx context.Context) (int64, error) {
 	var count int64
-	err := r.DB.Model(&domain.StudentEvaluation{}).Count(&count).Error
+	err := r.DB.WithContext(ctx).Model(&domain.StudentEvaluation{}).Count(&count).Error
 	if err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
-func (r *StudentEvaluationRepository) GetStudentEvaluationById(id string) (*domain.StudentEvaluation, error) {
+func (r *StudentEvaluationRepository) GetStudentEvaluationById(ctx context.Context, id string) (*domain.StudentEvaluation, error) {
 	var evaluation domain.StudentEvaluation
-	err := r.DB.Where("id = ?", id).First(&evaluation).Error
+	err := r.DB.WithContext(ctx).Preload("Answers").Where("id = ?", id).First(&evaluation).Error
 	if err != nil {
 		return nil, err
 	}
 	return &evaluation, nil
 }
+
+// GetLegacyStudentEvaluations returns up to limit evaluations submitted before questionnaires existed
+func (r *StudentEvaluationRepository) GetLegacyStudentEvaluations(ctx context.Context, limit int) ([]domain.StudentEvaluation, error) {
+	var evaluations []domain.StudentEvaluation
+	err := r.DB.WithContext(ctx).Where("questionnaire_id IS NULL").Order("id ASC").Limit(limit).Find(&evaluations).Error
+	return evaluations, err
+}
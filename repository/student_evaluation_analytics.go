@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+)
+
+// evaluationGroupSource returns the group expression and the joins it needs
+func evaluationGroupSource(groupBy domain.EvaluationGroupBy) (string, string, error) {
+	switch groupBy {
+	case domain.GroupByNone:
+		return "''", "", nil
+	case domain.GroupByStatus:
+		return "COALESCE(u.status, 'unknown')", "JOIN users u ON u.id = e.student_id", nil
+	case domain.GroupByFaculty:
+		// A student scanned at the same faculty on several days is counted once
+		return "t.faculty", "JOIN (SELECT DISTINCT student_registration_id, faculty FROM student_transactions) t ON t.student_registration_id = e.student_id", nil
+	default:
+		return "", "", domain.ErrInvalidEvaluationGroupBy
+	}
+}
+
+// evaluationScope returns the condition keeping the evaluations of students scanned at the faculty,
+// or every evaluation without a scope
+func evaluationScope(scope *string) (string, []interface{}) {
+	if scope == nil {
+		return "TRUE", nil
+	}
+	return "EXISTS (SELECT 1 FROM student_transactions st WHERE st.student_registration_id = e.student_id AND st.faculty = ?)", []interface{}{*scope}
+}
+
+// GetEvaluationScoreCounts counts each score of each rating question per group
+func (r *StudentEvaluationRepository) GetEvaluationScoreCounts(ctx context.Context, groupBy domain.EvaluationGroupBy, scope *string) ([]domain.EvaluationScoreCount, error) {
+	group, join, err := evaluationGroupSource(groupBy)
+	if err != nil {
+		return nil, err
+	}
+	where, args := evaluationScope(scope)
+
+	query := fmt.Sprintf(`
+		SELECT %s AS "group", a.question_key AS question, a.score, COUNT(*) AS count
+		FROM student_evaluations e
+		JOIN evaluation_answers a ON a.evaluation_id = e.id
+		%s
+		WHERE a.score IS NOT NULL AND %s
+		GROUP BY 1, a.question_key, a.score
+		ORDER BY 1, a.question_key, a.score;`, group, join, where)
+
+	var results []domain.EvaluationScoreCount
+	err = r.DB.WithContext(ctx).Raw(query, args...).Scan(&results).Error
+	return results, err
+}
+
+// GetEvaluationGroupCounts counts the evaluations in each group
+func (r *StudentEvaluationRepository) GetEvaluationGroupCounts(ctx context.Context, groupBy domain.EvaluationGroupBy, scope *string) ([]domain.EvaluationGroupCount, error) {
+	group, join, err := evaluationGroupSource(groupBy)
+	if err != nil {
+		return nil, err
+	}
+	where, args := evaluationScope(scope)
+
+	query := fmt.Sprintf(`
+		SELECT %s AS "group", COUNT(DISTINCT e.id) AS count
+		FROM student_evaluations e
+		%s
+		WHERE %s
+		GROUP BY 1
+		ORDER BY count DESC;`, group, join, where)
+
+	var results []domain.EvaluationGroupCount
+	err = r.DB.WithContext(ctx).Raw(query, args...).Scan(&results).Error
+	return results, err
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"context"
+
 	"github.com/isd-sgcu/oph-67-backend/domain"
 	"gorm.io/gorm"
 )
@@ -14,45 +16,45 @@ func NewStudentTransactionRepository(db *gorm.DB) *StudentTransactionRepository
 }
 
 // Create a new student transaction
-func (r *StudentTransactionRepository) Create(transaction *domain.StudentTransaction) error {
-	return r.DB.Create(transaction).Error
+func (r *StudentTransactionRepository) Create(ctx context.Context, transaction *domain.StudentTransaction) error {
+	return r.DB.WithContext(ctx).Create(transaction).Error
 }
 
 // Get all student transactions
-func (r *StudentTransactionRepository) GetAll() ([]domain.StudentTransaction, error) {
+func (r *StudentTransactionRepository) GetAll(ctx context.Context) ([]domain.StudentTransaction, error) {
 	var transactions []domain.StudentTransaction
-	err := r.DB.Find(&transactions).Error
+	err := r.DB.WithContext(ctx).Find(&transactions).Error
 	return transactions, err
 }
 
 // Get a student transaction by ID
-func (r *StudentTransactionRepository) GetById(id string) (domain.StudentTransaction, error) {
+func (r *StudentTransactionRepository) GetById(ctx context.Context, id string) (domain.StudentTransaction, error) {
 	var transaction domain.StudentTransaction
-	err := r.DB.Where("id = ?", id).First(&transaction).Error
+	err := r.DB.WithContext(ctx).Where("id = ?", id).First(&transaction).Error
 	return transaction, err
 }
 
 // Get student transactions by student ID
-func (r *StudentTransactionRepository) GetByStudentId(studentId string) ([]domain.StudentTransaction, error) {
+func (r *StudentTransactionRepository) GetByStudentId(ctx context.Context, studentId string) ([]domain.StudentTransaction, error) {
 	var transactions []domain.StudentTransaction
-	err := r.DB.Where("student_registration_id = ?", studentId).Find(&transactions).Error
+	err := r.DB.WithContext(ctx).Where("student_registration_id = ?", studentId).Find(&transactions).Error
 	return transactions, err
 }
 
 // Update a student transaction
-func (r *StudentTransactionRepository) Update(id string, transaction *domain.StudentTransaction) error {
-	err := r.DB.Model(&domain.StudentTransaction{}).Where("id = ?", id).Updates(transaction).Error
+func (r *StudentTransactionRepository) Update(ctx context.Context, id string, transaction *domain.StudentTransaction) error {
+	err := r.DB.WithContext(ctx).Model(&domain.StudentTransaction{}).Where("id = ?", id).Updates(transaction).Error
 	return err
 }
 
 // Delete a student transaction by ID
-func (r *StudentTransactionRepository) Delete(id string) error {
-	err := r.DB.Where("id = ?", id).Delete(&domain.StudentTransaction{}).Error
+func (r *StudentTransactionRepository) Delete(ctx context.Context, id string) error {
+	err := r.DB.WithContext(ctx).Where("id = ?", id).Delete(&domain.StudentTransaction{}).Error
 	return err
 }
 
-func (r *StudentTransactionRepository) GetByStudentIdAndFaculty(studentId string, faculty string) ([]domain.StudentTransaction, error) {
+func (r *StudentTransactionRepository) GetByStudentIdAndFaculty(ctx context.Context, studentId string, faculty string) ([]domain.StudentTransaction, error) {
 	var transactions []domain.StudentTransaction
-	err := r.DB.Where("student_registration_id = ? AND faculty = ?", studentId, faculty).Find(&transactions).Error
+	err := r.DB.WithContext(ctx).Where("student_registration_id = ? AND faculty = ?", studentId, faculty).Find(&transactions).Error
 	return transactions, err
 }
@@ -1,6 +1,11 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
 	"github.com/isd-sgcu/oph-67-backend/domain"
 	"gorm.io/gorm"
 )
@@ -13,47 +18,119 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{DB: db}
 }
 
-func (r *UserRepository) Create(user *domain.User) error {
-	return r.DB.Create(user).Error
-}
-
-func (r *UserRepository) GetAll() ([]domain.User, error) {
-	var users []domain.User
-	err := r.DB.Find(&users).Error
-	return users, err
+func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
+	return r.DB.WithContext(ctx).Create(user).Error
 }
 
-func (r *UserRepository) GetById(id string) (domain.User, error) {
+func (r *UserRepository) GetById(ctx context.Context, id string) (domain.User, error) {
 	var user domain.User
-	err := r.DB.Where("id = ?", id).First(&user).Error
+	err := r.DB.WithContext(ctx).Where("id = ?", id).First(&user).Error
 	return user, err
 }
 
-func (r *UserRepository) GetByName(name string) ([]domain.User, error) {
+// userSortColumns maps the sort fields of a listing to their columns
+var userSortColumns = map[domain.UserSort]string{
+	domain.UserSortRegisteredAt: "registered_at",
+	domain.UserSortLastEntered:  "last_entered",
+	domain.UserSortName:         "name",
+	domain.UserSortID:           "id",
+}
+
+// List returns up to limit users matching filter in the given order, starting after the cursor if
+// there is one. Pages are cut by keyset rather than offset, so they stay stable while users register.
+func (r *UserRepository) List(ctx context.Context, filter domain.UserFilter, order domain.UserOrder, after *domain.UserCursor, limit int) ([]domain.User, error) {
+	column := userSortColumns[order.Sort]
+	direction, op := "ASC", ">"
+	if order.Desc {
+		direction, op = "DESC", "<"
+	}
+
+	query := r.filtered(ctx, filter)
+	if after != nil {
+		value, err := after.SortValue()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case order.Sort == domain.UserSortID:
+			query = query.Where("id "+op+" ?", after.ID)
+		case value == nil:
+			// Users without a value come last, ordered by ID alone
+			query = query.Where(column+" IS NULL AND id "+op+" ?", after.ID)
+		default:
+			query = query.Where(
+				fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?) OR %[1]s IS NULL)", column, op),
+				value, value, after.ID,
+			)
+		}
+	}
+
+	if order.Sort == domain.UserSortID {
+		query = query.Order("id " + direction)
+	} else {
+		query = query.Order(fmt.Sprintf("%s %s NULLS LAST, id %s", column, direction, direction))
+	}
+
 	var users []domain.User
-	err := r.DB.Where("name ILIKE ?", "%"+name+"%").Find(&users).Error
+	err := query.Limit(limit).Find(&users).Error
 	return users, err
 }
 
-func (r *UserRepository) GetByPhone(phone string) (domain.User, error) {
+// Count returns the number of users matching filter
+func (r *UserRepository) Count(ctx context.Context, filter domain.UserFilter) (int64, error) {
+	var total int64
+	err := r.filtered(ctx, filter).Count(&total).Error
+	return total, err
+}
+
+// filtered selects the users matching filter
+func (r *UserRepository) filtered(ctx context.Context, filter domain.UserFilter) *gorm.DB {
+	query := r.DB.WithContext(ctx).Model(&domain.User{})
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.Name != "" {
+		query = query.Where("name ILIKE ?", containsPattern(filter.Name))
+	}
+	switch {
+	case filter.Search != "" && filter.SearchContacts:
+		query = query.Where(
+			"(name ILIKE @pattern OR phone ILIKE @pattern OR email ILIKE @pattern OR uid ILIKE @pattern OR school ILIKE @pattern)",
+			sql.Named("pattern", containsPattern(filter.Search)),
+		)
+	case filter.Search != "":
+		query = query.Where("(name ILIKE @pattern OR school ILIKE @pattern)", sql.Named("pattern", containsPattern(filter.Search)))
+	}
+	return query
+}
+
+// containsPattern returns an ILIKE pattern matching values that contain s, with LIKE wildcards in s
+// matched literally
+func containsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (r *UserRepository) GetByPhone(ctx context.Context, phone string) (domain.User, error) {
 	var user domain.User
-	err := r.DB.Where("phone = ?", phone).First(&user).Error
+	err := r.DB.WithContext(ctx).Where("phone = ?", phone).First(&user).Error
 	return user, err
 }
 
-func (r *UserRepository) Update(id string, user *domain.User) error {
-	err := r.DB.Model(&domain.User{}).Where("id = ?", id).Updates(user).Error
+func (r *UserRepository) Update(ctx context.Context, id string, user *domain.User) error {
+	err := r.DB.WithContext(ctx).Model(&domain.User{}).Where("id = ?", id).Updates(user).Error
 	return err
 }
 
-func (r *UserRepository) Delete(id string) error {
-	err := r.DB.Where("id = ?", id).Delete(&domain.User{}).Error
+func (r *UserRepository) Delete(ctx context.Context, id string) error {
+	err := r.DB.WithContext(ctx).Where("id = ?", id).Delete(&domain.User{}).Error
 	return err
 }
 
-func (r *UserRepository) IsUIDExists(uid string) (bool, error) {
+func (r *UserRepository) IsUIDExists(ctx context.Context, uid string) (bool, error) {
 	var count int64
-	err := r.DB.Model(&domain.User{}).Where("uid = ?", uid).Count(&count).Error
+	err := r.DB.WithContext(ctx).Model(&domain.User{}).Where("uid = ?", uid).Count(&count).Error
 	if err != nil {
 		return false, err
 	}
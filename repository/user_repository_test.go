@@ -0,0 +1,297 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// statement is the SQL of a query built in dry run mode, with its parameters
+type statement struct {
+	sql  string
+	vars []interface{}
+}
+
+// dryRunUsers returns a repository that only builds its queries, and the statements it built
+func dryRunUsers(t *testing.T) (*UserRepository, *[]statement) {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements := &[]statement{}
+	err = db.Callback().Query().After("gorm:query").Register("test:capture", func(db *gorm.DB) {
+		*statements = append(*statements, statement{db.Statement.SQL.String(), db.Statement.Vars})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewUserRepository(db), statements
+}
+
+func TestUserListFilters(t *testing.T) {
+	for _, role := range []domain.Role{"", domain.Student} {
+		for _, name := range []string{"", "som_chai"} {
+			for _, search := range []string{"", "081"} {
+				for _, contacts := range []bool{false, true} {
+					filter := domain.UserFilter{Role: role, Name: name, Search: search, SearchContacts: contacts}
+					t.Run(fmt.Sprintf("%+v", filter), func(t *testing.T) {
+						repo, statements := dryRunUsers(t)
+						repo.List(context.Background(), filter, domain.UserOrder{Sort: domain.UserSortID}, nil, 10)
+						repo.Count(context.Background(), filter)
+						if len(*statements) != 2 {
+							t.Fatalf("built %d statements, want the list and the count", len(*statements))
+						}
+
+						for _, s := range *statements {
+							checkClause(t, s.sql, "role = $", role != "")
+							checkClause(t, s.sql, "school ILIKE $", search != "")
+							checkClause(t, s.sql, "phone ILIKE $", search != "" && contacts)
+							checkClause(t, s.sql, "email ILIKE $", search != "" && contacts)
+							checkClause(t, s.sql, "uid ILIKE $", search != "" && contacts)
+
+							// The search pattern is bound once per searched column
+							want := 0
+							switch {
+							case search != "" && contacts:
+								want = 5
+							case search != "":
+								want = 2
+							}
+							if got := countVar(s.vars, "%081%"); got != want {
+								t.Errorf("vars %v hold the search pattern %d times, want %d", s.vars, got, want)
+							}
+							// LIKE wildcards in the name are matched literally
+							if want := btoi(name != ""); countVar(s.vars, `%som\_chai%`) != want {
+								t.Errorf("vars %v hold the escaped name pattern %d times, want %d", s.vars, countVar(s.vars, `%som\_chai%`), want)
+							}
+						}
+					})
+				}
+			}
+		}
+	}
+}
+
+func checkClause(t *testing.T, sql string, clause string, want bool) {
+	t.Helper()
+	if got := strings.Contains(sql, clause); got != want {
+		t.Errorf("%s\ncontains %q: %v, want %v", sql, clause, got, want)
+	}
+}
+
+func countVar(vars []interface{}, want interface{}) int {
+	n := 0
+	for _, v := range vars {
+		if v == want {
+			n++
+		}
+	}
+	return n
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestUserListCursor(t *testing.T) {
+	entered := time.Date(2024, 11, 16, 9, 0, 0, 0, time.UTC)
+	value := entered.Format(time.RFC3339Nano)
+	tests := []struct {
+		name  string
+		order domain.UserOrder
+		after *domain.UserCursor
+		where string
+		vars  []interface{}
+		sort  string
+	}{
+		{
+			name:  "first page",
+			order: domain.UserOrder{Sort: domain.UserSortLastEntered},
+			where: "",
+			sort:  "ORDER BY last_entered ASC NULLS LAST, id ASC",
+		},
+		{
+			name:  "by ID",
+			order: domain.UserOrder{Sort: domain.UserSortID, Desc: true},
+			after: &domain.UserCursor{Sort: domain.UserSortID, Desc: true, Value: ptr("u5"), ID: "u5"},
+			where: "WHERE id < $1",
+			vars:  []interface{}{"u5"},
+			sort:  "ORDER BY id DESC",
+		},
+		{
+			name:  "after a value",
+			order: domain.UserOrder{Sort: domain.UserSortLastEntered},
+			after: &domain.UserCursor{Sort: domain.UserSortLastEntered, Value: &value, ID: "u5"},
+			where: "WHERE (last_entered > $1 OR (last_entered = $2 AND id > $3) OR last_entered IS NULL)",
+			vars:  []interface{}{entered, entered, "u5"},
+			sort:  "ORDER BY last_entered ASC NULLS LAST, id ASC",
+		},
+		{
+			name:  "after a value, descending",
+			order: domain.UserOrder{Sort: domain.UserSortLastEntered, Desc: true},
+			after: &domain.UserCursor{Sort: domain.UserSortLastEntered, Desc: true, Value: &value, ID: "u5"},
+			where: "WHERE (last_entered < $1 OR (last_entered = $2 AND id < $3) OR last_entered IS NULL)",
+			vars:  []interface{}{entered, entered, "u5"},
+			sort:  "ORDER BY last_entered DESC NULLS LAST, id DESC",
+		},
+		{
+			// Users without a value come last, so the next page only holds the rest of them
+			name:  "after a NULL",
+			order: domain.UserOrder{Sort: domain.UserSortLastEntered},
+			after: &domain.UserCursor{Sort: domain.UserSortLastEntered, ID: "u5"},
+			where: "WHERE last_entered IS NULL AND id > $1",
+			vars:  []interface{}{"u5"},
+			sort:  "ORDER BY last_entered ASC NULLS LAST, id ASC",
+		},
+		{
+			name:  "after a NULL, descending",
+			order: domain.UserOrder{Sort: domain.UserSortLastEntered, Desc: true},
+			after: &domain.UserCursor{Sort: domain.UserSortLastEntered, Desc: true, ID: "u5"},
+			where: "WHERE last_entered IS NULL AND id < $1",
+			vars:  []interface{}{"u5"},
+			sort:  "ORDER BY last_entered DESC NULLS LAST, id DESC",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, statements := dryRunUsers(t)
+			if _, err := repo.List(context.Background(), domain.UserFilter{}, tt.order, tt.after, 10); err != nil {
+				t.Fatal(err)
+			}
+			s := (*statements)[0]
+			want := `SELECT * FROM "users" ` + tt.where
+			if tt.where != "" {
+				want += " "
+			}
+			want += tt.sort + fmt.Sprintf(" LIMIT $%d", len(tt.vars)+1)
+			if s.sql != want {
+				t.Errorf("sql\n%s\nwant\n%s", s.sql, want)
+			}
+			if len(s.vars) != len(tt.vars)+1 {
+				t.Fatalf("vars %v, want %v and the limit", s.vars, tt.vars)
+			}
+			for i, v := range tt.vars {
+				if fmt.Sprint(s.vars[i]) != fmt.Sprint(v) {
+					t.Errorf("var %d = %v, want %v", i, s.vars[i], v)
+				}
+			}
+		})
+	}
+}
+
+// TestUserListPagesOnPostgres pages through users against a migrated database, set with
+// TEST_DATABASE_DSN. It runs in a transaction that is rolled back, so it leaves no users behind.
+func TestUserListPagesOnPostgres(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	// Every name holds the marker, so filtering on it leaves out the users already in the database
+	const marker = "listtest"
+	base := time.Date(2024, 11, 16, 9, 0, 0, 0, time.UTC)
+	at := func(minutes int) *time.Time {
+		t := base.Add(time.Duration(minutes) * time.Minute)
+		return &t
+	}
+	users := []domain.User{
+		{ID: marker + "-1", Name: marker + " a", Role: domain.Student, LastEntered: at(2)},
+		{ID: marker + "-2", Name: marker + " b", Role: domain.Student, LastEntered: at(1)},
+		{ID: marker + "-3", Name: marker + " c", Role: domain.Student, LastEntered: at(1)},
+		{ID: marker + "-4", Name: marker + " d", Role: domain.Student},
+		{ID: marker + "-5", Name: marker + " e", Role: domain.Staff},
+		{ID: marker + "-6", Name: marker + " f", Role: domain.Student},
+		{ID: marker + "-7", Name: marker + " g", Role: domain.Student},
+	}
+	for i := range users {
+		users[i].Phone = fmt.Sprintf("08%08d", 99990000+i)
+		users[i].UID = users[i].ID
+	}
+	if err := tx.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	repo := NewUserRepository(tx)
+
+	tests := []struct {
+		filter domain.UserFilter
+		order  domain.UserOrder
+		want   []string
+	}{
+		{
+			filter: domain.UserFilter{Name: marker},
+			order:  domain.UserOrder{Sort: domain.UserSortLastEntered},
+			want:   []string{"2", "3", "1", "4", "5", "6", "7"},
+		},
+		{
+			filter: domain.UserFilter{Name: marker},
+			order:  domain.UserOrder{Sort: domain.UserSortLastEntered, Desc: true},
+			want:   []string{"1", "3", "2", "7", "6", "5", "4"},
+		},
+		{
+			filter: domain.UserFilter{Name: marker, Role: domain.Student},
+			order:  domain.UserOrder{Sort: domain.UserSortLastEntered},
+			want:   []string{"2", "3", "1", "4", "6", "7"},
+		},
+		{
+			filter: domain.UserFilter{Search: "99990004", SearchContacts: true, Name: marker},
+			order:  domain.UserOrder{Sort: domain.UserSortID},
+			want:   []string{"5"},
+		},
+		{
+			filter: domain.UserFilter{Search: "99990004", Name: marker},
+			order:  domain.UserOrder{Sort: domain.UserSortID},
+			want:   []string{},
+		},
+	}
+	for _, tt := range tests {
+		// Pages of two make the cursor cross from users with a value to users without one
+		var got []string
+		var after *domain.UserCursor
+		for page := 0; page < len(users); page++ {
+			listed, err := repo.List(context.Background(), tt.filter, tt.order, after, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, user := range listed {
+				got = append(got, strings.TrimPrefix(user.ID, marker+"-"))
+			}
+			if len(listed) < 2 {
+				break
+			}
+			cursor := domain.NewUserCursor(tt.order, listed[len(listed)-1])
+			after = &cursor
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%+v %+v: listed %v, want %v", tt.filter, tt.order, got, tt.want)
+		}
+
+		total, err := repo.Count(context.Background(), tt.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != int64(len(tt.want)) {
+			t.Errorf("%+v: count %d, want %d", tt.filter, total, len(tt.want))
+		}
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/handler"
+	"github.com/isd-sgcu/oph-67-backend/middleware"
+	"github.com/isd-sgcu/oph-67-backend/usecase"
+)
+
+func RegisterCertificateRoutes(app *fiber.App, certificateUsecase *usecase.CertificateUsecase, userUsecase *usecase.UserUsecase) {
+	certificateHandler := handler.NewCertificateHandler(certificateUsecase)
+
+	certificates := app.Group("/api/certificates")
+
+	// Public route - linked from the QR code on every certificate, registered before /:event
+	certificates.Get("/verify", certificateHandler.VerifyCertificate)
+
+	// Admin-only routes - registered before /:event so "templates" and "issued" are not taken as events
+	admin := middleware.RoleMiddleware(userUsecase, domain.Admin)
+	certificates.Get("/templates", admin, certificateHandler.GetCertificateTemplates)        // List templates
+	certificates.Get("/templates/:event", admin, certificateHandler.GetCertificateTemplate)  // Get the template of an event
+	certificates.Put("/templates/:event", admin, certificateHandler.SaveCertificateTemplate) // Create or replace the template of an event
+	certificates.Get("/issued", admin, certificateHandler.GetIssuedCertificates)             // Issued certificates; ?revoked=true is the revocation list
+	certificates.Post("/issued/:serial/revoke", admin, certificateHandler.RevokeCertificate) // Revoke a certificate
+
+	// Authenticated user routes
+	certificates.Get("/:event", middleware.AuthMiddleware(userUsecase), certificateHandler.GetCertificate) // Download own certificate PDF
+}
@@ -1,22 +1,40 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/domain"
 	"github.com/isd-sgcu/oph-67-backend/handler"
+	"github.com/isd-sgcu/oph-67-backend/middleware"
 	"github.com/isd-sgcu/oph-67-backend/usecase"
 )
 
-func RegisterDashboardRoutes(app *fiber.App, dashboardUsecase *usecase.DashboardUseCase) {
-	dashboardHandler := handler.NewDashBoardUseCase(dashboardUsecase)
+func RegisterDashboardRoutes(app *fiber.App, dashboardUsecase *usecase.DashboardUseCase, userUsecase *usecase.UserUsecase, streamTimeout time.Duration) {
+	dashboardHandler := handler.NewDashBoardUseCase(dashboardUsecase, streamTimeout)
 
 	api := app.Group("/api")
 
-	dashboard := api.Group("/dashboard")
-	dashboard.Get("/faculties", dashboardHandler.GetFacultyCount)
-	dashboard.Get("/sources", dashboardHandler.GetSourceCount)
-	dashboard.Get("/ages", dashboardHandler.GetAgeGroupCount)
-	dashboard.Get("/faculties/today", dashboardHandler.GetFacultyTodayCount)
-	dashboard.Get("/status", dashboardHandler.GetStatusStudent)
-	dashboard.Get("/download", dashboardHandler.ExportAllStudents)
-	dashboard.Get("attended", dashboardHandler.GetAttendedCount)
+	// Every dashboard route requires a valid JWT
+	dashboard := api.Group("/dashboard", middleware.AuthMiddleware(userUsecase))
+
+	// Aggregate statistics - faculty staff only see their own faculty
+	view := middleware.PermissionMiddleware(domain.PermissionViewDashboard)
+	dashboard.Get("/faculties", view, dashboardHandler.GetFacultyCount)
+	dashboard.Get("/sources", view, dashboardHandler.GetSourceCount)
+	dashboard.Get("/ages", view, dashboardHandler.GetAgeGroupCount)
+	dashboard.Get("/faculties/today", view, dashboardHandler.GetFacultyTodayCount)
+	dashboard.Get("/status", view, dashboardHandler.GetStatusStudent)
+	dashboard.Get("/attended", view, dashboardHandler.GetAttendedCount)
+	dashboard.Get("/provinces", view, dashboardHandler.GetProvinceCount)
+	dashboard.Get("/provinces/geo", view, dashboardHandler.GetProvinceGeo)
+	dashboard.Get("/schools", view, dashboardHandler.GetSchoolCount)
+
+	// Student PII export
+	export := middleware.PermissionMiddleware(domain.PermissionExportStudents)
+	dashboard.Get("/download", export, dashboardHandler.ExportAllStudents)
+
+	// Faculty-scoped student export - faculty staff are pinned to their own faculty
+	facultyExport := middleware.PermissionMiddleware(domain.PermissionExportFacultyStudents)
+	dashboard.Get("/download/faculty", facultyExport, dashboardHandler.ExportFacultyStudents)
 }
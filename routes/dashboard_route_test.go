@@ -0,0 +1,202 @@
+package routes
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/usecase"
+)
+
+// fakeDashboardRepository returns canned statistics for two faculties and records the scope it was asked for
+type fakeDashboardRepository struct {
+	scope  *string
+	called bool
+
+	// streamDeadline and streamErr describe the context StreamStudents ran with
+	streamDeadline time.Duration
+	streamErr      error
+}
+
+func (r *fakeDashboardRepository) record(scope *string) {
+	r.scope, r.called = scope, true
+}
+
+func (r *fakeDashboardRepository) GetFacultyCount(ctx context.Context) ([]domain.FacultyPercent, error) {
+	return []domain.FacultyPercent{{Faculty: "engineering"}, {Faculty: "arts"}}, nil
+}
+
+func (r *fakeDashboardRepository) GetSourceCount(ctx context.Context, scope *string) ([]domain.SourceCount, error) {
+	r.record(scope)
+	return nil, nil
+}
+
+func (r *fakeDashboardRepository) GetAgeGroupCount(ctx context.Context, scope *string) ([]domain.AgeCount, error) {
+	r.record(scope)
+	return nil, nil
+}
+
+func (r *fakeDashboardRepository) GetFacultyToday(ctx context.Context) ([]domain.FacultyRegisterCount, error) {
+	return []domain.FacultyRegisterCount{{Faculty: "engineering"}, {Faculty: "arts"}}, nil
+}
+
+func (r *fakeDashboardRepository) GetStatusStudent(ctx context.Context, scope *string) ([]domain.StatusCount, error) {
+	r.record(scope)
+	return nil, nil
+}
+
+func (r *fakeDashboardRepository) GetAttendedCount(ctx context.Context, scope *string) ([]domain.AttendedCount, error) {
+	r.record(scope)
+	return nil, nil
+}
+
+func (r *fakeDashboardRepository) GetProvinceCount(ctx context.Context, scope *string) ([]domain.ProvinceCount, error) {
+	r.record(scope)
+	return nil, nil
+}
+
+func (r *fakeDashboardRepository) GetSchoolCount(ctx context.Context, scope *string) ([]domain.SchoolCount, error) {
+	r.record(scope)
+	return nil, nil
+}
+
+func (r *fakeDashboardRepository) StreamStudents(ctx context.Context, filter domain.StudentExportFilter, fn func(row *domain.StudentExportRow) error) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		r.streamDeadline = time.Until(deadline)
+	}
+	r.streamErr = ctx.Err()
+	return nil
+}
+
+var dashboardRoutes = []string{
+	"/api/dashboard/faculties",
+	"/api/dashboard/sources",
+	"/api/dashboard/ages",
+	"/api/dashboard/faculties/today",
+	"/api/dashboard/status",
+	"/api/dashboard/attended",
+	"/api/dashboard/provinces",
+	"/api/dashboard/provinces/geo",
+	"/api/dashboard/schools",
+	"/api/dashboard/download",
+	"/api/dashboard/download/faculty",
+}
+
+var (
+	testAdmin        = domain.User{ID: "admin", Role: domain.Admin}
+	testCentralStaff = domain.User{ID: "central", Role: domain.Staff, IsCentralStaff: ptr(true)}
+	testFacultyStaff = domain.User{ID: "faculty", Role: domain.Staff, Faculty: ptr("engineering")}
+	testMember       = domain.User{ID: "member", Role: domain.Member}
+	testStudent      = domain.User{ID: "student", Role: domain.Student}
+)
+
+func newDashboardApp(t *testing.T) (*fakeDashboardRepository, func(path string, userID string) *http.Response) {
+	t.Helper()
+	users := newFakeUserRepository(testAdmin, testCentralStaff, testFacultyStaff, testMember, testStudent)
+	app, userUsecase := newTestApp(t, users)
+	repo := &fakeDashboardRepository{}
+	RegisterDashboardRoutes(app, usecase.NewDashBoardUseCase(repo), userUsecase, time.Minute)
+
+	return repo, func(path string, userID string) *http.Response {
+		return send(t, app, httptest.NewRequest(http.MethodGet, path, nil), userID)
+	}
+}
+
+func TestDashboardRoutesRequireToken(t *testing.T) {
+	_, get := newDashboardApp(t)
+	for _, path := range dashboardRoutes {
+		if resp := get(path, ""); resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("GET %s without a token: status %d, want 401", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestDashboardRoutesDenyMembersAndStudents(t *testing.T) {
+	_, get := newDashboardApp(t)
+	for _, user := range []domain.User{testMember, testStudent} {
+		for _, path := range dashboardRoutes {
+			if resp := get(path, user.ID); resp.StatusCode != http.StatusForbidden {
+				t.Errorf("GET %s as %s: status %d, want 403", path, user.Role, resp.StatusCode)
+			}
+		}
+	}
+}
+
+func TestDashboardRoutesAllowCentralStaffAndAdmins(t *testing.T) {
+	_, get := newDashboardApp(t)
+	for _, user := range []domain.User{testAdmin, testCentralStaff} {
+		for _, path := range dashboardRoutes {
+			if path == "/api/dashboard/download/faculty" {
+				path += "?faculty=arts"
+			}
+			if resp := get(path, user.ID); resp.StatusCode != http.StatusOK {
+				t.Errorf("GET %s as %s: status %d, want 200", path, user.ID, resp.StatusCode)
+			}
+		}
+	}
+}
+
+func TestDashboardExportDeniesFacultyStaffOtherFaculties(t *testing.T) {
+	_, get := newDashboardApp(t)
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/api/dashboard/download", http.StatusForbidden},
+		{"/api/dashboard/download/faculty?faculty=arts", http.StatusForbidden},
+		{"/api/dashboard/download/faculty", http.StatusOK},
+		{"/api/dashboard/download/faculty?faculty=engineering", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if resp := get(tt.path, testFacultyStaff.ID); resp.StatusCode != tt.status {
+			t.Errorf("GET %s as faculty staff: status %d, want %d", tt.path, resp.StatusCode, tt.status)
+		}
+	}
+}
+
+func TestDashboardStatisticsAreScopedToFaculty(t *testing.T) {
+	repo, get := newDashboardApp(t)
+	for _, path := range dashboardRoutes[:9] {
+		*repo = fakeDashboardRepository{}
+		resp := get(path, testFacultyStaff.ID)
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s as faculty staff: status %d, want 200", path, resp.StatusCode)
+			continue
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if strings.Contains(string(body), "arts") {
+			t.Errorf("GET %s as faculty staff returned another faculty: %s", path, body)
+		}
+		if repo.called && (repo.scope == nil || *repo.scope != "engineering") {
+			t.Errorf("GET %s as faculty staff: queried scope %v, want engineering", path, repo.scope)
+		}
+	}
+
+	*repo = fakeDashboardRepository{}
+	get("/api/dashboard/sources", testCentralStaff.ID)
+	if !repo.called || repo.scope != nil {
+		t.Errorf("GET /api/dashboard/sources as central staff: queried scope %v, want none", repo.scope)
+	}
+}
+
+func TestDashboardExportStreamsWithItsOwnDeadline(t *testing.T) {
+	repo, get := newDashboardApp(t)
+	resp := get("/api/dashboard/download", testAdmin.ID)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /api/dashboard/download: status %d, want 200", resp.StatusCode)
+	}
+	io.ReadAll(resp.Body)
+
+	// The body is written after the handler returned, so the request context is already cancelled by then
+	if repo.streamErr != nil {
+		t.Errorf("export ran with a done context: %v", repo.streamErr)
+	}
+	if repo.streamDeadline <= 0 || repo.streamDeadline > time.Minute {
+		t.Errorf("export deadline in %v, want within the configured minute", repo.streamDeadline)
+	}
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/handler"
+	"github.com/isd-sgcu/oph-67-backend/middleware"
+	"github.com/isd-sgcu/oph-67-backend/usecase"
+)
+
+func RegisterExportJobRoutes(app *fiber.App, exportJobUsecase *usecase.ExportJobUsecase, userUsecase *usecase.UserUsecase) {
+	exportJobHandler := handler.NewExportJobHandler(exportJobUsecase)
+
+	api := app.Group("/api")
+
+	// Middleware is attached per route so the signed download link stays public
+	auth := middleware.AuthMiddleware(userUsecase)
+	api.Post("/exports", auth, exportJobHandler.SubmitExportJob) // Submit an export job
+	api.Get("/exports/:id", auth, exportJobHandler.GetExportJob) // Poll job status
+
+	// Public route - authorised by the signature in the link
+	api.Get("/exports/:id/download", exportJobHandler.DownloadExport)
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/handler"
+	"github.com/isd-sgcu/oph-67-backend/middleware"
+	"github.com/isd-sgcu/oph-67-backend/usecase"
+)
+
+func RegisterFacultySurveyRoutes(app *fiber.App, facultySurveyUsecase *usecase.FacultySurveyUsecase, userUsecase *usecase.UserUsecase) {
+	facultySurveyHandler := handler.NewFacultySurveyHandler(facultySurveyUsecase)
+
+	surveys := app.Group("/api/surveys", middleware.AuthMiddleware(userUsecase))
+
+	// Student routes
+	surveys.Get("/pending", facultySurveyHandler.GetPendingSurveys)   // Surveys of faculties the student was scanned at
+	surveys.Post("/:id/responses", facultySurveyHandler.SubmitSurvey) // Answer a faculty survey
+
+	// Faculty staff routes - faculty staff only see their own faculty
+	manage := middleware.PermissionMiddleware(domain.PermissionManageFacultySurveys)
+	surveys.Get("/questionnaires", manage, facultySurveyHandler.GetFacultyQuestionnaires)                    // List the faculty's survey versions
+	surveys.Post("/questionnaires", manage, facultySurveyHandler.CreateFacultyQuestionnaire)                 // Create a new inactive version
+	surveys.Patch("/questionnaires/:id/activate", manage, facultySurveyHandler.ActivateFacultyQuestionnaire) // Switch the faculty's active version
+	surveys.Get("/questionnaires/:id/results", manage, facultySurveyHandler.GetFacultySurveyResults)         // Responses and rating statistics
+}
@@ -0,0 +1,157 @@
+package routes
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/config"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/keyring"
+	"github.com/isd-sgcu/oph-67-backend/middleware"
+	"github.com/isd-sgcu/oph-67-backend/usecase"
+	"github.com/isd-sgcu/oph-67-backend/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	testKeyID      = "test"
+	testSecret     = "0123456789abcdef0123456789abcdef"
+	testLinkSecret = "fedcba9876543210fedcba9876543210"
+)
+
+// fakeUserRepository keeps users in memory by ID and records the filter of the last listing
+type fakeUserRepository struct {
+	users      map[string]domain.User
+	lastFilter domain.UserFilter
+}
+
+func newFakeUserRepository(users ...domain.User) *fakeUserRepository {
+	r := &fakeUserRepository{users: map[string]domain.User{}}
+	for _, user := range users {
+		r.users[user.ID] = user
+	}
+	return r
+}
+
+func (r *fakeUserRepository) Create(ctx context.Context, user *domain.User) error {
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *fakeUserRepository) List(ctx context.Context, filter domain.UserFilter, order domain.UserOrder, after *domain.UserCursor, limit int) ([]domain.User, error) {
+	r.lastFilter = filter
+	users := []domain.User{}
+	for _, user := range r.users {
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (r *fakeUserRepository) Count(ctx context.Context, filter domain.UserFilter) (int64, error) {
+	return int64(len(r.users)), nil
+}
+
+func (r *fakeUserRepository) GetById(ctx context.Context, id string) (domain.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return domain.User{}, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+func (r *fakeUserRepository) GetByPhone(ctx context.Context, phone string) (domain.User, error) {
+	for _, user := range r.users {
+		if user.Phone == phone {
+			return user, nil
+		}
+	}
+	return domain.User{}, gorm.ErrRecordNotFound
+}
+
+func (r *fakeUserRepository) IsUIDExists(ctx context.Context, uid string) (bool, error) {
+	return false, nil
+}
+
+// Update writes the non-zero fields of user, like gorm's Updates
+func (r *fakeUserRepository) Update(ctx context.Context, id string, user *domain.User) error {
+	current := r.users[id]
+	dst, src := reflect.ValueOf(&current).Elem(), reflect.ValueOf(user).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		if !src.Field(i).IsZero() && dst.Field(i).CanSet() {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+	// id may be a Fiber path parameter, whose bytes are reused by the next request
+	r.users[strings.Clone(id)] = current
+	return nil
+}
+
+func (r *fakeUserRepository) Delete(ctx context.Context, id string) error {
+	delete(r.users, id)
+	return nil
+}
+
+// testKeys returns a keyring with a known JWT secret, so tests can sign their own access tokens
+func testKeys(t *testing.T) *keyring.Keyring {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := keyring.Load(config.KeysConfig{
+		JWTSecret:      testSecret,
+		JWTKeyID:       testKeyID,
+		CertPrivateKey: base64.RawURLEncoding.EncodeToString(private),
+		CertKeyID:      testKeyID,
+		LinkSecret:     testLinkSecret,
+		LinkKeyID:      testKeyID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+// newTestApp returns an app with the same error handler as the server and a user usecase over users
+func newTestApp(t *testing.T, users *fakeUserRepository) (*fiber.App, *usecase.UserUsecase) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler(logger)})
+	return app, usecase.NewUserUsecase(users, nil, testKeys(t), "http://localhost", logger)
+}
+
+// bearer returns the Authorization header of an access token for the user
+func bearer(t *testing.T, userID string) string {
+	t.Helper()
+	token, err := utils.GenerateTokens(userID, testKeyID, testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + token
+}
+
+// send makes a request through the app, authenticated as userID unless it is empty
+func send(t *testing.T, app *fiber.App, req *http.Request, userID string) *http.Response {
+	t.Helper()
+	if userID != "" {
+		req.Header.Set("Authorization", bearer(t, userID))
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
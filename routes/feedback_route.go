@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/handler"
+	"github.com/isd-sgcu/oph-67-backend/middleware"
+	"github.com/isd-sgcu/oph-67-backend/usecase"
+)
+
+func RegisterFeedbackRoutes(app *fiber.App, feedbackUsecase *usecase.FeedbackUsecase, userUsecase *usecase.UserUsecase) {
+	feedbackHandler := handler.NewFeedbackHandler(feedbackUsecase)
+
+	// Admin-only routes
+	feedback := app.Group("/api/feedback", middleware.RoleMiddleware(userUsecase, domain.Admin))
+	feedback.Get("/suggestions", feedbackHandler.GetSuggestions)         // Browse free-text answers
+	feedback.Put("/suggestions/:id/tags", feedbackHandler.TagSuggestion) // Replace the tags of an answer
+	feedback.Get("/keywords", feedbackHandler.GetKeywords)               // Keyword and phrase frequency
+	feedback.Get("/booths", feedbackHandler.GetBooths)                   // Booth catalog
+	feedback.Post("/booths", feedbackHandler.CreateBooth)                // Add a booth to the catalog
+	feedback.Get("/booths/groups", feedbackHandler.GetBoothGroups)       // Booth answers grouped by catalog entry
+}
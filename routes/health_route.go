@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/handler"
+	"github.com/isd-sgcu/oph-67-backend/usecase"
+)
+
+// RegisterHealthRoutes adds the public probes used by the load balancer
+func RegisterHealthRoutes(app *fiber.App, healthUsecase *usecase.HealthUsecase) {
+	healthHandler := handler.NewHealthHandler(healthUsecase)
+
+	app.Get("/healthz", healthHandler.Liveness) // Liveness: the process is running
+	app.Get("/readyz", healthHandler.Readiness) // Readiness: database reachable and migrations applied
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"crypto/subtle"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/metrics"
+)
+
+// RegisterMetricsRoutes exposes the Prometheus metrics. When token is set, scrapers must send it as a bearer token.
+func RegisterMetricsRoutes(app *fiber.App, token string) {
+	handler := adaptor.HTTPHandler(metrics.Handler())
+
+	app.Get("/metrics", func(c *fiber.Ctx) error {
+		if token != "" && subtle.ConstantTimeCompare([]byte(c.Get(fiber.HeaderAuthorization)), []byte("Bearer "+token)) != 1 {
+			return domain.ErrUnauthorized.WithMessage("Invalid metrics token")
+		}
+		return handler(c)
+	})
+}
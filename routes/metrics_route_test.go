@@ -0,0 +1,161 @@
+package routes
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/middleware"
+	"github.com/isd-sgcu/oph-67-backend/usecase"
+)
+
+const testMetricsToken = "metrics-token"
+
+// fakeTransactionRepository keeps the faculty entries made by scans in memory
+type fakeTransactionRepository struct {
+	usecase.StudentTransactionRepositoryInterface
+	transactions []domain.StudentTransaction
+}
+
+func (r *fakeTransactionRepository) GetByStudentIdAndFaculty(ctx context.Context, studentId string, faculty string) ([]domain.StudentTransaction, error) {
+	var found []domain.StudentTransaction
+	for _, transaction := range r.transactions {
+		if transaction.StudentRegistrationID == studentId && transaction.Faculty == faculty {
+			found = append(found, transaction)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeTransactionRepository) Create(ctx context.Context, transaction *domain.StudentTransaction) error {
+	r.transactions = append(r.transactions, *transaction)
+	return nil
+}
+
+// scrape returns the samples of /metrics by series, e.g. oph_scans_total{faculty="central",outcome="accepted"}
+func scrape(t *testing.T, app *fiber.App) map[string]float64 {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer "+testMetricsToken)
+	resp := send(t, app, req, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /metrics: status %d, want 200", resp.StatusCode)
+	}
+	defer resp.Body.Close()
+
+	samples := map[string]float64{}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			t.Fatalf("sample %q: %v", line, err)
+		}
+		samples[line[:i]] = value
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return samples
+}
+
+func TestMetricsCountRequestsByRouteAndScansByOutcome(t *testing.T) {
+	users := newFakeUserRepository(testCentralStaff, testFacultyStaff, testStudent)
+	app, userUsecase := newTestApp(t, users)
+	userUsecase.StudentTransactionRepo = &fakeTransactionRepository{}
+	app.Use(middleware.MetricsMiddleware())
+	RegisterMetricsRoutes(app, testMetricsToken)
+	RegisterUserRoutes(app, userUsecase, nil)
+
+	before := scrape(t, app)
+
+	get := func(userID string) {
+		if resp := send(t, app, httptest.NewRequest(http.MethodGet, "/api/users/"+userID, nil), userID); resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET /api/users/%s: status %d, want 200", userID, resp.StatusCode)
+		}
+	}
+	get(testStudent.ID)
+	get(testFacultyStaff.ID)
+
+	scan := func(staffID, studentID string, status int) {
+		t.Helper()
+		if resp := send(t, app, httptest.NewRequest(http.MethodPost, "/api/users/qr/"+studentID, nil), staffID); resp.StatusCode != status {
+			t.Fatalf("scan %s by %s: status %d, want %d", studentID, staffID, resp.StatusCode, status)
+		}
+	}
+	scan(testFacultyStaff.ID, testStudent.ID, http.StatusOK)
+	scan(testFacultyStaff.ID, testStudent.ID, http.StatusConflict)
+	scan(testCentralStaff.ID, testStudent.ID, http.StatusOK)
+	scan(testCentralStaff.ID, "nobody", http.StatusNotFound)
+
+	after := scrape(t, app)
+	tests := []struct {
+		series string
+		want   float64
+	}{
+		// Both users share the route template, so IDs do not create a series each
+		{`oph_http_requests_total{method="GET",route="/api/users/:id",status="200"}`, 2},
+		{`oph_http_request_duration_seconds_count{method="GET",route="/api/users/:id",status="200"}`, 2},
+		{`oph_http_requests_total{method="POST",route="/api/users/qr/:id",status="409"}`, 1},
+		{`oph_http_requests_total{method="POST",route="/api/users/qr/:id",status="404"}`, 1},
+		{`oph_http_requests_total{method="GET",route="/api/users/student",status="200"}`, 0},
+		{`oph_scans_total{faculty="engineering",outcome="accepted"}`, 1},
+		{`oph_scans_total{faculty="engineering",outcome="duplicate"}`, 1},
+		{`oph_scans_total{faculty="central",outcome="accepted"}`, 1},
+		{`oph_scans_total{faculty="central",outcome="unknown_user"}`, 1},
+	}
+	for _, tt := range tests {
+		if got := after[tt.series] - before[tt.series]; got != tt.want {
+			t.Errorf("%s increased by %v, want %v", tt.series, got, tt.want)
+		}
+	}
+}
+
+func TestMetricsRequireTokenWhenSet(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		status        int
+	}{
+		{"no token configured", "", "", http.StatusOK},
+		{"token sent", testMetricsToken, "Bearer " + testMetricsToken, http.StatusOK},
+		{"token missing", testMetricsToken, "", http.StatusUnauthorized},
+		{"wrong token", testMetricsToken, "Bearer wrong", http.StatusUnauthorized},
+		{"token without scheme", testMetricsToken, testMetricsToken, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, _ := newTestApp(t, newFakeUserRepository())
+			RegisterMetricsRoutes(app, tt.token)
+
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			resp := send(t, app, req, "")
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status %d, want %d", resp.StatusCode, tt.status)
+			}
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exposed := strings.Contains(string(body), "go_goroutines"); exposed != (tt.status == http.StatusOK) {
+				t.Errorf("metrics exposed = %v with status %d", exposed, tt.status)
+			}
+		})
+	}
+}
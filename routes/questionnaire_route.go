@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/handler"
+	"github.com/isd-sgcu/oph-67-backend/middleware"
+	"github.com/isd-sgcu/oph-67-backend/usecase"
+)
+
+func RegisterQuestionnaireRoutes(app *fiber.App, questionnaireUsecase *usecase.QuestionnaireUsecase, userUsecase *usecase.UserUsecase) {
+	questionnaireHandler := handler.NewQuestionnaireHandler(questionnaireUsecase)
+
+	questionnaires := app.Group("/api/questionnaires")
+
+	// Public route - the form is needed before a student can answer it
+	questionnaires.Get("/active", questionnaireHandler.GetActiveQuestionnaire)
+
+	// Admin-only routes - middleware is attached per route so /active stays public
+	admin := middleware.RoleMiddleware(userUsecase, domain.Admin)
+	questionnaires.Get("/", admin, questionnaireHandler.GetAllQuestionnaires)                // List every version
+	questionnaires.Post("/", admin, questionnaireHandler.CreateQuestionnaire)                // Create a new inactive version
+	questionnaires.Get("/:id", admin, questionnaireHandler.GetQuestionnaireById)             // Get one version
+	questionnaires.Patch("/:id/activate", admin, questionnaireHandler.ActivateQuestionnaire) // Switch the active version
+	questionnaires.Patch("/:id", admin, questionnaireHandler.UpdateQuestionnaireSettings)    // Toggle attendance gating
+}
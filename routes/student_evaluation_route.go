@@ -13,12 +13,15 @@ func RegisterStudentEvaluationRoutes(app *fiber.App, studentEvaluationUsecase *u
 
 	api := app.Group("/api")
 
+	viewDashboard := middleware.PermissionMiddleware(domain.PermissionViewDashboard)
+
 	// Authenticated user routes - Requires valid JWT
 	authenticated := api.Group("/student-evaluation", middleware.AuthMiddleware(userUsecases))
-	authenticated.Post("/", studentEvaluationHandler.CreateStudentEvaluation)           // Create a new student evaluation
-	authenticated.Get("/:id", studentEvaluationHandler.GetStudentEvaluationByStudentId) // Get student evaluation by ID
-	authenticated.Patch("/:id", studentEvaluationHandler.UpdateStudentEvaluation)       // Update student evaluation
-	authenticated.Delete("/:id", studentEvaluationHandler.DeleteStudentEvaluation)      // Delete student evaluation
+	authenticated.Post("/", studentEvaluationHandler.CreateStudentEvaluation)                       // Create a new student evaluation
+	authenticated.Get("/analytics", viewDashboard, studentEvaluationHandler.GetEvaluationAnalytics) // Rating analytics, registered before /:id
+	authenticated.Get("/:id", studentEvaluationHandler.GetStudentEvaluationByStudentId)             // Get student evaluation by ID
+	authenticated.Patch("/:id", studentEvaluationHandler.UpdateStudentEvaluation)                   // Update student evaluation
+	authenticated.Delete("/:id", studentEvaluationHandler.DeleteStudentEvaluation)                  // Delete student evaluation
 
 	// Staff/Admin routes - Requires Staff or Admin role
 	staffAdmin := api.Group("/student-evaluation", middleware.RoleMiddleware(userUsecases, domain.Staff, domain.Admin))
@@ -0,0 +1,82 @@
+package routes
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/usecase"
+	"gorm.io/gorm"
+)
+
+// fakeAnalyticsRepository returns ratings for two faculties and records the scope the analytics were asked for
+type fakeAnalyticsRepository struct {
+	usecase.StudentEvaluationRepositoryInterface
+	scopes []*string
+}
+
+func (r *fakeAnalyticsRepository) GetEvaluationGroupCounts(ctx context.Context, groupBy domain.EvaluationGroupBy, scope *string) ([]domain.EvaluationGroupCount, error) {
+	r.scopes = append(r.scopes, scope)
+	if groupBy != domain.GroupByFaculty {
+		return []domain.EvaluationGroupCount{{Count: 2}}, nil
+	}
+	return []domain.EvaluationGroupCount{{Group: "engineering", Count: 1}, {Group: "arts", Count: 1}}, nil
+}
+
+func (r *fakeAnalyticsRepository) GetEvaluationScoreCounts(ctx context.Context, groupBy domain.EvaluationGroupBy, scope *string) ([]domain.EvaluationScoreCount, error) {
+	r.scopes = append(r.scopes, scope)
+	return nil, nil
+}
+
+// noActiveQuestionnaire has no active questionnaire, so analytics fall back to the default scale
+type noActiveQuestionnaire struct {
+	usecase.QuestionnaireRepositoryInterface
+}
+
+func (noActiveQuestionnaire) GetActive(ctx context.Context) (domain.Questionnaire, error) {
+	return domain.Questionnaire{}, gorm.ErrRecordNotFound
+}
+
+func TestEvaluationAnalyticsAreScopedToFaculty(t *testing.T) {
+	users := newFakeUserRepository(testAdmin, testCentralStaff, testFacultyStaff)
+	app, userUsecase := newTestApp(t, users)
+	repo := &fakeAnalyticsRepository{}
+	RegisterStudentEvaluationRoutes(app, usecase.NewStudentEvaluationUsecase(repo, noActiveQuestionnaire{}, nil, nil), userUsecase)
+
+	for _, path := range []string{
+		"/api/student-evaluation/analytics",
+		"/api/student-evaluation/analytics?by=status",
+		"/api/student-evaluation/analytics?by=faculty",
+	} {
+		repo.scopes = nil
+		resp := send(t, app, httptest.NewRequest(http.MethodGet, path, nil), testFacultyStaff.ID)
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s as faculty staff: status %d, want 200", path, resp.StatusCode)
+			continue
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if strings.Contains(string(body), "arts") {
+			t.Errorf("GET %s as faculty staff returned another faculty: %s", path, body)
+		}
+		if len(repo.scopes) == 0 {
+			t.Errorf("GET %s as faculty staff: no query was made", path)
+		}
+		for _, scope := range repo.scopes {
+			if scope == nil || *scope != "engineering" {
+				t.Errorf("GET %s as faculty staff: queried scope %v, want engineering", path, scope)
+			}
+		}
+	}
+
+	repo.scopes = nil
+	send(t, app, httptest.NewRequest(http.MethodGet, "/api/student-evaluation/analytics", nil), testCentralStaff.ID)
+	for _, scope := range repo.scopes {
+		if scope != nil {
+			t.Errorf("GET /api/student-evaluation/analytics as central staff: queried scope %q, want none", *scope)
+		}
+	}
+}
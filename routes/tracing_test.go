@@ -0,0 +1,127 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/isd-sgcu/oph-67-backend/config"
+	"github.com/isd-sgcu/oph-67-backend/middleware"
+	"github.com/isd-sgcu/oph-67-backend/repository"
+	"github.com/isd-sgcu/oph-67-backend/tracing"
+	"github.com/isd-sgcu/oph-67-backend/usecase"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// recordSpans sends every span of the test to an in-memory exporter, restoring the global provider afterwards
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(config.TracingConfig{ServiceName: "test", SampleRatio: 1}, sdktrace.NewSimpleSpanProcessor(exporter))
+
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return exporter
+}
+
+// dryRunDB builds the SQL of every query with the tracing plugin installed, without a database
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(tracing.NewGormPlugin()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func spanNamed(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	names := []string{}
+	for _, span := range spans {
+		names = append(names, span.Name)
+	}
+	t.Fatalf("no span %q, got %v", name, names)
+	return tracetest.SpanStub{}
+}
+
+func TestRequestSpansNestAndContinueTraceparent(t *testing.T) {
+	exporter := recordSpans(t)
+	app, userUsecase := newTestApp(t, newFakeUserRepository())
+	app.Use(middleware.TracingMiddleware())
+	questionnaireUsecase := usecase.NewQuestionnaireUsecase(repository.NewQuestionnaireRepository(dryRunDB(t)))
+	RegisterQuestionnaireRoutes(app, questionnaireUsecase, userUsecase)
+
+	const traceID, parentID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	req := httptest.NewRequest(http.MethodGet, "/api/questionnaires/active", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentID+"-01")
+	send(t, app, req, "")
+	// Fiber reuses the request buffers, so the next request must not change what the first span recorded
+	send(t, app, httptest.NewRequest(http.MethodPost, "/api/questionnaires/active", nil), "")
+
+	spans := exporter.GetSpans()
+	server := spanNamed(t, spans, "GET /api/questionnaires/active")
+	usecaseSpan := spanNamed(t, spans, "QuestionnaireUsecase.GetActive")
+	query := spanNamed(t, spans, "gorm.query questionnaires")
+
+	for _, attr := range server.Attributes {
+		if attr.Key == semconv.HTTPRequestMethodKey && attr.Value.AsString() != http.MethodGet {
+			t.Errorf("server span %s = %q, want GET", attr.Key, attr.Value.AsString())
+		}
+	}
+
+	// The incoming traceparent is the remote parent of the server span
+	if got := server.SpanContext.TraceID().String(); got != traceID {
+		t.Errorf("server span trace ID %s, want %s", got, traceID)
+	}
+	if got := server.Parent.SpanID().String(); got != parentID || !server.Parent.IsRemote() {
+		t.Errorf("server span parent %s (remote %v), want remote %s", got, server.Parent.IsRemote(), parentID)
+	}
+
+	if usecaseSpan.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Errorf("usecase span is not a child of the server span")
+	}
+	if query.Parent.SpanID() != usecaseSpan.SpanContext.SpanID() {
+		t.Errorf("query span is not a child of the usecase span")
+	}
+	for _, span := range []tracetest.SpanStub{usecaseSpan, query} {
+		if span.SpanContext.TraceID() != server.SpanContext.TraceID() {
+			t.Errorf("span %q is in trace %s, want %s", span.Name, span.SpanContext.TraceID(), traceID)
+		}
+	}
+
+	// The query text keeps its placeholders; parameter values are not recorded
+	var text string
+	for _, attr := range query.Attributes {
+		if attr.Key == semconv.DBQueryTextKey {
+			text = attr.Value.AsString()
+		}
+	}
+	if !strings.Contains(text, `FROM "questionnaires"`) || !strings.Contains(text, "is_active = $1") {
+		t.Errorf("db.query.text = %q, want the SELECT from questionnaires with placeholders", text)
+	}
+	if strings.Contains(text, "true") {
+		t.Errorf("db.query.text = %q records a parameter value", text)
+	}
+}
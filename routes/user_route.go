@@ -21,10 +21,11 @@ func RegisterUserRoutes(app *fiber.App, userUsecase *usecase.UserUsecase, studen
 
 	// Authenticated user routes - Requires valid JWT
 	authenticated := api.Group("/users", middleware.AuthMiddleware(userUsecase))
-	authenticated.Get("/:id", userHandler.GetById)                // Get user by ID (self)
-	authenticated.Patch("/:id", userHandler.Update)               // Update own account info
-	authenticated.Get("/qr/:id", userHandler.GetQRURL)            // Get user's QR code URL
-	authenticated.Get("/certToken/:id", userHandler.GetCertToken) // Get user's certificate token
+	authenticated.Get("/:id", userHandler.GetById)     // Get user by ID (self; staff without export get no contacts)
+	authenticated.Patch("/:id", userHandler.Update)    // Update own account info (any user for admins)
+	authenticated.Get("/qr/:id", userHandler.GetQRURL) // Get user's QR code URL
+	// Deprecated: name-only certificate token, superseded by /api/certificates/:event
+	authenticated.Get("/certToken/:id", userHandler.GetCertToken)
 
 	// Staff/Admin routes - Requires Staff or Admin role
 	staffAdmin := api.Group("/users", middleware.RoleMiddleware(userUsecase, domain.Staff, domain.Admin))
@@ -36,5 +37,6 @@ func RegisterUserRoutes(app *fiber.App, userUsecase *usecase.UserUsecase, studen
 	admin.Delete("/:id", userHandler.RemoveStaff)         // Delete user
 	admin.Patch("/role/:id", userHandler.UpdateRole)      // Update user role
 	admin.Patch("/addstaff/:phone", userHandler.AddStaff) // Promote user to Staff by phone
+	admin.Patch("/staff/:id", userHandler.UpdateStaff)    // Move staff to the central team or a faculty
 	admin.Delete("/users/:id", userHandler.Delete)        // Delete user
 }
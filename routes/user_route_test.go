@@ -0,0 +1,335 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/usecase"
+	"github.com/isd-sgcu/oph-67-backend/utils"
+	"gorm.io/gorm"
+)
+
+func jsonRequest(method, path, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestStaffRegisterDoesNotGrantStaff(t *testing.T) {
+	users := newFakeUserRepository(testMember)
+	app, userUsecase := newTestApp(t, users)
+	RegisterUserRoutes(app, userUsecase, nil)
+
+	// The staff scope is not a field of the registration, so it is rejected rather than ignored
+	body := `{"id":"new","name":"New","phone":"0812345678","email":"new@example.com","faculty":"engineering","isCentralStaff":true}`
+	if resp := send(t, app, jsonRequest(http.MethodPost, "/api/staff/register", body), ""); resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("register as central staff: status %d, want 422", resp.StatusCode)
+	}
+	if _, ok := users.users["new"]; ok {
+		t.Fatal("rejected registration created the user")
+	}
+
+	body = `{"id":"new","name":"New","phone":"0812345678","email":"new@example.com","faculty":"engineering"}`
+	if resp := send(t, app, jsonRequest(http.MethodPost, "/api/staff/register", body), ""); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("register: status %d, want 201", resp.StatusCode)
+	}
+	created := users.users["new"]
+	if created.Role != domain.Member || created.IsCentralStaff != nil {
+		t.Errorf("registered role %q, central %v; want a member with no central flag", created.Role, created.IsCentralStaff)
+	}
+
+	// Registering again as an existing member signs in without promoting
+	body = `{"id":"member","name":"Member","phone":"0812345679","email":"member@example.com"}`
+	if resp := send(t, app, jsonRequest(http.MethodPost, "/api/staff/register", body), ""); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("register again: status %d, want 201", resp.StatusCode)
+	}
+	if role := users.users["member"].Role; role != domain.Member {
+		t.Errorf("re-registered role %q, want member", role)
+	}
+}
+
+func TestAddStaffAssignsFacultyOrCentralTeam(t *testing.T) {
+	users := newFakeUserRepository(testAdmin, testCentralStaff,
+		domain.User{ID: "a", Role: domain.Member, Phone: "0811111111"},
+		domain.User{ID: "b", Role: domain.Member, Phone: "0822222222"},
+		domain.User{ID: "c", Role: domain.Member, Phone: "0833333333"},
+	)
+	app, userUsecase := newTestApp(t, users)
+	RegisterUserRoutes(app, userUsecase, nil)
+
+	tests := []struct {
+		name   string
+		userID string
+		phone  string
+		body   string
+		status int
+	}{
+		{"staff cannot add staff", testCentralStaff.ID, "0811111111", `{"isCentralStaff":true}`, http.StatusForbidden},
+		{"faculty staff need a faculty", testAdmin.ID, "0811111111", `{}`, http.StatusUnprocessableEntity},
+		{"faculty staff", testAdmin.ID, "0811111111", `{"faculty":"engineering"}`, http.StatusNoContent},
+		{"central staff", testAdmin.ID, "0822222222", `{"isCentralStaff":true}`, http.StatusNoContent},
+		{"no body adds central staff", testAdmin.ID, "0833333333", "", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		req := jsonRequest(http.MethodPatch, "/api/admin/addstaff/"+tt.phone, tt.body)
+		if tt.body == "" {
+			req = httptest.NewRequest(http.MethodPatch, "/api/admin/addstaff/"+tt.phone, nil)
+		}
+		if resp := send(t, app, req, tt.userID); resp.StatusCode != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, resp.StatusCode, tt.status)
+		}
+	}
+
+	if a := users.users["a"]; a.Role != domain.Staff || !a.IsFacultyStaff() || *a.Faculty != "engineering" {
+		t.Errorf("user a is %q of faculty %v, want faculty staff of engineering", a.Role, a.Faculty)
+	}
+	for _, id := range []string{"b", "c"} {
+		if user := users.users[id]; user.Role != domain.Staff || user.IsFacultyStaff() {
+			t.Errorf("user %s is %q, faculty staff %v; want central staff", id, user.Role, user.IsFacultyStaff())
+		}
+	}
+}
+
+// evaluatedStudents has an evaluation for each of the student IDs
+type evaluatedStudents struct {
+	usecase.StudentEvaluationRepositoryInterface
+	ids []string
+}
+
+func (r evaluatedStudents) GetStudentEvaluationByStudentId(ctx context.Context, studentId string) (*domain.StudentEvaluation, error) {
+	for _, id := range r.ids {
+		if id == studentId {
+			return &domain.StudentEvaluation{StudentId: id}, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestCertTokenRouteIsDeprecatedAlias(t *testing.T) {
+	student := domain.User{ID: "student", Role: domain.Student, Name: "สมชาย ใจดี"}
+	users := newFakeUserRepository(student, testMember)
+	app, userUsecase := newTestApp(t, users)
+	evaluations := usecase.NewStudentEvaluationUsecase(evaluatedStudents{ids: []string{student.ID}}, nil, nil, nil)
+	RegisterUserRoutes(app, userUsecase, evaluations)
+
+	resp := send(t, app, httptest.NewRequest(http.MethodGet, "/api/users/certToken/"+student.ID, nil), student.ID)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want 200", resp.StatusCode)
+	}
+	if resp.Header.Get("Deprecation") != "true" {
+		t.Errorf("Deprecation header %q, want true", resp.Header.Get("Deprecation"))
+	}
+	var body domain.CertTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	name, err := utils.VerifyLegacyED25519Signature(userUsecase.Keys.Cert.LegacyPublicKeys(), body.CertToken)
+	if err != nil || name != student.Name {
+		t.Errorf("token signs %q (%v), want %q", name, err, student.Name)
+	}
+
+	resp = send(t, app, httptest.NewRequest(http.MethodGet, "/api/users/certToken/"+testMember.ID, nil), testMember.ID)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("without an evaluation: status %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestUpdateUserOnlyChangesOwnProfile(t *testing.T) {
+	users := newFakeUserRepository(testAdmin, testFacultyStaff, testMember, testStudent)
+	app, userUsecase := newTestApp(t, users)
+	RegisterUserRoutes(app, userUsecase, nil)
+
+	tests := []struct {
+		name   string
+		userID string
+		target string
+		body   string
+		status int
+	}{
+		{"anonymous", "", testStudent.ID, `{"name":"Anonymous"}`, http.StatusUnauthorized},
+		{"another user", testMember.ID, testStudent.ID, `{"name":"Not mine"}`, http.StatusForbidden},
+		{"own staff scope", testFacultyStaff.ID, testFacultyStaff.ID, `{"name":"Escalated","faculty":"arts","isCentralStaff":true}`, http.StatusUnprocessableEntity},
+		{"own profile", testFacultyStaff.ID, testFacultyStaff.ID, `{"name":"Renamed"}`, http.StatusNoContent},
+		{"admin on another user", testAdmin.ID, testMember.ID, `{"name":"By admin"}`, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		req := jsonRequest(http.MethodPatch, "/api/users/"+tt.target, tt.body)
+		if resp := send(t, app, req, tt.userID); resp.StatusCode != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, resp.StatusCode, tt.status)
+		}
+	}
+
+	if name := users.users[testStudent.ID].Name; name != testStudent.Name {
+		t.Errorf("student renamed to %q by someone else", name)
+	}
+	// The staff scope is not part of the profile, so the faculty staff member stays in their faculty
+	staff := users.users[testFacultyStaff.ID]
+	if staff.Name != "Renamed" || !staff.IsFacultyStaff() || *staff.Faculty != "engineering" {
+		t.Errorf("faculty staff is %q of faculty %v (faculty staff %v), want Renamed of engineering", staff.Name, staff.Faculty, staff.IsFacultyStaff())
+	}
+	if name := users.users[testMember.ID].Name; name != "By admin" {
+		t.Errorf("member name %q, want By admin", name)
+	}
+}
+
+func TestUpdateStaffScopeIsAdminOnly(t *testing.T) {
+	users := newFakeUserRepository(testAdmin, testCentralStaff, testFacultyStaff, testMember)
+	app, userUsecase := newTestApp(t, users)
+	RegisterUserRoutes(app, userUsecase, nil)
+
+	tests := []struct {
+		name   string
+		userID string
+		target string
+		body   string
+		status int
+	}{
+		{"staff cannot change their scope", testFacultyStaff.ID, testFacultyStaff.ID, `{"isCentralStaff":true}`, http.StatusForbidden},
+		{"faculty staff need a faculty", testAdmin.ID, testCentralStaff.ID, `{}`, http.StatusUnprocessableEntity},
+		{"members have no scope", testAdmin.ID, testMember.ID, `{"isCentralStaff":true}`, http.StatusConflict},
+		{"unknown user", testAdmin.ID, "nobody", `{"isCentralStaff":true}`, http.StatusNotFound},
+		{"to the central team", testAdmin.ID, testFacultyStaff.ID, `{"isCentralStaff":true}`, http.StatusNoContent},
+		{"to a faculty", testAdmin.ID, testCentralStaff.ID, `{"faculty":"arts"}`, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		req := jsonRequest(http.MethodPatch, "/api/admin/staff/"+tt.target, tt.body)
+		if resp := send(t, app, req, tt.userID); resp.StatusCode != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, resp.StatusCode, tt.status)
+		}
+	}
+
+	if staff := users.users[testFacultyStaff.ID]; staff.Role != domain.Staff || staff.IsFacultyStaff() {
+		t.Errorf("former faculty staff is %q, faculty staff %v; want central staff", staff.Role, staff.IsFacultyStaff())
+	}
+	if staff := users.users[testCentralStaff.ID]; staff.Role != domain.Staff || !staff.IsFacultyStaff() || *staff.Faculty != "arts" {
+		t.Errorf("former central staff is %q of faculty %v, want faculty staff of arts", staff.Role, staff.Faculty)
+	}
+	if member := users.users[testMember.ID]; member.IsCentralStaff != nil {
+		t.Errorf("member got a central flag")
+	}
+}
+
+func TestListUsersSearchesContactsOnlyForExporters(t *testing.T) {
+	users := newFakeUserRepository(testAdmin, testCentralStaff, testFacultyStaff, testStudent)
+	app, userUsecase := newTestApp(t, users)
+	RegisterUserRoutes(app, userUsecase, nil)
+
+	tests := []struct {
+		user     domain.User
+		contacts bool
+	}{
+		{testAdmin, true},
+		{testCentralStaff, true},
+		{testFacultyStaff, false},
+	}
+	for _, tt := range tests {
+		users.lastFilter = domain.UserFilter{}
+		resp := send(t, app, httptest.NewRequest(http.MethodGet, "/api/users?q=0812&role=student&name=som", nil), tt.user.ID)
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET /api/users as %s: status %d, want 200", tt.user.ID, resp.StatusCode)
+			continue
+		}
+		want := domain.UserFilter{Search: "0812", Name: "som", Role: domain.Student, SearchContacts: tt.contacts}
+		if users.lastFilter != want {
+			t.Errorf("GET /api/users as %s: filter %+v, want %+v", tt.user.ID, users.lastFilter, want)
+		}
+	}
+
+	if resp := send(t, app, httptest.NewRequest(http.MethodGet, "/api/users?q=0812", nil), testStudent.ID); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("GET /api/users as a student: status %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestListUsersShowsContactsOnlyToExporters(t *testing.T) {
+	student := domain.User{ID: "student", Role: domain.Student, Name: "Somchai", UID: "uid-1", Phone: "0812345678", Email: "somchai@example.com"}
+	users := newFakeUserRepository(testAdmin, testCentralStaff, testFacultyStaff, student)
+	app, userUsecase := newTestApp(t, users)
+	RegisterUserRoutes(app, userUsecase, nil)
+
+	tests := []struct {
+		user     domain.User
+		contacts bool
+	}{
+		{testAdmin, true},
+		{testCentralStaff, true},
+		{testFacultyStaff, false},
+	}
+	for _, tt := range tests {
+		resp := send(t, app, httptest.NewRequest(http.MethodGet, "/api/users?limit=200", nil), tt.user.ID)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET /api/users as %s: status %d, want 200", tt.user.ID, resp.StatusCode)
+		}
+		var page struct {
+			Users []map[string]interface{} `json:"users"`
+			Total int64                    `json:"total"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Users) != 4 || page.Total != 4 {
+			t.Errorf("GET /api/users as %s: %d users of %d, want 4", tt.user.ID, len(page.Users), page.Total)
+		}
+		for _, listed := range page.Users {
+			if listed["id"] != student.ID {
+				continue
+			}
+			if listed["name"] != student.Name {
+				t.Errorf("GET /api/users as %s: name %v, want %s", tt.user.ID, listed["name"], student.Name)
+			}
+			for _, field := range []string{"phone", "email", "uid"} {
+				if _, ok := listed[field]; ok != tt.contacts {
+					t.Errorf("GET /api/users as %s: %s listed %v, want %v", tt.user.ID, field, ok, tt.contacts)
+				}
+			}
+		}
+	}
+}
+
+func TestGetUserShowsContactsOnlyToSelfAndExporters(t *testing.T) {
+	student := domain.User{ID: "student", Role: domain.Student, Name: "Somchai", UID: "uid-1", Phone: "0812345678", Email: "somchai@example.com"}
+	other := domain.User{ID: "other", Role: domain.Student, Name: "Somying"}
+	users := newFakeUserRepository(testAdmin, testCentralStaff, testFacultyStaff, testMember, student, other)
+	app, userUsecase := newTestApp(t, users)
+	RegisterUserRoutes(app, userUsecase, nil)
+
+	tests := []struct {
+		name     string
+		caller   domain.User
+		status   int
+		contacts bool
+	}{
+		{"self", student, http.StatusOK, true},
+		{"admin", testAdmin, http.StatusOK, true},
+		{"central staff", testCentralStaff, http.StatusOK, true},
+		{"faculty staff without export permission", testFacultyStaff, http.StatusOK, false},
+		{"member", testMember, http.StatusForbidden, false},
+		{"another student", other, http.StatusForbidden, false},
+	}
+	for _, tt := range tests {
+		resp := send(t, app, httptest.NewRequest(http.MethodGet, "/api/users/"+student.ID, nil), tt.caller.ID)
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, resp.StatusCode, tt.status)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body["id"] != student.ID || body["name"] != student.Name {
+			t.Errorf("%s: got user %v %v, want %s", tt.name, body["id"], body["name"], student.Name)
+		}
+		for _, field := range []string{"phone", "email", "uid"} {
+			if _, ok := body[field]; ok != tt.contacts {
+				t.Errorf("%s: %s returned %v, want %v", tt.name, field, ok, tt.contacts)
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package textanalysis
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+var stopwords = map[string]bool{
+	// Thai particles and function words
+	"ที่": true, "และ": true, "ของ": true, "ใน": true, "มี": true, "เป็น": true, "ให้": true, "ได้": true,
+	"จะ": true, "ก็": true, "กับ": true, "แต่": true, "ว่า": true, "นี้": true, "นั้น": true, "อยู่": true,
+	"ไป": true, "มา": true, "แล้ว": true, "ด้วย": true, "ค่ะ": true, "ครับ": true, "คะ": true, "นะ": true,
+	"จ้า": true, "หรือ": true, "เพราะ": true, "มาก": true, "กว่า": true, "เลย": true, "ทุก": true, "อีก": true,
+	"ยัง": true, "ต้อง": true, "จาก": true, "โดย": true, "ถ้า": true, "เมื่อ": true, "ซึ่ง": true, "คือ": true,
+	"ทำ": true, "อยาก": true, "อยากให้": true, "ควร": true,
+	// English
+	"a": true, "an": true, "the": true, "and": true, "or": true, "but": true, "of": true, "to": true,
+	"in": true, "on": true, "for": true, "with": true, "is": true, "are": true, "was": true, "be": true,
+	"it": true, "this": true, "that": true, "i": true, "we": true, "you": true, "very": true, "more": true,
+	"should": true, "can": true, "please": true, "so": true, "too": true, "not": true, "no": true,
+}
+
+// IsStopword reports whether a token carries no meaning on its own
+func IsStopword(token string) bool {
+	return stopwords[token]
+}
+
+// TermCount is how often a keyword or phrase occurs and in how many texts
+type TermCount struct {
+	Term      string `json:"term"`
+	Count     int    `json:"count"`
+	Documents int    `json:"documents"`
+}
+
+// Frequencies holds the most frequent keywords (single words) and phrases (two consecutive words)
+type Frequencies struct {
+	Documents int         `json:"documents"`
+	Keywords  []TermCount `json:"keywords"`
+	Phrases   []TermCount `json:"phrases"`
+}
+
+// CountTerms tokenizes every text and returns up to limit keywords and phrases, most frequent first.
+// Stopwords are dropped and phrases never span a removed stopword.
+func CountTerms(texts []string, limit int) Frequencies {
+	keywords := newCounter()
+	phrases := newCounter()
+
+	for doc, text := range texts {
+		var previous string
+		for _, token := range Tokenize(text) {
+			if IsStopword(token) || utf8.RuneCountInString(token) < 2 {
+				previous = ""
+				continue
+			}
+			keywords.add(token, doc)
+			if previous != "" {
+				phrases.add(joinPhrase(previous, token), doc)
+			}
+			previous = token
+		}
+	}
+
+	return Frequencies{
+		Documents: len(texts),
+		Keywords:  keywords.top(limit, 1),
+		Phrases:   phrases.top(limit, 2),
+	}
+}
+
+// joinPhrase writes Thai words together, as Thai is written, and separates everything else with a space
+func joinPhrase(a, b string) string {
+	first, _ := utf8.DecodeRuneInString(b)
+	last, _ := utf8.DecodeLastRuneInString(a)
+	if isThai(first) && isThai(last) {
+		return a + b
+	}
+	return a + " " + b
+}
+
+type counter struct {
+	counts   map[string]int
+	docs     map[string]int
+	lastSeen map[string]int
+}
+
+func newCounter() *counter {
+	return &counter{counts: map[string]int{}, docs: map[string]int{}, lastSeen: map[string]int{}}
+}
+
+func (c *counter) add(term string, doc int) {
+	c.counts[term]++
+	if last, ok := c.lastSeen[term]; !ok || last != doc {
+		c.docs[term]++
+		c.lastSeen[term] = doc
+	}
+}
+
+// top returns the terms seen at least minCount times, most frequent first
+func (c *counter) top(limit int, minCount int) []TermCount {
+	terms := make([]TermCount, 0, len(c.counts))
+	for term, count := range c.counts {
+		if count >= minCount {
+			terms = append(terms, TermCount{Term: term, Count: count, Documents: c.docs[term]})
+		}
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].Count != terms[j].Count {
+			return terms[i].Count > terms[j].Count
+		}
+		return terms[i].Term < terms[j].Term
+	})
+	if limit > 0 && len(terms) > limit {
+		terms = terms[:limit]
+	}
+	return terms
+}
@@ -0,0 +1,120 @@
+package textanalysis
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MinSimilarity is the lowest similarity at which a spelling is grouped with a catalog entry
+const MinSimilarity = 0.75
+
+// CatalogEntry is something free-text answers may refer to, e.g. a booth, with its known spellings
+type CatalogEntry struct {
+	ID    int
+	Names []string
+	keys  []string
+}
+
+// prefixes people often add in front of a booth name
+var namePrefixes = []string{"บูธ", "คณะ", "booth", "facultyof", "faculty"}
+
+// Normalize lower-cases a short answer and strips spaces, punctuation and common prefixes
+// so that "คณะ วิศวะ", "วิศวะ!" and "วิศวะ" compare equal.
+func Normalize(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || isThaiCombining(r) {
+			b.WriteRune(r)
+		}
+	}
+	normalized := b.String()
+	for _, prefix := range namePrefixes {
+		if trimmed := strings.TrimPrefix(normalized, prefix); trimmed != "" {
+			normalized = trimmed
+		}
+	}
+	return normalized
+}
+
+// Matcher finds the catalog entry a free-text answer most likely refers to
+type Matcher struct {
+	entries []CatalogEntry
+}
+
+func NewMatcher(entries []CatalogEntry) *Matcher {
+	m := &Matcher{entries: make([]CatalogEntry, len(entries))}
+	for i, entry := range entries {
+		entry.keys = make([]string, 0, len(entry.Names))
+		for _, name := range entry.Names {
+			if key := Normalize(name); key != "" {
+				entry.keys = append(entry.keys, key)
+			}
+		}
+		m.entries[i] = entry
+	}
+	return m
+}
+
+// Match returns the ID of the most similar entry and the similarity in [0, 1].
+// ok is false when nothing reaches MinSimilarity.
+func (m *Matcher) Match(text string) (id int, similarity float64, ok bool) {
+	key := Normalize(text)
+	if key == "" {
+		return 0, 0, false
+	}
+
+	for _, entry := range m.entries {
+		for _, name := range entry.keys {
+			score := Similarity(key, name)
+			if score > similarity {
+				id, similarity = entry.ID, score
+			}
+		}
+	}
+	return id, similarity, similarity >= MinSimilarity
+}
+
+// Similarity compares two normalized strings: 1 for equal, 0.9 when the shorter one (at least
+// three characters) is contained in the other, otherwise one minus the normalized edit distance.
+func Similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	shorter, longer := a, b
+	if len(ra) > len(rb) {
+		shorter, longer = b, a
+	}
+	if len([]rune(shorter)) >= 3 && strings.Contains(longer, shorter) {
+		return 0.9
+	}
+
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,176 @@
+// Package textanalysis provides offline text processing for free-text evaluation feedback:
+// Thai-aware word segmentation, keyword and phrase counts, and fuzzy matching of short answers
+// to a catalog.
+package textanalysis
+
+import (
+	_ "embed"
+	"strings"
+	"unicode"
+)
+
+//go:embed thai_words.txt
+var thaiWordList string
+
+var thaiDictionary, maxThaiWordLength = loadDictionary(thaiWordList)
+
+func loadDictionary(list string) (map[string]bool, int) {
+	dictionary := make(map[string]bool)
+	longest := 0
+	for _, line := range strings.Split(list, "\n") {
+		word := strings.TrimSpace(line)
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		dictionary[word] = true
+		if n := len([]rune(word)); n > longest {
+			longest = n
+		}
+	}
+	return dictionary, longest
+}
+
+func isThai(r rune) bool {
+	return r >= 0x0E00 && r <= 0x0E7F
+}
+
+// isThaiMark reports whether r is the repetition mark ๆ or the abbreviation mark ฯ. Both are letters
+// to Unicode but never part of a word.
+func isThaiMark(r rune) bool {
+	return r == 'ๆ' || r == 'ฯ'
+}
+
+// isThaiCombining reports whether r attaches to the previous character (above/below vowels and tone marks),
+// so a word boundary can never fall before it.
+func isThaiCombining(r rune) bool {
+	return r == 0x0E31 || (r >= 0x0E34 && r <= 0x0E3A) || (r >= 0x0E47 && r <= 0x0E4E)
+}
+
+// isThaiLeadingVowel reports whether r is written before the consonant it belongs to (เ แ โ ใ ไ),
+// so a word boundary can never fall after it.
+func isThaiLeadingVowel(r rune) bool {
+	return r >= 0x0E40 && r <= 0x0E44
+}
+
+// Tokenize splits text into lower-cased words. Latin and digit runs are split on anything else;
+// Thai runs, which have no spaces between words, are segmented against the embedded dictionary.
+func Tokenize(text string) []string {
+	var tokens []string
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case isThaiMark(r):
+			// Repetition and abbreviation marks end a word but are not words themselves
+			i++
+		case isThai(r):
+			j := i
+			for j < len(runes) && isThai(runes[j]) && !isThaiMark(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, segmentThai(runes[i:j])...)
+			i = j
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && !isThai(runes[j]) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+				j++
+			}
+			tokens = append(tokens, strings.ToLower(string(runes[i:j])))
+			i = j
+		default:
+			i++
+		}
+	}
+	return tokens
+}
+
+// segmentThai applies maximal matching: of all ways to split the run into dictionary words and unknown
+// character clusters, it picks the one leaving the fewest characters unknown, then the one with the fewest
+// words, preferring a longer first word on ties. This avoids the mistakes of greedy longest matching when a
+// long word swallows the start of the next one. Consecutive unknown clusters are joined into a single token,
+// which keeps names and slang intact.
+func segmentThai(runes []rune) []string {
+	type split struct {
+		unknown, words int
+		length         int  // length of the first piece
+		known          bool // whether the first piece is a dictionary word
+	}
+
+	// best[i] is the best split of runes[i:]
+	best := make([]split, len(runes)+1)
+	better := func(a, b split) bool {
+		return a.unknown < b.unknown || a.unknown == b.unknown && a.words < b.words
+	}
+	for i := len(runes) - 1; i >= 0; i-- {
+		n := clusterLength(runes, i)
+		cluster := split{unknown: best[i+n].unknown + n, words: best[i+n].words + 1, length: n}
+
+		// A dictionary word beats an unknown cluster unless the cluster leaves fewer characters unknown
+		best[i] = cluster
+		for j, length := range matches(runes, i) {
+			word := split{unknown: best[i+length].unknown, words: best[i+length].words + 1, length: length, known: true}
+			if j == 0 || better(word, best[i]) {
+				best[i] = word
+			}
+		}
+		if better(cluster, best[i]) {
+			best[i] = cluster
+		}
+	}
+
+	var tokens []string
+	var unknown []rune
+	for i := 0; i < len(runes); i += best[i].length {
+		piece := runes[i : i+best[i].length]
+		if !best[i].known {
+			unknown = append(unknown, piece...)
+			continue
+		}
+		if len(unknown) > 0 {
+			tokens = append(tokens, string(unknown))
+			unknown = nil
+		}
+		tokens = append(tokens, string(piece))
+	}
+	if len(unknown) > 0 {
+		tokens = append(tokens, string(unknown))
+	}
+	return tokens
+}
+
+// matches returns the lengths of the dictionary words starting at runes[start], longest first
+func matches(runes []rune, start int) []int {
+	var lengths []int
+	end := start + maxThaiWordLength
+	if end > len(runes) {
+		end = len(runes)
+	}
+	for ; end > start; end-- {
+		if !validBoundary(runes, end) {
+			continue
+		}
+		if thaiDictionary[string(runes[start:end])] {
+			lengths = append(lengths, end-start)
+		}
+	}
+	return lengths
+}
+
+// validBoundary reports whether a word may end right before runes[i]
+func validBoundary(runes []rune, i int) bool {
+	if i >= len(runes) {
+		return true
+	}
+	return !isThaiCombining(runes[i]) && !isThaiLeadingVowel(runes[i-1])
+}
+
+// clusterLength returns the length of the character cluster starting at i: a consonant with any leading
+// vowel and the marks that combine with it.
+func clusterLength(runes []rune, i int) int {
+	j := i + 1
+	for j < len(runes) && !validBoundary(runes, j) {
+		j++
+	}
+	return j - i
+}
@@ -0,0 +1,95 @@
+package textanalysis
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenizeThaiFeedback(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{
+			"กิจกรรมสนุกมากค่ะ พี่ๆ ใจดีและเป็นกันเอง",
+			[]string{"กิจกรรม", "สนุก", "มาก", "ค่ะ", "พี่", "ใจดี", "และ", "เป็นกันเอง"},
+		},
+		{
+			"อยากให้มีป้ายบอกทางชัดเจนกว่านี้ หาตึกไม่เจอ",
+			[]string{"อยากให้", "มี", "ป้ายบอกทาง", "ชัดเจน", "กว่า", "นี้", "หา", "ตึก", "ไม่", "เจอ"},
+		},
+		{
+			"คนเยอะมาก รอคิวนานเกินไป อากาศร้อน",
+			[]string{"คนเยอะ", "มาก", "รอคิว", "นาน", "เกินไป", "อากาศร้อน"},
+		},
+		{
+			"ได้ความรู้เกี่ยวกับคณะวิศวกรรมศาสตร์เยอะมากครับ",
+			[]string{"ได้", "ความรู้", "เกี่ยวกับ", "คณะ", "วิศวกรรมศาสตร์", "เยอะ", "มาก", "ครับ"},
+		},
+		{
+			"ห้องน้ำไม่พอ ต้องต่อคิวนาน",
+			[]string{"ห้องน้ำ", "ไม่", "พอ", "ต้อง", "ต่อคิว", "นาน"},
+		},
+		{
+			"รถรางมาช้า เดินทางลำบาก",
+			[]string{"รถราง", "มา", "ช้า", "เดินทาง", "ลำบาก"},
+		},
+		{
+			"ลงทะเบียนผ่าน LINE ง่ายมาก ใช้เวลา 5 นาที",
+			[]string{"ลงทะเบียน", "ผ่าน", "line", "ง่าย", "มาก", "ใช้", "เวลา", "5", "นาที"},
+		},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q)\n got %q\nwant %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+// Greedy longest matching takes "อยากเข้า" and "มาก" here and leaves the rest of the next word unknown
+func TestTokenizePrefersFullCoverageOverLongestWord(t *testing.T) {
+	tests := map[string][]string{
+		"อยากเข้าใจเนื้อหามากขึ้น": {"อยาก", "เข้าใจ", "เนื้อหา", "มากขึ้น"},
+		"อยากรู้จักรุ่นพี่":        {"อยาก", "รู้จัก", "รุ่นพี่"},
+		"พี่ๆมากลับบ้านดึก":        {"พี่", "มา", "กลับบ้าน", "ดึก"},
+	}
+	for text, want := range tests {
+		if got := Tokenize(text); !reflect.DeepEqual(got, want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestTokenizeKeepsUnknownWordsTogether(t *testing.T) {
+	got := Tokenize("ชอบบูธน้องมิ้นท์มาก")
+	want := []string{"ชอบ", "บูธ", "น้อง", "มิ้นท์", "มาก"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTokenizeSkipsRepetitionAndAbbreviationMarks(t *testing.T) {
+	got := Tokenize("สนุกๆ ๆ จุฬาฯ")
+	want := []string{"สนุก", "จุฬา"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCountTermsDropsStopwords(t *testing.T) {
+	frequencies := CountTerms([]string{"คนเยอะมาก รอคิวนาน", "รอคิวนานมากค่ะ"}, 10)
+
+	counts := map[string]int{}
+	for _, keyword := range frequencies.Keywords {
+		counts[keyword.Term] = keyword.Documents
+	}
+	if counts["รอคิว"] != 2 || counts["นาน"] != 2 || counts["คนเยอะ"] != 1 {
+		t.Errorf("keywords %+v", frequencies.Keywords)
+	}
+	if _, ok := counts["มาก"]; ok {
+		t.Errorf("stopword counted: %+v", frequencies.Keywords)
+	}
+	if len(frequencies.Phrases) != 1 || frequencies.Phrases[0].Term != "รอคิวนาน" {
+		t.Errorf("phrases %+v, want รอคิวนาน", frequencies.Phrases)
+	}
+}
@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanKey stores the span of a query between its before and after callbacks
+const spanKey = "tracing:span"
+
+// GormPlugin wraps every gorm query in a client span named after the operation, with the SQL as an
+// attribute. The SQL keeps its placeholders; parameter values are personal data and are not recorded.
+type GormPlugin struct{}
+
+// NewGormPlugin returns the plugin, to be installed with db.Use
+func NewGormPlugin() GormPlugin {
+	return GormPlugin{}
+}
+
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+func (p GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("tracing:before_create", p.before("create", "INSERT")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", p.after),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", p.before("query", "SELECT")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", p.after),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", p.before("update", "UPDATE")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", p.after),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", p.before("delete", "DELETE")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", p.after),
+		// Row and Raw run arbitrary SQL, so the operation is left to the query text
+		cb.Row().Before("gorm:row").Register("tracing:before_row", p.before("row", "")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", p.after),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", p.before("raw", "")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", p.after),
+	)
+}
+
+// before starts the span of a statement about to be run by the given gorm callback
+func (GormPlugin) before(callback, operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		attrs := []attribute.KeyValue{semconv.DBSystemPostgreSQL}
+		if operation != "" {
+			attrs = append(attrs, semconv.DBOperationName(operation))
+		}
+		if db.Statement.Table != "" {
+			attrs = append(attrs, semconv.DBCollectionName(db.Statement.Table))
+		}
+
+		name := "gorm." + callback
+		if db.Statement.Table != "" {
+			name += " " + db.Statement.Table
+		}
+		_, span := Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+// after ends the span started by before with the executed SQL and the outcome
+func (GormPlugin) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	// A missing row is an answer, not a failure of the query
+	if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,75 @@
+// Package tracing sets up OpenTelemetry tracing. Spans are always created and W3C trace context
+// is always propagated, but they are only recorded and exported when an OTLP endpoint is configured.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/isd-sgcu/oph-67-backend/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation names the tracer of this module
+const instrumentation = "github.com/isd-sgcu/oph-67-backend"
+
+// Setup installs the global tracer provider and propagator. It returns a function that flushes
+// pending spans on shutdown; when no endpoint is configured spans are not recorded and it does nothing.
+func Setup(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP exporter: %w", err)
+	}
+	provider := NewProvider(cfg, sdktrace.NewBatchSpanProcessor(exporter))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewProvider returns a tracer provider sending spans to processor, sampled as configured.
+// Traces started by a caller are sampled as the caller decided.
+func NewProvider(cfg config.TracingConfig, processor sdktrace.SpanProcessor) *sdktrace.TracerProvider {
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cfg.ServiceName)))
+	if err != nil {
+		// Only fails on conflicting schema URLs; the default resource is still usable
+		res = resource.Default()
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+}
+
+// Tracer returns the tracer of this module from the global provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// Start starts a span named after the operation, such as "UserUsecase.ScanQR"
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on span, if any, and ends it. Use it with a named error result:
+//
+//	defer func() { tracing.End(span, err) }()
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
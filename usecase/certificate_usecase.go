@@ -0,0 +1,318 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/isd-sgcu/oph-67-backend/certificate"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/keyring"
+	"github.com/isd-sgcu/oph-67-backend/tracing"
+	"github.com/isd-sgcu/oph-67-backend/utils"
+	"gorm.io/gorm"
+)
+
+// CertificateUsecase issues participation certificates to students who attended and completed the evaluation
+type CertificateUsecase struct {
+	TemplateRepo    CertificateTemplateRepositoryInterface
+	CertificateRepo CertificateRepositoryInterface
+	UserRepo        UserRepositoryInterface
+	Evaluations     *StudentEvaluationUsecase
+	Config          CertificateConfig
+}
+
+type CertificateTemplateRepositoryInterface interface {
+	GetAll(ctx context.Context) ([]domain.CertificateTemplate, error)
+	GetByEvent(ctx context.Context, event string) (domain.CertificateTemplate, error)
+	Save(ctx context.Context, template *domain.CertificateTemplate) error
+}
+
+type CertificateRepositoryInterface interface {
+	Create(ctx context.Context, certificate *domain.Certificate) error
+	GetBySerial(ctx context.Context, serial string) (domain.Certificate, error)
+	GetActive(ctx context.Context, userId string, event string) (domain.Certificate, error)
+	GetAll(ctx context.Context, filter domain.CertificateFilter) ([]domain.Certificate, error)
+	Revoke(ctx context.Context, serial string, reason string, revokedBy string, at time.Time) error
+}
+
+type CertificateConfig struct {
+	Keys     *keyring.Ed25519Keys // the active key signs new certificates; previous keys still verify
+	BaseURL  string               // public URL of this API, used in the verification QR code
+	AssetDir string               // the only directory template fonts and backgrounds are read from
+}
+
+func NewCertificateUsecase(
+	templateRepo CertificateTemplateRepositoryInterface,
+	certificateRepo CertificateRepositoryInterface,
+	userRepo UserRepositoryInterface,
+	evaluations *StudentEvaluationUsecase,
+	config CertificateConfig,
+) *CertificateUsecase {
+	return &CertificateUsecase{
+		TemplateRepo:    templateRepo,
+		CertificateRepo: certificateRepo,
+		UserRepo:        userRepo,
+		Evaluations:     evaluations,
+		Config:          config,
+	}
+}
+
+func (u *CertificateUsecase) GetTemplates(ctx context.Context) ([]domain.CertificateTemplate, error) {
+	ctx, span := tracing.Start(ctx, "CertificateUsecase.GetTemplates")
+	defer span.End()
+
+	return u.TemplateRepo.GetAll(ctx)
+}
+
+func (u *CertificateUsecase) GetTemplate(ctx context.Context, event string) (domain.CertificateTemplate, error) {
+	ctx, span := tracing.Start(ctx, "CertificateUsecase.GetTemplate")
+	defer span.End()
+
+	template, err := u.TemplateRepo.GetByEvent(ctx, event)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.CertificateTemplate{}, domain.ErrCertificateTemplateNotFound
+	}
+	return template, err
+}
+
+// SaveTemplate creates or replaces the certificate template of an event
+func (u *CertificateUsecase) SaveTemplate(ctx context.Context, template *domain.CertificateTemplate) error {
+	ctx, span := tracing.Start(ctx, "CertificateUsecase.SaveTemplate")
+	defer span.End()
+
+	verr := &domain.ValidationError{}
+	if strings.TrimSpace(template.Event) == "" {
+		verr.Add("event", domain.FieldRequired, "is required")
+	}
+	if strings.TrimSpace(template.EventName) == "" {
+		verr.Add("eventName", domain.FieldRequired, "is required")
+	}
+	if strings.TrimSpace(template.Title) == "" {
+		verr.Add("title", domain.FieldRequired, "is required")
+	}
+	if template.FontFile == "" {
+		verr.Add("fontFile", domain.FieldRequired, "is required")
+	} else {
+		u.checkAsset(verr, "fontFile", template.FontFile)
+	}
+	if template.BackgroundFile != nil && *template.BackgroundFile != "" {
+		u.checkAsset(verr, "backgroundFile", *template.BackgroundFile)
+	}
+	if err := verr.OrNil(); err != nil {
+		return err
+	}
+
+	template.ID = 0
+	return u.TemplateRepo.Save(ctx, template)
+}
+
+// checkAsset reports a template file name that is outside the asset directory or missing from it
+func (u *CertificateUsecase) checkAsset(verr *domain.ValidationError, field string, name string) {
+	path, err := certificate.AssetPath(u.Config.AssetDir, name)
+	if err != nil {
+		verr.Add(field, domain.FieldInvalid, "must be a path relative to the certificate asset directory")
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		verr.Add(field, domain.FieldInvalid, "file not found in the certificate asset directory")
+	}
+}
+
+// Issue renders the certificate of an event for a student. The student must have entered the event
+// and submitted the evaluation. The first download records the certificate; later downloads re-render it.
+func (u *CertificateUsecase) Issue(ctx context.Context, studentId string, event string) (domain.CertificateFile, error) {
+	ctx, span := tracing.Start(ctx, "CertificateUsecase.Issue")
+	defer span.End()
+
+	template, err := u.GetTemplate(ctx, event)
+	if err != nil {
+		return domain.CertificateFile{}, err
+	}
+
+	cert, err := u.CertificateRepo.GetActive(ctx, studentId, event)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		cert, err = u.issue(ctx, studentId, template)
+	}
+	if err != nil {
+		return domain.CertificateFile{}, err
+	}
+
+	var buf bytes.Buffer
+	err = certificate.Render(&buf, u.Config.AssetDir, template, certificate.Data{
+		Name:      cert.HolderName,
+		VerifyURL: u.Config.BaseURL + "/api/certificates/verify?token=" + url.QueryEscape(cert.Token),
+	})
+	if err != nil {
+		return domain.CertificateFile{}, err
+	}
+	return domain.CertificateFile{FileName: certificate.FileName(event, cert.Serial), PDF: buf.Bytes()}, nil
+}
+
+// issue checks that the student may get a certificate, signs its claims and records it
+func (u *CertificateUsecase) issue(ctx context.Context, studentId string, template domain.CertificateTemplate) (domain.Certificate, error) {
+	student, err := u.UserRepo.GetById(ctx, studentId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.Certificate{}, domain.ErrUserNotFound
+		}
+		return domain.Certificate{}, err
+	}
+
+	attended, err := u.Evaluations.HasAttended(ctx, studentId)
+	if err != nil {
+		return domain.Certificate{}, err
+	}
+	if !attended {
+		return domain.Certificate{}, domain.ErrStudentNotAttended.WithMessage("Only students who entered the event can get a certificate")
+	}
+	evaluated, err := u.Evaluations.HasEvaluated(ctx, studentId)
+	if err != nil {
+		return domain.Certificate{}, err
+	}
+	if !evaluated {
+		return domain.Certificate{}, domain.ErrEvaluationRequired
+	}
+
+	serial, err := utils.GenerateSerial()
+	if err != nil {
+		return domain.Certificate{}, err
+	}
+	issuedAt := time.Now().Truncate(time.Second)
+	claims, err := json.Marshal(domain.CertificateClaims{
+		Serial:   serial,
+		UserID:   student.ID,
+		UID:      student.UID,
+		Name:     student.Name,
+		Event:    template.Event,
+		IssuedAt: issuedAt.Unix(),
+	})
+	if err != nil {
+		return domain.Certificate{}, err
+	}
+	token, err := u.Config.Keys.Sign(string(claims))
+	if err != nil {
+		return domain.Certificate{}, err
+	}
+
+	cert := domain.Certificate{
+		Serial:     serial,
+		UserID:     student.ID,
+		UID:        student.UID,
+		HolderName: student.Name,
+		Event:      template.Event,
+		KeyID:      u.Config.Keys.ActiveID,
+		Token:      token,
+		IssuedAt:   issuedAt,
+	}
+	if err := u.CertificateRepo.Create(ctx, &cert); err != nil {
+		// A concurrent download may have recorded the certificate first
+		if existing, getErr := u.CertificateRepo.GetActive(ctx, studentId, template.Event); getErr == nil {
+			return existing, nil
+		}
+		return domain.Certificate{}, err
+	}
+	return cert, nil
+}
+
+// GetCertificates lists issued certificates; Revoked=true gives the revocation list
+func (u *CertificateUsecase) GetCertificates(ctx context.Context, filter domain.CertificateFilter) ([]domain.Certificate, error) {
+	ctx, span := tracing.Start(ctx, "CertificateUsecase.GetCertificates")
+	defer span.End()
+
+	return u.CertificateRepo.GetAll(ctx, filter)
+}
+
+// Revoke revokes a certificate so verification rejects it. The holder can download a new one afterwards.
+func (u *CertificateUsecase) Revoke(ctx context.Context, serial string, reason string, revokedBy string) (domain.Certificate, error) {
+	ctx, span := tracing.Start(ctx, "CertificateUsecase.Revoke")
+	defer span.End()
+
+	cert, err := u.CertificateRepo.GetBySerial(ctx, serial)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.Certificate{}, domain.ErrCertificateNotFound
+		}
+		return domain.Certificate{}, err
+	}
+	if cert.RevokedAt != nil {
+		return domain.Certificate{}, domain.ErrCertificateAlreadyRevoked
+	}
+
+	now := time.Now()
+	if err := u.CertificateRepo.Revoke(ctx, serial, reason, revokedBy, now); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.Certificate{}, domain.ErrCertificateAlreadyRevoked
+		}
+		return domain.Certificate{}, err
+	}
+	cert.RevokedAt = &now
+	cert.RevocationReason = &reason
+	cert.RevokedBy = &revokedBy
+	return cert, nil
+}
+
+// Verify checks a certificate token against the current and retired public keys and the revocation list.
+// Tokens naming their key must carry a recorded serial. Name-only tokens from the deprecated /api/users/certToken
+// route are checked against the legacy keys and reported as legacy, vouching only for the name.
+// Tokens that fail verification are reported as not valid rather than as an error.
+func (u *CertificateUsecase) Verify(ctx context.Context, token string) (domain.CertificateVerification, error) {
+	ctx, span := tracing.Start(ctx, "CertificateUsecase.Verify")
+	defer span.End()
+
+	if strings.Count(token, ".") == 1 {
+		name, err := utils.VerifyLegacyED25519Signature(u.Config.Keys.LegacyPublicKeys(), token)
+		if err != nil {
+			return domain.CertificateVerification{Valid: false}, nil
+		}
+		return domain.CertificateVerification{Valid: true, Legacy: true, Name: name}, nil
+	}
+
+	keyID, message, err := utils.VerifyED25519Signature(u.Config.Keys.PublicKeys(), token)
+	if err != nil {
+		return domain.CertificateVerification{Valid: false}, nil
+	}
+
+	var claims domain.CertificateClaims
+	if err := json.Unmarshal([]byte(message), &claims); err != nil || claims.Serial == "" {
+		return domain.CertificateVerification{Valid: false}, nil
+	}
+
+	result := domain.CertificateVerification{Valid: true, KeyID: keyID, Serial: claims.Serial, Name: claims.Name, Event: claims.Event}
+	if claims.IssuedAt != 0 {
+		issuedAt := time.Unix(claims.IssuedAt, 0)
+		result.IssuedAt = &issuedAt
+	}
+
+	cert, err := u.CertificateRepo.GetBySerial(ctx, claims.Serial)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.CertificateVerification{Valid: false, Serial: claims.Serial}, nil
+	}
+	if err != nil {
+		return domain.CertificateVerification{}, err
+	}
+	if cert.RevokedAt != nil {
+		result.Valid = false
+		result.Revoked = true
+		result.RevokedAt = cert.RevokedAt
+	}
+
+	if claims.Event != "" {
+		template, err := u.GetTemplate(ctx, claims.Event)
+		if err != nil && !errors.Is(err, domain.ErrCertificateTemplateNotFound) {
+			return domain.CertificateVerification{}, err
+		}
+		if err == nil {
+			result.EventName = template.EventName
+			if !template.EventDate.IsZero() {
+				result.EventDate = &template.EventDate
+			}
+		}
+	}
+	return result, nil
+}
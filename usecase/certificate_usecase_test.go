@@ -0,0 +1,194 @@
+package usecase
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isd-sgcu/oph-67-backend/config"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/keyring"
+	"github.com/isd-sgcu/oph-67-backend/utils"
+	"gorm.io/gorm"
+)
+
+// savedTemplates records the templates that passed validation
+type savedTemplates struct {
+	CertificateTemplateRepositoryInterface
+	saved []domain.CertificateTemplate
+}
+
+func (r *savedTemplates) Save(ctx context.Context, template *domain.CertificateTemplate) error {
+	r.saved = append(r.saved, *template)
+	return nil
+}
+
+func TestSaveTemplateOnlyAcceptsFilesInAssetDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Sarabun-Regular.ttf"), []byte("font"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(t.TempDir(), "secret.key")
+	if err := os.WriteFile(outside, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		font       string
+		background string
+		valid      bool
+	}{
+		{"relative font", "Sarabun-Regular.ttf", "", true},
+		{"missing font", "Missing.ttf", "", false},
+		{"absolute font", outside, "", false},
+		{"font outside", "../" + filepath.Base(filepath.Dir(outside)) + "/secret.key", "", false},
+		{"absolute background", "Sarabun-Regular.ttf", outside, false},
+		{"background outside", "Sarabun-Regular.ttf", "../secret.key", false},
+	}
+	for _, tt := range tests {
+		repo := &savedTemplates{}
+		u := NewCertificateUsecase(repo, nil, nil, nil, CertificateConfig{AssetDir: dir})
+		template := &domain.CertificateTemplate{Event: "oph-2025", EventName: "CU Open House", Title: "Certificate", FontFile: tt.font}
+		if tt.background != "" {
+			template.BackgroundFile = &tt.background
+		}
+
+		err := u.SaveTemplate(context.Background(), template)
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("%s: valid %v, want %v (error %v)", tt.name, valid, tt.valid, err)
+		}
+		if !tt.valid && len(repo.saved) > 0 {
+			t.Errorf("%s: template saved", tt.name)
+		}
+	}
+}
+
+// issuedCertificates knows the certificates by serial
+type issuedCertificates struct {
+	CertificateRepositoryInterface
+	serials map[string]domain.Certificate
+}
+
+func (r issuedCertificates) GetBySerial(ctx context.Context, serial string) (domain.Certificate, error) {
+	cert, ok := r.serials[serial]
+	if !ok {
+		return domain.Certificate{}, gorm.ErrRecordNotFound
+	}
+	return cert, nil
+}
+
+// noTemplates has no certificate template for any event
+type noTemplates struct {
+	CertificateTemplateRepositoryInterface
+}
+
+func (noTemplates) GetByEvent(ctx context.Context, event string) (domain.CertificateTemplate, error) {
+	return domain.CertificateTemplate{}, gorm.ErrRecordNotFound
+}
+
+func TestVerifyRequiresKeyIDAndSerialOrLegacyName(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey := base64.RawURLEncoding.EncodeToString(private)
+	legacyPublic, legacyPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, unknownPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := keyring.Load(config.KeysConfig{
+		JWTSecret:           "0123456789abcdef0123456789abcdef",
+		JWTKeyID:            "k1",
+		CertPrivateKey:      privateKey,
+		CertKeyID:           "k1",
+		CertLegacyPublicKey: base64.RawURLEncoding.EncodeToString(legacyPublic),
+		LinkSecret:          "fedcba9876543210fedcba9876543210",
+		LinkKeyID:           "k1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revokedAt, reason := time.Date(2025, 1, 20, 9, 0, 0, 0, time.UTC), "issued to the wrong student"
+	certificates := issuedCertificates{serials: map[string]domain.Certificate{
+		"CERT-1": {Serial: "CERT-1"},
+		"CERT-3": {Serial: "CERT-3", RevokedAt: &revokedAt, RevocationReason: &reason},
+	}}
+	u := NewCertificateUsecase(noTemplates{}, certificates, nil, nil, CertificateConfig{Keys: keys.Cert})
+
+	sign := func(message string) string {
+		token, err := keys.Cert.Sign(message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	signWith := func(private ed25519.PrivateKey, message string) string {
+		token, err := utils.GenerateED25519Signature(base64.RawURLEncoding.EncodeToString(private), message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	legacyName, err := keys.Cert.SignLegacy("Somchai")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		valid  bool
+		legacy bool
+	}{
+		{"issued certificate", sign(`{"serial":"CERT-1","name":"Somchai"}`), true, false},
+		{"claims without key ID", signWith(private, `{"serial":"CERT-1","name":"Somchai"}`), false, false},
+		{"empty key ID", "." + signWith(private, `{"serial":"CERT-1","name":"Somchai"}`), false, false},
+		{"keyed bare name", sign("Somchai"), false, false},
+		{"no serial", sign(`{"name":"Somchai","event":"oph-2025"}`), false, false},
+		{"unknown serial", sign(`{"serial":"CERT-2","name":"Somchai"}`), false, false},
+		{"revoked", sign(`{"serial":"CERT-3","name":"Somchai"}`), false, false},
+		{"legacy name, active key", legacyName, true, true},
+		{"legacy name, legacy key", signWith(legacyPrivate, "Somchai"), true, true},
+		{"legacy name, unknown key", signWith(unknownPrivate, "Somchai"), false, false},
+		{"keyed claims with the key ID cut off", strings.SplitN(sign(`{"serial":"CERT-1","name":"Somchai"}`), ".", 2)[1], false, false},
+	}
+	for _, tt := range tests {
+		result, err := u.Verify(context.Background(), tt.token)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if result.Valid != tt.valid || result.Legacy != tt.legacy {
+			t.Errorf("%s: valid %v, legacy %v; want %v, %v", tt.name, result.Valid, result.Legacy, tt.valid, tt.legacy)
+		}
+		if tt.legacy && result.Name != "Somchai" {
+			t.Errorf("%s: name %q, want Somchai", tt.name, result.Name)
+		}
+	}
+
+	// Anyone can verify a token, so a revocation only shows when it happened and not why
+	result, err := u.Verify(context.Background(), sign(`{"serial":"CERT-3","name":"Somchai"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(result)
+	if !result.Revoked || result.RevokedAt == nil || strings.Contains(string(body), reason) {
+		t.Errorf("revoked certificate verifies as %s, want revoked with revokedAt and no reason", body)
+	}
+
+	if _, err := keys.Cert.SignLegacy(` {"serial":"CERT-1","name":"Somchai"}`); !errors.Is(err, keyring.ErrClaimsLikeName) {
+		t.Errorf("signing a claims-like name: %v, want ErrClaimsLikeName", err)
+	}
+}
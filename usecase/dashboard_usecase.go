@@ -1,56 +1,222 @@
 package usecase
 
 import (
+	"context"
+	"io"
+	"sort"
+
 	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/export"
+	"github.com/isd-sgcu/oph-67-backend/tracing"
+	"github.com/isd-sgcu/oph-67-backend/utils"
 )
 
+// DefaultSchoolLimit is how many schools are listed before the rest are folded into "other"
+const DefaultSchoolLimit = 10
+
+// DefaultProvinceLimit is how many provinces are listed before the rest are folded into "other"
+const DefaultProvinceLimit = 10
+
 type DashboardUseCase struct {
 	DashboardRepo DashBoardRepositoryInterface
 }
 
 type DashBoardRepositoryInterface interface {
-	GetFacultyCount() ([]domain.FacultyPercent, error)
-	GetSourceCount() ([]domain.SourceCount, error)
-	GetAgeGroupCount() ([]domain.AgeCount, error)
-	GetFacultyToday() ([]domain.FacultyRegisterCount, error)
-	GetStatusStudent() ([]domain.StatusCount, error)
-	GetAllStudents() ([]domain.StudentProfile, error)
-	GetStudentsByFacultyInterest(faculty string) ([]domain.StudentProfile, error)
-	GetAttendedCount() ([]domain.AttendedCount, error)
+	GetFacultyCount(ctx context.Context) ([]domain.FacultyPercent, error)
+	GetSourceCount(ctx context.Context, scope *string) ([]domain.SourceCount, error)
+	GetAgeGroupCount(ctx context.Context, scope *string) ([]domain.AgeCount, error)
+	GetFacultyToday(ctx context.Context) ([]domain.FacultyRegisterCount, error)
+	GetStatusStudent(ctx context.Context, scope *string) ([]domain.StatusCount, error)
+	GetAttendedCount(ctx context.Context, scope *string) ([]domain.AttendedCount, error)
+	GetProvinceCount(ctx context.Context, scope *string) ([]domain.ProvinceCount, error)
+	GetSchoolCount(ctx context.Context, scope *string) ([]domain.SchoolCount, error)
+	StreamStudents(ctx context.Context, filter domain.StudentExportFilter, fn func(row *domain.StudentExportRow) error) error
 }
 
 func NewDashBoardUseCase(dashboardRepo DashBoardRepositoryInterface) *DashboardUseCase {
 	return &DashboardUseCase{DashboardRepo: dashboardRepo}
 }
 
-func (d *DashboardUseCase) GetFacultyCount() ([]domain.FacultyPercent, error) {
-	return d.DashboardRepo.GetFacultyCount()
+// GetFacultyCount returns interest counts per faculty. A non-nil scope keeps only that faculty.
+func (d *DashboardUseCase) GetFacultyCount(ctx context.Context, scope *string) ([]domain.FacultyPercent, error) {
+	ctx, span := tracing.Start(ctx, "DashboardUseCase.GetFacultyCount")
+	defer span.End()
+
+	results, err := d.DashboardRepo.GetFacultyCount(ctx)
+	if err != nil || scope == nil {
+		return results, err
+	}
+	scoped := []domain.FacultyPercent{}
+	for _, result := range results {
+		if result.Faculty == *scope {
+			scoped = append(scoped, result)
+		}
+	}
+	return scoped, nil
+}
+
+func (d *DashboardUseCase) GetSourceCount(ctx context.Context, scope *string) ([]domain.SourceCount, error) {
+	ctx, span := tracing.Start(ctx, "DashboardUseCase.GetSourceCount")
+	defer span.End()
+
+	return d.DashboardRepo.GetSourceCount(ctx, scope)
+}
+
+func (d *DashboardUseCase) GetAgeGroupCount(ctx context.Context, scope *string) ([]domain.AgeCount, error) {
+	ctx, span := tracing.Start(ctx, "DashboardUseCase.GetAgeGroupCount")
+	defer span.End()
+
+	return d.DashboardRepo.GetAgeGroupCount(ctx, scope)
+}
+
+// GetFacultyTodayCount returns today's scans per faculty. A non-nil scope keeps only that faculty.
+func (d *DashboardUseCase) GetFacultyTodayCount(ctx context.Context, scope *string) ([]domain.FacultyRegisterCount, error) {
+	ctx, span := tracing.Start(ctx, "DashboardUseCase.GetFacultyTodayCount")
+	defer span.End()
+
+	results, err := d.DashboardRepo.GetFacultyToday(ctx)
+	if err != nil || scope == nil {
+		return results, err
+	}
+	scoped := []domain.FacultyRegisterCount{}
+	for _, result := range results {
+		if result.Faculty == *scope {
+			scoped = append(scoped, result)
+		}
+	}
+	return scoped, nil
 }
 
-func (d *DashboardUseCase) GetSourceCount() ([]domain.SourceCount, error) {
-	return d.DashboardRepo.GetSourceCount()
+func (d *DashboardUseCase) GetStatusStudent(ctx context.Context, scope *string) ([]domain.StatusCount, error) {
+	ctx, span := tracing.Start(ctx, "DashboardUseCase.GetStatusStudent")
+	defer span.End()
+
+	return d.DashboardRepo.GetStatusStudent(ctx, scope)
 }
 
-func (d *DashboardUseCase) GetAgeGroupCount() ([]domain.AgeCount, error) {
-	return d.DashboardRepo.GetAgeGroupCount()
+func (d *DashboardUseCase) GetAttendedCount(ctx context.Context, scope *string) ([]domain.AttendedCount, error) {
+	ctx, span := tracing.Start(ctx, "DashboardUseCase.GetAttendedCount")
+	defer span.End()
+
+	return d.DashboardRepo.GetAttendedCount(ctx, scope)
 }
 
-func (d *DashboardUseCase) GetFacultyTodayCount() ([]domain.FacultyRegisterCount, error) {
-	return d.DashboardRepo.GetFacultyToday()
+// GetProvinceCount returns the top provinces by registrations, folding the remainder into a single "other" entry.
+// Spellings of the same province are counted together under its Thai name and code.
+func (d *DashboardUseCase) GetProvinceCount(ctx context.Context, scope *string, limit int) ([]domain.ProvinceCount, error) {
+	ctx, span := tracing.Start(ctx, "DashboardUseCase.GetProvinceCount")
+	defer span.End()
+
+	results, err := d.provinceCounts(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultProvinceLimit
+	}
+	if len(results) <= limit {
+		return results, nil
+	}
+
+	other := domain.ProvinceCount{Province: domain.OtherGroup}
+	for _, result := range results[limit:] {
+		other.Registered += result.Registered
+		other.Attended += result.Attended
+	}
+	return append(results[:limit:limit], other), nil
 }
 
-func (d *DashboardUseCase) GetStatusStudent() ([]domain.StatusCount, error) {
-	return d.DashboardRepo.GetStatusStudent()
+// GetProvinceGeo returns the counts of every recognised province keyed by its code.
+func (d *DashboardUseCase) GetProvinceGeo(ctx context.Context, scope *string) (map[string]domain.ProvinceCount, error) {
+	ctx, span := tracing.Start(ctx, "DashboardUseCase.GetProvinceGeo")
+	defer span.End()
+
+	results, err := d.provinceCounts(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	geo := make(map[string]domain.ProvinceCount)
+	for _, result := range results {
+		if result.Code != "" {
+			geo[result.Code] = result
+		}
+	}
+	return geo, nil
 }
 
-func (d *DashboardUseCase) GetAllStudent() ([]domain.StudentProfile, error) {
-	return d.DashboardRepo.GetAllStudents()
+// provinceCounts merges the spellings of each recognised province, ordered by registrations.
+// Names that are not recognised are kept as they were written, without a code.
+func (d *DashboardUseCase) provinceCounts(ctx context.Context, scope *string) ([]domain.ProvinceCount, error) {
+	results, err := d.DashboardRepo.GetProvinceCount(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := []domain.ProvinceCount{}
+	index := make(map[string]int)
+	for _, result := range results {
+		key := result.Province
+		if code, ok := utils.ProvinceCode(result.Province); ok {
+			result.Province, result.Code = utils.ProvinceName(code), code
+			key = code
+		}
+		if i, ok := index[key]; ok {
+			merged[i].Registered += result.Registered
+			merged[i].Attended += result.Attended
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, result)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		if merged[i].Registered != merged[j].Registered {
+			return merged[i].Registered > merged[j].Registered
+		}
+		return merged[i].Province < merged[j].Province
+	})
+	return merged, nil
 }
 
-func (d *DashboardUseCase) GetStudentsByFacultyInterest(faculty string) ([]domain.StudentProfile, error) {
-	return d.DashboardRepo.GetStudentsByFacultyInterest(faculty)
+// GetSchoolCount returns the top schools by registrations, folding the remainder into a single "other" entry.
+func (d *DashboardUseCase) GetSchoolCount(ctx context.Context, scope *string, limit int) ([]domain.SchoolCount, error) {
+	ctx, span := tracing.Start(ctx, "DashboardUseCase.GetSchoolCount")
+	defer span.End()
+
+	results, err := d.DashboardRepo.GetSchoolCount(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultSchoolLimit
+	}
+	if len(results) <= limit {
+		return results, nil
+	}
+
+	other := domain.SchoolCount{School: domain.OtherGroup}
+	for _, result := range results[limit:] {
+		other.Registered += result.Registered
+		other.Attended += result.Attended
+	}
+	return append(results[:limit:limit], other), nil
 }
 
-func (d *DashboardUseCase) GetAttendedCount() ([]domain.AttendedCount, error) {
-	return d.DashboardRepo.GetAttendedCount()
+// ExportStudents writes the students matching the filter to w in the given format, one row at a time.
+func (d *DashboardUseCase) ExportStudents(ctx context.Context, w io.Writer, filter domain.StudentExportFilter, format export.Format, columns []export.Column) error {
+	ctx, span := tracing.Start(ctx, "DashboardUseCase.ExportStudents")
+	defer span.End()
+
+	if filter.By != "" && filter.By != domain.ByInterest && filter.By != domain.ByVisit {
+		return domain.ErrInvalidFacultyListBasis
+	}
+
+	writer, err := export.NewRowWriter(format, w, columns)
+	if err != nil {
+		return err
+	}
+	if err := d.DashboardRepo.StreamStudents(ctx, filter, writer.Write); err != nil {
+		return err
+	}
+	return writer.Close()
 }
@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+)
+
+// provinceRepository returns its provinces as the database grouped them, by trimmed name
+type provinceRepository struct {
+	DashBoardRepositoryInterface
+	provinces []domain.ProvinceCount
+}
+
+func (r provinceRepository) GetProvinceCount(ctx context.Context, scope *string) ([]domain.ProvinceCount, error) {
+	return r.provinces, nil
+}
+
+func TestGetProvinceCount(t *testing.T) {
+	many := []domain.ProvinceCount{}
+	for i := 0; i < 12; i++ {
+		many = append(many, domain.ProvinceCount{Province: fmt.Sprintf("Unknown %02d", i), Registered: 20 - i, Attended: 1})
+	}
+
+	tests := []struct {
+		name      string
+		provinces []domain.ProvinceCount
+		limit     int
+		want      []domain.ProvinceCount
+	}{
+		{
+			name: "spellings of a province are merged",
+			provinces: []domain.ProvinceCount{
+				{Province: "เชียงใหม่", Registered: 5, Attended: 2},
+				{Province: "กรุงเทพ", Registered: 4, Attended: 1},
+				{Province: "กรุงเทพมหานคร", Registered: 3, Attended: 3},
+				{Province: "Bangkok", Registered: 1},
+				{Province: "Atlantis", Registered: 1, Attended: 1},
+			},
+			want: []domain.ProvinceCount{
+				{Province: "กรุงเทพมหานคร", Code: "TH-10", Registered: 8, Attended: 4},
+				{Province: "เชียงใหม่", Code: "TH-50", Registered: 5, Attended: 2},
+				{Province: "Atlantis", Registered: 1, Attended: 1},
+			},
+		},
+		{
+			name:      "the tail is folded into other",
+			provinces: many[:5],
+			limit:     3,
+			want: []domain.ProvinceCount{
+				many[0], many[1], many[2],
+				{Province: domain.OtherGroup, Registered: 33, Attended: 2},
+			},
+		},
+		{
+			name:      "the default limit applies without one",
+			provinces: many,
+			want:      append(append([]domain.ProvinceCount{}, many[:DefaultProvinceLimit]...), domain.ProvinceCount{Province: domain.OtherGroup, Registered: 19, Attended: 2}),
+		},
+		{
+			name:      "no provinces",
+			provinces: nil,
+			want:      []domain.ProvinceCount{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := NewDashBoardUseCase(provinceRepository{provinces: tt.provinces})
+			got, err := u.GetProvinceCount(context.Background(), nil, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetProvinceGeoKeepsRecognisedProvinces(t *testing.T) {
+	u := NewDashBoardUseCase(provinceRepository{provinces: []domain.ProvinceCount{
+		{Province: "กรุงเทพ", Registered: 4, Attended: 1},
+		{Province: "Bangkok", Registered: 1},
+		{Province: "Atlantis", Registered: 9},
+	}})
+	geo, err := u.GetProvinceGeo(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]domain.ProvinceCount{
+		"TH-10": {Province: "กรุงเทพมหานคร", Code: "TH-10", Registered: 5, Attended: 1},
+	}
+	if !reflect.DeepEqual(geo, want) {
+		t.Errorf("got %+v, want %+v", geo, want)
+	}
+}
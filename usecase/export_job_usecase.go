@@ -0,0 +1,431 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/export"
+	"github.com/isd-sgcu/oph-67-backend/keyring"
+	"github.com/isd-sgcu/oph-67-backend/tracing"
+	"github.com/isd-sgcu/oph-67-backend/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// ExportJobUsecase runs student exports in the background and serves the results through expiring signed links.
+// Jobs are stored in the database so pending work is picked up again after a restart.
+//
+// Files are written to Config.Dir on the replica that ran the job. With more than one replica the directory must
+// be storage shared by all of them, or a download can reach a replica without the file.
+//
+// A worker holds a lease on the job it runs and renews it every exportHeartbeat. A running job whose lease has
+// expired was abandoned by a process that died, and the sweeper puts it back in the queue. Running jobs of live
+// replicas are never touched.
+type ExportJobUsecase struct {
+	ExportJobRepo ExportJobRepositoryInterface
+	Dashboard     *DashboardUseCase
+	Config        ExportJobConfig
+	Logger        *slog.Logger
+
+	queue   chan string
+	workers sync.WaitGroup
+}
+
+// ExportJobConfig controls where exports are stored and for how long
+type ExportJobConfig struct {
+	Dir       string            // local directory for finished files
+	Retention time.Duration     // how long a finished file is kept
+	LinkTTL   time.Duration     // how long a signed download link is valid
+	LinkKeys  *keyring.HMACKeys // signs download links; previous keys keep verifying
+	BaseURL   string            // public base URL used to build download links
+	Workers   int
+}
+
+const (
+	// exportHeartbeat is how often a worker renews the lease on the job it runs
+	exportHeartbeat = 30 * time.Second
+	// exportLease is how long a running job without a heartbeat is left alone before it is requeued
+	exportLease = 3 * exportHeartbeat
+	// exportFailedMessage is stored on failed jobs; the cause is only logged
+	exportFailedMessage = "Export failed, please try again"
+)
+
+type ExportJobRepositoryInterface interface {
+	Create(ctx context.Context, job *domain.ExportJob) error
+	GetById(ctx context.Context, id string) (domain.ExportJob, error)
+	GetByStatus(ctx context.Context, status domain.ExportJobStatus) ([]domain.ExportJob, error)
+	GetExpired(ctx context.Context, now time.Time) ([]domain.ExportJob, error)
+	Claim(ctx context.Context, id string) (bool, error)
+	Heartbeat(ctx context.Context, id string, now time.Time) error
+	Reclaim(ctx context.Context, staleBefore time.Time) (int64, error)
+	Update(ctx context.Context, job *domain.ExportJob) error
+}
+
+func NewExportJobUsecase(exportJobRepo ExportJobRepositoryInterface, dashboard *DashboardUseCase, cfg ExportJobConfig, logger *slog.Logger) *ExportJobUsecase {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	return &ExportJobUsecase{
+		ExportJobRepo: exportJobRepo,
+		Dashboard:     dashboard,
+		Config:        cfg,
+		Logger:        logger,
+		queue:         make(chan string, 100),
+	}
+}
+
+// Start launches the workers and the sweeper that requeues pending and abandoned jobs and deletes expired files.
+// The workers run their queries with ctx.
+func (u *ExportJobUsecase) Start(ctx context.Context) error {
+	if err := os.MkdirAll(u.Config.Dir, 0o750); err != nil {
+		return fmt.Errorf("error creating export directory: %w", err)
+	}
+
+	u.workers.Add(u.Config.Workers + 1)
+	for i := 0; i < u.Config.Workers; i++ {
+		go u.work(ctx)
+	}
+	go u.sweep(ctx)
+
+	return nil
+}
+
+// Wait blocks until the workers and the sweeper have stopped after the context given to Start is cancelled.
+// A job interrupted this way stays running in the database and is retried once its lease expires.
+func (u *ExportJobUsecase) Wait() {
+	u.workers.Wait()
+}
+
+// Submit validates and stores a new export job, then queues it.
+func (u *ExportJobUsecase) Submit(ctx context.Context, requestedBy string, req domain.ExportJobRequest) (domain.ExportJob, error) {
+	ctx, span := tracing.Start(ctx, "ExportJobUsecase.Submit")
+	defer span.End()
+
+	format, err := export.ParseFormat(req.Format)
+	if err != nil {
+		return domain.ExportJob{}, err
+	}
+	if _, err := export.ParseColumns(req.Columns); err != nil {
+		return domain.ExportJob{}, err
+	}
+	by := domain.FacultyListBasis(req.By)
+	if req.Faculty != "" && by == "" {
+		by = domain.ByInterest
+	}
+	if by != "" && by != domain.ByInterest && by != domain.ByVisit {
+		return domain.ExportJob{}, domain.ErrInvalidFacultyListBasis
+	}
+
+	job := domain.ExportJob{
+		ID:          utils.GenerateUID(),
+		RequestedBy: requestedBy,
+		Status:      domain.ExportJobPending,
+		Format:      string(format),
+		Columns:     req.Columns,
+		Faculty:     req.Faculty,
+		By:          by,
+		CreatedAt:   time.Now(),
+	}
+	if err := u.ExportJobRepo.Create(ctx, &job); err != nil {
+		return domain.ExportJob{}, fmt.Errorf("error saving export job: %w", err)
+	}
+
+	u.enqueue(job.ID)
+	return job, nil
+}
+
+// Get returns a job with a fresh download link if it has finished. Only admins and the requester, while they may
+// still export the job's faculty, can see a job; anyone else gets ErrExportJobNotFound before a link is signed.
+func (u *ExportJobUsecase) Get(ctx context.Context, id string, user domain.User) (domain.ExportJobResponse, error) {
+	ctx, span := tracing.Start(ctx, "ExportJobUsecase.Get")
+	defer span.End()
+
+	job, err := u.ExportJobRepo.GetById(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.ExportJobResponse{}, domain.ErrExportJobNotFound
+		}
+		return domain.ExportJobResponse{}, err
+	}
+	if !canViewExportJob(user, job) {
+		return domain.ExportJobResponse{}, domain.ErrExportJobNotFound
+	}
+
+	response := domain.ExportJobResponse{ExportJob: job}
+	if job.Status == domain.ExportJobDone {
+		expires := time.Now().Add(u.Config.LinkTTL)
+		if job.ExpiresAt != nil && job.ExpiresAt.Before(expires) {
+			expires = *job.ExpiresAt
+		}
+		keyID, secret := u.Config.LinkKeys.Active()
+		url := fmt.Sprintf("%s/api/exports/%s/download?expires=%d&kid=%s&signature=%s",
+			u.Config.BaseURL, job.ID, expires.Unix(), keyID, utils.SignExpiring(secret, job.ID, expires))
+		response.DownloadURL = &url
+	}
+	return response, nil
+}
+
+// canViewExportJob applies the rules of Submit again, as the requester may have changed faculty or role since
+func canViewExportJob(user domain.User, job domain.ExportJob) bool {
+	if user.Role == domain.Admin {
+		return true
+	}
+	if job.RequestedBy != user.ID {
+		return false
+	}
+	if faculty, scoped := user.FacultyScope(); scoped && job.Faculty != faculty {
+		return false
+	}
+	if job.Faculty == "" {
+		return user.HasPermission(domain.PermissionExportStudents)
+	}
+	return user.HasPermission(domain.PermissionExportFacultyStudents)
+}
+
+// Download verifies a signed link made with any accepted link key and returns the job whose file it points to.
+func (u *ExportJobUsecase) Download(ctx context.Context, id string, keyID string, expires int64, signature string) (domain.ExportJob, error) {
+	ctx, span := tracing.Start(ctx, "ExportJobUsecase.Download")
+	defer span.End()
+
+	secret, ok := u.Config.LinkKeys.Secret(keyID)
+	if !ok || !utils.VerifyExpiring(secret, id, expires, signature, time.Now()) {
+		return domain.ExportJob{}, domain.ErrInvalidDownloadLink
+	}
+
+	job, err := u.ExportJobRepo.GetById(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.ExportJob{}, domain.ErrExportJobNotFound
+		}
+		return domain.ExportJob{}, err
+	}
+	if job.Status != domain.ExportJobDone {
+		return domain.ExportJob{}, domain.ErrExportJobNotReady
+	}
+	if _, err := os.Stat(job.FilePath); err != nil {
+		// EXPORT_DIR is not shared with the replica that wrote the file, or the file was removed by hand
+		u.Logger.ErrorContext(ctx, "Export file is missing", "jobId", job.ID, "path", job.FilePath, "error", err)
+		return domain.ExportJob{}, domain.ErrExportJobNotReady
+	}
+	return job, nil
+}
+
+// FileName is the name a finished export is downloaded as
+func (u *ExportJobUsecase) FileName(job domain.ExportJob) string {
+	return "students_export_" + job.ID + "." + job.Format
+}
+
+func (u *ExportJobUsecase) enqueue(id string) {
+	select {
+	case u.queue <- id:
+	default:
+		// Queue is full, the sweeper will pick the job up later
+	}
+}
+
+func (u *ExportJobUsecase) work(ctx context.Context) {
+	defer u.workers.Done()
+
+	for {
+		var id string
+		select {
+		case <-ctx.Done():
+			return
+		case id = <-u.queue:
+		}
+
+		claimed, err := u.ExportJobRepo.Claim(ctx, id)
+		if err != nil {
+			u.Logger.ErrorContext(ctx, "Failed to claim export job", "jobId", id, "error", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		u.run(ctx, id)
+	}
+}
+
+func (u *ExportJobUsecase) run(ctx context.Context, id string) {
+	// Each job is its own trace, as the request that submitted it is long gone
+	ctx, span := tracing.Start(ctx, "ExportJobUsecase.run", attribute.String("export.job_id", id))
+	var runErr error
+	defer func() { tracing.End(span, runErr) }()
+
+	job, err := u.ExportJobRepo.GetById(ctx, id)
+	if err != nil {
+		u.Logger.ErrorContext(ctx, "Failed to load export job", "jobId", id, "error", err)
+		return
+	}
+
+	// Keep the lease while the file is written so the sweeper does not hand the job to another worker
+	stopHeartbeat := u.heartbeat(ctx, id)
+	path := filepath.Join(u.Config.Dir, job.ID+"."+job.Format)
+	runErr = u.writeFile(ctx, job, path)
+	stopHeartbeat()
+
+	now := time.Now()
+	expires := now.Add(u.Config.Retention)
+	job.CompletedAt = &now
+	job.ExpiresAt = &expires
+	if runErr != nil {
+		// The cause may name tables or paths, so only the log gets it
+		u.Logger.ErrorContext(ctx, "Export job failed", "jobId", id, "error", runErr)
+		msg := exportFailedMessage
+		job.Status = domain.ExportJobFailed
+		job.Error = &msg
+		os.Remove(path)
+	} else {
+		job.Status = domain.ExportJobDone
+		job.FilePath = path
+	}
+
+	if err := u.ExportJobRepo.Update(ctx, &job); err != nil {
+		u.Logger.ErrorContext(ctx, "Failed to update export job", "jobId", id, "error", err)
+	}
+}
+
+// heartbeat renews the lease on a running job until the returned function is called
+func (u *ExportJobUsecase) heartbeat(ctx context.Context, id string) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(exportHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := u.ExportJobRepo.Heartbeat(ctx, id, now); err != nil {
+					u.Logger.ErrorContext(ctx, "Failed to renew export job lease", "jobId", id, "error", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func (u *ExportJobUsecase) writeFile(ctx context.Context, job domain.ExportJob, path string) error {
+	format, err := export.ParseFormat(job.Format)
+	if err != nil {
+		return err
+	}
+	columns, err := export.ParseColumns(job.Columns)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	filter := domain.StudentExportFilter{Faculty: job.Faculty, By: job.By}
+	if err := u.Dashboard.ExportStudents(ctx, f, filter, format, columns); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// sweep periodically requeues pending jobs, expires finished jobs past their retention period and deletes their files
+func (u *ExportJobUsecase) sweep(ctx context.Context) {
+	defer u.workers.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		u.sweepOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (u *ExportJobUsecase) sweepOnce(ctx context.Context) {
+	reclaimed, err := u.ExportJobRepo.Reclaim(ctx, time.Now().Add(-exportLease))
+	if err != nil {
+		u.Logger.ErrorContext(ctx, "Failed to reclaim abandoned export jobs", "error", err)
+	} else if reclaimed > 0 {
+		u.Logger.WarnContext(ctx, "Requeued abandoned export jobs", "count", reclaimed)
+	}
+
+	pending, err := u.ExportJobRepo.GetByStatus(ctx, domain.ExportJobPending)
+	if err != nil {
+		u.Logger.ErrorContext(ctx, "Failed to list pending export jobs", "error", err)
+	}
+	for _, job := range pending {
+		u.enqueue(job.ID)
+	}
+
+	expired, err := u.ExportJobRepo.GetExpired(ctx, time.Now())
+	if err != nil {
+		u.Logger.ErrorContext(ctx, "Failed to list expired export jobs", "error", err)
+		return
+	}
+	for _, job := range expired {
+		if job.FilePath != "" {
+			if err := os.Remove(job.FilePath); err != nil && !os.IsNotExist(err) {
+				u.Logger.ErrorContext(ctx, "Failed to delete export file", "jobId", job.ID, "path", job.FilePath, "error", err)
+				continue
+			}
+		}
+		job.Status = domain.ExportJobExpired
+		job.FilePath = ""
+		if err := u.ExportJobRepo.Update(ctx, &job); err != nil {
+			u.Logger.ErrorContext(ctx, "Failed to expire export job", "jobId", job.ID, "error", err)
+		}
+	}
+	u.removeStaleFiles(ctx)
+}
+
+// removeStaleFiles deletes the files in the export directory whose job has expired, failed or is gone. Another
+// replica may have expired the job without seeing the file, so every replica cleans up its own directory.
+func (u *ExportJobUsecase) removeStaleFiles(ctx context.Context) {
+	entries, err := os.ReadDir(u.Config.Dir)
+	if err != nil {
+		u.Logger.ErrorContext(ctx, "Failed to list export files", "dir", u.Config.Dir, "error", err)
+		return
+	}
+
+	now := time.Now()
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		id := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		job, err := u.ExportJobRepo.GetById(ctx, id)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			u.Logger.ErrorContext(ctx, "Failed to load export job", "jobId", id, "error", err)
+			continue
+		}
+		stale := err != nil ||
+			job.Status == domain.ExportJobExpired ||
+			job.Status == domain.ExportJobFailed ||
+			(job.Status == domain.ExportJobDone && job.ExpiresAt != nil && job.ExpiresAt.Before(now))
+		if !stale {
+			continue
+		}
+		path := filepath.Join(u.Config.Dir, entry.Name())
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			u.Logger.ErrorContext(ctx, "Failed to delete export file", "jobId", id, "path", path, "error", err)
+		}
+	}
+}
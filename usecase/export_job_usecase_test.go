@@ -0,0 +1,274 @@
+package usecase
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isd-sgcu/oph-67-backend/config"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/keyring"
+	"github.com/isd-sgcu/oph-67-backend/utils"
+	"gorm.io/gorm"
+)
+
+// fakeExportJobRepository keeps jobs in memory and records the lease cutoff of the last reclaim
+type fakeExportJobRepository struct {
+	jobs        map[string]domain.ExportJob
+	staleBefore time.Time
+}
+
+func (r *fakeExportJobRepository) Create(ctx context.Context, job *domain.ExportJob) error {
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+func (r *fakeExportJobRepository) GetById(ctx context.Context, id string) (domain.ExportJob, error) {
+	job, ok := r.jobs[id]
+	if !ok {
+		return domain.ExportJob{}, gorm.ErrRecordNotFound
+	}
+	return job, nil
+}
+
+func (r *fakeExportJobRepository) GetByStatus(ctx context.Context, status domain.ExportJobStatus) ([]domain.ExportJob, error) {
+	return nil, nil
+}
+
+func (r *fakeExportJobRepository) GetExpired(ctx context.Context, now time.Time) ([]domain.ExportJob, error) {
+	return nil, nil
+}
+
+func (r *fakeExportJobRepository) Claim(ctx context.Context, id string) (bool, error) {
+	return true, nil
+}
+
+func (r *fakeExportJobRepository) Heartbeat(ctx context.Context, id string, now time.Time) error {
+	return nil
+}
+
+func (r *fakeExportJobRepository) Reclaim(ctx context.Context, staleBefore time.Time) (int64, error) {
+	r.staleBefore = staleBefore
+	return 0, nil
+}
+
+func (r *fakeExportJobRepository) Update(ctx context.Context, job *domain.ExportJob) error {
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+// failingDashboardRepository fails every export; its other methods are not used by export jobs
+type failingDashboardRepository struct {
+	DashBoardRepositoryInterface
+}
+
+func (r failingDashboardRepository) StreamStudents(ctx context.Context, filter domain.StudentExportFilter, fn func(row *domain.StudentExportRow) error) error {
+	return errors.New(`pq: relation "users" does not exist`)
+}
+
+func testLinkKeys(t *testing.T, previous string) *keyring.HMACKeys {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := keyring.Load(config.KeysConfig{
+		JWTSecret:        "0123456789abcdef0123456789abcdef",
+		JWTKeyID:         "k1",
+		CertPrivateKey:   base64.RawURLEncoding.EncodeToString(private),
+		CertKeyID:        "k1",
+		LinkSecret:       "fedcba9876543210fedcba9876543210",
+		LinkKeyID:        "k2",
+		LinkPreviousKeys: previous,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys.Link
+}
+
+func newTestExportJobUsecase(t *testing.T, repo *fakeExportJobRepository) *ExportJobUsecase {
+	t.Helper()
+	return NewExportJobUsecase(repo, NewDashBoardUseCase(failingDashboardRepository{}), ExportJobConfig{
+		Dir:       t.TempDir(),
+		Retention: time.Hour,
+		LinkTTL:   time.Minute,
+		LinkKeys:  testLinkKeys(t, "k1=abcdefabcdefabcdefabcdefabcdefab"),
+		BaseURL:   "http://localhost",
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestExportJobFailureHidesCause(t *testing.T) {
+	repo := &fakeExportJobRepository{jobs: map[string]domain.ExportJob{
+		"job": {ID: "job", Status: domain.ExportJobRunning, Format: "csv"},
+	}}
+	u := newTestExportJobUsecase(t, repo)
+
+	u.run(context.Background(), "job")
+
+	job := repo.jobs["job"]
+	if job.Status != domain.ExportJobFailed {
+		t.Fatalf("status %q, want failed", job.Status)
+	}
+	if job.Error == nil || *job.Error != exportFailedMessage {
+		t.Errorf("error %v, want %q", job.Error, exportFailedMessage)
+	}
+}
+
+// writeExportFile creates the file of a finished job in the export directory and returns its path
+func writeExportFile(t *testing.T, u *ExportJobUsecase, name string) string {
+	t.Helper()
+	path := filepath.Join(u.Config.Dir, name)
+	if err := os.WriteFile(path, []byte("id\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExportDownloadAcceptsPreviousLinkKeys(t *testing.T) {
+	repo := &fakeExportJobRepository{jobs: map[string]domain.ExportJob{}}
+	u := newTestExportJobUsecase(t, repo)
+	repo.jobs["job"] = domain.ExportJob{ID: "job", Status: domain.ExportJobDone, Format: "csv", FilePath: writeExportFile(t, u, "job.csv")}
+	expires := time.Now().Add(time.Minute)
+
+	tests := []struct {
+		name   string
+		keyID  string
+		secret string
+		valid  bool
+	}{
+		{"active key", "k2", "fedcba9876543210fedcba9876543210", true},
+		{"previous key", "k1", "abcdefabcdefabcdefabcdefabcdefab", true},
+		{"unknown key", "k3", "fedcba9876543210fedcba9876543210", false},
+		{"no key ID", "", "fedcba9876543210fedcba9876543210", false},
+		{"JWT secret", "k2", "0123456789abcdef0123456789abcdef", false},
+	}
+	for _, tt := range tests {
+		signature := utils.SignExpiring(tt.secret, "job", expires)
+		_, err := u.Download(context.Background(), "job", tt.keyID, expires.Unix(), signature)
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("%s: valid %v, want %v (error %v)", tt.name, valid, tt.valid, err)
+		}
+	}
+}
+
+func TestExportDownloadWithoutFileIsNotReady(t *testing.T) {
+	repo := &fakeExportJobRepository{jobs: map[string]domain.ExportJob{}}
+	u := newTestExportJobUsecase(t, repo)
+	// Written by a replica that does not share this one's export directory
+	repo.jobs["job"] = domain.ExportJob{ID: "job", Status: domain.ExportJobDone, Format: "csv", FilePath: filepath.Join(u.Config.Dir, "job.csv")}
+
+	expires := time.Now().Add(time.Minute)
+	signature := utils.SignExpiring("fedcba9876543210fedcba9876543210", "job", expires)
+	if _, err := u.Download(context.Background(), "job", "k2", expires.Unix(), signature); !errors.Is(err, domain.ErrExportJobNotReady) {
+		t.Errorf("error %v, want %v", err, domain.ErrExportJobNotReady)
+	}
+}
+
+func TestExportJobLinkNamesActiveKey(t *testing.T) {
+	repo := &fakeExportJobRepository{jobs: map[string]domain.ExportJob{
+		"job": {ID: "job", RequestedBy: "admin", Status: domain.ExportJobDone, Format: "csv"},
+	}}
+	u := newTestExportJobUsecase(t, repo)
+
+	job, err := u.Get(context.Background(), "job", domain.User{ID: "admin", Role: domain.Admin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.DownloadURL == nil || !strings.Contains(*job.DownloadURL, "&kid=k2&") {
+		t.Errorf("download URL %v does not name the active link key", job.DownloadURL)
+	}
+}
+
+func TestExportSweepOnlyReclaimsExpiredLeases(t *testing.T) {
+	repo := &fakeExportJobRepository{jobs: map[string]domain.ExportJob{}}
+	u := newTestExportJobUsecase(t, repo)
+
+	before := time.Now()
+	u.sweepOnce(context.Background())
+
+	if cutoff := before.Add(-exportLease); repo.staleBefore.Before(cutoff) || repo.staleBefore.After(time.Now().Add(-exportLease)) {
+		t.Errorf("reclaimed jobs renewed before %v, want %v", repo.staleBefore, cutoff)
+	}
+}
+
+func TestExportJobGetOnlySignsForAllowedUsers(t *testing.T) {
+	repo := &fakeExportJobRepository{jobs: map[string]domain.ExportJob{
+		"all":         {ID: "all", RequestedBy: "central", Status: domain.ExportJobDone, Format: "csv"},
+		"engineering": {ID: "engineering", RequestedBy: "faculty", Status: domain.ExportJobDone, Format: "csv", Faculty: "engineering"},
+		"arts":        {ID: "arts", RequestedBy: "faculty", Status: domain.ExportJobDone, Format: "csv", Faculty: "arts"},
+	}}
+	u := newTestExportJobUsecase(t, repo)
+
+	admin := domain.User{ID: "admin", Role: domain.Admin}
+	central := domain.User{ID: "central", Role: domain.Staff, IsCentralStaff: ptr(true)}
+	faculty := domain.User{ID: "faculty", Role: domain.Staff, Faculty: ptr("engineering")}
+	demoted := domain.User{ID: "central", Role: domain.Member}
+	tests := []struct {
+		name  string
+		job   string
+		user  domain.User
+		allow bool
+	}{
+		{"admin sees any job", "engineering", admin, true},
+		{"requester sees their export", "all", central, true},
+		{"central staff cannot see another requester's job", "engineering", central, false},
+		{"faculty staff see their faculty's export", "engineering", faculty, true},
+		{"faculty staff moved away from the job's faculty", "arts", faculty, false},
+		{"requester who lost the export permission", "all", demoted, false},
+		{"unknown job", "missing", admin, false},
+	}
+	for _, tt := range tests {
+		job, err := u.Get(context.Background(), tt.job, tt.user)
+		if tt.allow {
+			if err != nil || job.DownloadURL == nil {
+				t.Errorf("%s: link %v, error %v; want a link", tt.name, job.DownloadURL, err)
+			}
+			continue
+		}
+		if !errors.Is(err, domain.ErrExportJobNotFound) || job.DownloadURL != nil {
+			t.Errorf("%s: link %v, error %v; want %v and no link", tt.name, job.DownloadURL, err, domain.ErrExportJobNotFound)
+		}
+	}
+}
+
+func TestExportSweepDeletesFilesOfJobsExpiredElsewhere(t *testing.T) {
+	repo := &fakeExportJobRepository{jobs: map[string]domain.ExportJob{}}
+	u := newTestExportJobUsecase(t, repo)
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+
+	// Another replica marked this job expired and cleared its path without seeing the file
+	repo.jobs["expired"] = domain.ExportJob{ID: "expired", Status: domain.ExportJobExpired, Format: "csv"}
+	repo.jobs["failed"] = domain.ExportJob{ID: "failed", Status: domain.ExportJobFailed, Format: "csv"}
+	repo.jobs["overdue"] = domain.ExportJob{ID: "overdue", Status: domain.ExportJobDone, Format: "csv", ExpiresAt: &past}
+	repo.jobs["done"] = domain.ExportJob{ID: "done", Status: domain.ExportJobDone, Format: "csv", ExpiresAt: &future}
+	repo.jobs["running"] = domain.ExportJob{ID: "running", Status: domain.ExportJobRunning, Format: "xlsx"}
+	for _, name := range []string{"expired.csv", "failed.csv", "overdue.csv", "deleted.jsonl", "done.csv", "running.xlsx"} {
+		writeExportFile(t, u, name)
+	}
+
+	u.sweepOnce(context.Background())
+
+	for name, kept := range map[string]bool{
+		"expired.csv": false, "failed.csv": false, "overdue.csv": false, "deleted.jsonl": false,
+		"done.csv": true, "running.xlsx": true,
+	} {
+		_, err := os.Stat(filepath.Join(u.Config.Dir, name))
+		if exists := err == nil; exists != kept {
+			t.Errorf("%s exists %v, want %v", name, exists, kept)
+		}
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,228 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/tracing"
+	"gorm.io/gorm"
+)
+
+// FacultySurveyUsecase handles the short surveys faculties run for students scanned at their booth
+type FacultySurveyUsecase struct {
+	SurveyRepo             FacultySurveyRepositoryInterface
+	StudentTransactionRepo StudentTransactionRepositoryInterface
+	Questionnaires         *QuestionnaireUsecase
+}
+
+type FacultySurveyRepositoryInterface interface {
+	CreateResponse(ctx context.Context, response *domain.FacultySurveyResponse) error
+	GetRespondedQuestionnaireIDs(ctx context.Context, studentId string) ([]int, error)
+	HasResponded(ctx context.Context, studentId string, questionnaireId int) (bool, error)
+	GetResponses(ctx context.Context, questionnaireId int) ([]domain.FacultySurveyResponse, error)
+}
+
+func NewFacultySurveyUsecase(surveyRepo FacultySurveyRepositoryInterface, studentTransactionRepo StudentTransactionRepositoryInterface, questionnaires *QuestionnaireUsecase) *FacultySurveyUsecase {
+	return &FacultySurveyUsecase{
+		SurveyRepo:             surveyRepo,
+		StudentTransactionRepo: studentTransactionRepo,
+		Questionnaires:         questionnaires,
+	}
+}
+
+// GetPending returns the active surveys of every faculty the student was scanned at and has not answered yet
+func (u *FacultySurveyUsecase) GetPending(ctx context.Context, studentId string) ([]domain.PendingSurvey, error) {
+	ctx, span := tracing.Start(ctx, "FacultySurveyUsecase.GetPending")
+	defer span.End()
+
+	transactions, err := u.StudentTransactionRepo.GetByStudentId(ctx, studentId)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	faculties := []string{}
+	for _, t := range transactions {
+		if !seen[t.Faculty] {
+			seen[t.Faculty] = true
+			faculties = append(faculties, t.Faculty)
+		}
+	}
+
+	questionnaires, err := u.Questionnaires.GetActiveByFaculties(ctx, faculties)
+	if err != nil {
+		return nil, err
+	}
+	responded, err := u.SurveyRepo.GetRespondedQuestionnaireIDs(ctx, studentId)
+	if err != nil {
+		return nil, err
+	}
+	answered := make(map[int]bool, len(responded))
+	for _, id := range responded {
+		answered[id] = true
+	}
+
+	pending := []domain.PendingSurvey{}
+	for _, q := range questionnaires {
+		if !answered[q.ID] {
+			pending = append(pending, domain.PendingSurvey{Faculty: *q.Faculty, Questionnaire: q})
+		}
+	}
+	return pending, nil
+}
+
+// Submit validates and stores a student's answers to a faculty survey. Only the active version can be answered,
+// only by students scanned at that faculty, and only once.
+func (u *FacultySurveyUsecase) Submit(ctx context.Context, studentId string, questionnaireId int, raw map[string]json.RawMessage) (*domain.FacultySurveyResponse, error) {
+	ctx, span := tracing.Start(ctx, "FacultySurveyUsecase.Submit")
+	defer span.End()
+
+	questionnaire, err := u.Questionnaires.GetById(ctx, questionnaireId)
+	if err != nil {
+		return nil, err
+	}
+	if questionnaire.Faculty == nil || !questionnaire.IsActive {
+		return nil, domain.ErrQuestionnaireNotFound
+	}
+	faculty := *questionnaire.Faculty
+
+	transactions, err := u.StudentTransactionRepo.GetByStudentIdAndFaculty(ctx, studentId, faculty)
+	if err != nil {
+		return nil, err
+	}
+	if len(transactions) == 0 {
+		return nil, domain.ErrSurveyNotEligible
+	}
+
+	responded, err := u.SurveyRepo.HasResponded(ctx, studentId, questionnaire.ID)
+	if err != nil {
+		return nil, err
+	}
+	if responded {
+		return nil, domain.ErrSurveyAlreadySubmitted
+	}
+
+	answers, err := ParseAnswers(questionnaire, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &domain.FacultySurveyResponse{
+		StudentId:       studentId,
+		QuestionnaireID: questionnaire.ID,
+		Faculty:         faculty,
+		Answers:         make([]domain.FacultySurveyAnswer, len(answers)),
+	}
+	for i, a := range answers {
+		response.Answers[i] = domain.FacultySurveyAnswer{
+			QuestionID:  a.QuestionID,
+			QuestionKey: a.QuestionKey,
+			Score:       a.Score,
+			Text:        a.Text,
+			Choices:     a.Choices,
+		}
+	}
+	if err := u.SurveyRepo.CreateResponse(ctx, response); err != nil {
+		// A concurrent submission won the unique index on (student_id, questionnaire_id)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, domain.ErrSurveyAlreadySubmitted
+		}
+		return nil, err
+	}
+	return response, nil
+}
+
+// GetQuestionnaires returns every version of a faculty's survey
+func (u *FacultySurveyUsecase) GetQuestionnaires(ctx context.Context, faculty string) ([]domain.Questionnaire, error) {
+	ctx, span := tracing.Start(ctx, "FacultySurveyUsecase.GetQuestionnaires")
+	defer span.End()
+
+	return u.Questionnaires.GetByFaculty(ctx, faculty)
+}
+
+// CreateQuestionnaire stores a new, inactive version of a faculty's survey
+func (u *FacultySurveyUsecase) CreateQuestionnaire(ctx context.Context, faculty string, questionnaire *domain.Questionnaire) error {
+	ctx, span := tracing.Start(ctx, "FacultySurveyUsecase.CreateQuestionnaire")
+	defer span.End()
+
+	questionnaire.Faculty = &faculty
+	questionnaire.RequireAttendance = false // eligibility comes from the faculty's own scans
+	return u.Questionnaires.Create(ctx, questionnaire)
+}
+
+// Activate makes a version the faculty's active survey
+func (u *FacultySurveyUsecase) Activate(ctx context.Context, faculty string, questionnaireId int) error {
+	ctx, span := tracing.Start(ctx, "FacultySurveyUsecase.Activate")
+	defer span.End()
+
+	if _, err := u.getOwned(ctx, faculty, questionnaireId); err != nil {
+		return err
+	}
+	return u.Questionnaires.Activate(ctx, questionnaireId)
+}
+
+// GetResults returns the responses to one version of a faculty's survey with rating statistics
+func (u *FacultySurveyUsecase) GetResults(ctx context.Context, faculty string, questionnaireId int) (domain.FacultySurveyResults, error) {
+	ctx, span := tracing.Start(ctx, "FacultySurveyUsecase.GetResults")
+	defer span.End()
+
+	questionnaire, err := u.getOwned(ctx, faculty, questionnaireId)
+	if err != nil {
+		return domain.FacultySurveyResults{}, err
+	}
+	responses, err := u.SurveyRepo.GetResponses(ctx, questionnaire.ID)
+	if err != nil {
+		return domain.FacultySurveyResults{}, err
+	}
+
+	// question -> score -> count
+	distributions := make(map[string]map[int]int)
+	for _, q := range questionnaire.Questions {
+		if q.Type == domain.QuestionRating {
+			distributions[q.Key] = make(map[int]int)
+		}
+	}
+	for _, response := range responses {
+		for _, a := range response.Answers {
+			if distribution, ok := distributions[a.QuestionKey]; ok && a.Score != nil {
+				distribution[*a.Score]++
+			}
+		}
+	}
+	questions := make([]string, 0, len(distributions))
+	for question := range distributions {
+		questions = append(questions, question)
+	}
+	sort.Strings(questions)
+
+	results := domain.FacultySurveyResults{
+		QuestionnaireID: questionnaire.ID,
+		Version:         questionnaire.Version,
+		Title:           questionnaire.Title,
+		Faculty:         faculty,
+		Responses:       len(responses),
+		Questions:       make([]domain.QuestionStats, 0, len(questions)),
+		Answers:         responses,
+	}
+	if results.Answers == nil {
+		results.Answers = []domain.FacultySurveyResponse{}
+	}
+	for _, question := range questions {
+		results.Questions = append(results.Questions, questionStats(question, distributions[question]))
+	}
+	return results, nil
+}
+
+// getOwned returns a questionnaire if it belongs to the faculty. Other faculties' surveys are reported as not found.
+func (u *FacultySurveyUsecase) getOwned(ctx context.Context, faculty string, questionnaireId int) (domain.Questionnaire, error) {
+	questionnaire, err := u.Questionnaires.GetById(ctx, questionnaireId)
+	if err != nil {
+		return domain.Questionnaire{}, err
+	}
+	if questionnaire.Faculty == nil || *questionnaire.Faculty != faculty {
+		return domain.Questionnaire{}, domain.ErrQuestionnaireNotFound
+	}
+	return questionnaire, nil
+}
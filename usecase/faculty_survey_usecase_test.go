@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"gorm.io/gorm"
+)
+
+// fakeQuestionnaireRepository serves the questionnaires it holds and fails creations with err
+type fakeQuestionnaireRepository struct {
+	QuestionnaireRepositoryInterface
+	questionnaires map[int]domain.Questionnaire
+	err            error
+}
+
+func (r *fakeQuestionnaireRepository) Create(ctx context.Context, questionnaire *domain.Questionnaire) error {
+	return r.err
+}
+
+func (r *fakeQuestionnaireRepository) GetById(ctx context.Context, id int) (domain.Questionnaire, error) {
+	questionnaire, ok := r.questionnaires[id]
+	if !ok {
+		return domain.Questionnaire{}, gorm.ErrRecordNotFound
+	}
+	return questionnaire, nil
+}
+
+// racingSurveyRepository reports no response yet but loses the insert to a concurrent submission
+type racingSurveyRepository struct {
+	FacultySurveyRepositoryInterface
+}
+
+func (r racingSurveyRepository) HasResponded(ctx context.Context, studentId string, questionnaireId int) (bool, error) {
+	return false, nil
+}
+
+func (r racingSurveyRepository) CreateResponse(ctx context.Context, response *domain.FacultySurveyResponse) error {
+	return gorm.ErrDuplicatedKey
+}
+
+// scannedEverywhere reports a booth scan at every faculty
+type scannedEverywhere struct {
+	StudentTransactionRepositoryInterface
+}
+
+func (r scannedEverywhere) GetByStudentIdAndFaculty(ctx context.Context, studentId string, faculty string) ([]domain.StudentTransaction, error) {
+	return []domain.StudentTransaction{{Faculty: faculty}}, nil
+}
+
+func TestSurveySubmitRaceIsAlreadySubmitted(t *testing.T) {
+	faculty := "engineering"
+	questionnaires := &fakeQuestionnaireRepository{questionnaires: map[int]domain.Questionnaire{
+		1: {ID: 1, Version: 1, Faculty: &faculty, IsActive: true, Questions: []domain.Question{
+			{ID: 1, Key: "comment", Type: domain.QuestionText},
+		}},
+	}}
+	u := NewFacultySurveyUsecase(racingSurveyRepository{}, scannedEverywhere{}, NewQuestionnaireUsecase(questionnaires))
+
+	_, err := u.Submit(context.Background(), "student", 1, map[string]json.RawMessage{"comment": json.RawMessage(`"fun"`)})
+	if !errors.Is(err, domain.ErrSurveyAlreadySubmitted) {
+		t.Errorf("got %v, want %v", err, domain.ErrSurveyAlreadySubmitted)
+	}
+}
+
+func TestQuestionnaireCreateVersionCollisionIsConflict(t *testing.T) {
+	u := NewQuestionnaireUsecase(&fakeQuestionnaireRepository{err: gorm.ErrDuplicatedKey})
+	questionnaire := domain.DefaultQuestionnaire()
+
+	if err := u.Create(context.Background(), &questionnaire); !errors.Is(err, domain.ErrQuestionnaireVersionConflict) {
+		t.Errorf("got %v, want %v", err, domain.ErrQuestionnaireVersionConflict)
+	}
+}
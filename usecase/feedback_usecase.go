@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/textanalysis"
+	"github.com/isd-sgcu/oph-67-backend/tracing"
+	"gorm.io/gorm"
+)
+
+// FeedbackUsecase analyses and organises free-text evaluation answers
+type FeedbackUsecase struct {
+	FeedbackRepo FeedbackRepositoryInterface
+}
+
+type FeedbackRepositoryInterface interface {
+	GetTextAnswers(ctx context.Context, question string, tag string, search string, limit int, offset int) ([]domain.TextAnswer, int64, error)
+	GetTexts(ctx context.Context, question string) ([]string, error)
+	GetTextCounts(ctx context.Context, question string) ([]domain.SpellingCount, error)
+	SetAnswerTags(ctx context.Context, id int, tags []string) error
+	GetBooths(ctx context.Context) ([]domain.Booth, error)
+	CreateBooth(ctx context.Context, booth *domain.Booth) error
+}
+
+func NewFeedbackUsecase(feedbackRepo FeedbackRepositoryInterface) *FeedbackUsecase {
+	return &FeedbackUsecase{FeedbackRepo: feedbackRepo}
+}
+
+// GetTextAnswers lists free-text answers to a question for review
+func (u *FeedbackUsecase) GetTextAnswers(ctx context.Context, question string, tag string, search string, limit int, offset int) (domain.TextAnswerPage, error) {
+	ctx, span := tracing.Start(ctx, "FeedbackUsecase.GetTextAnswers")
+	defer span.End()
+
+	answers, total, err := u.FeedbackRepo.GetTextAnswers(ctx, question, tag, search, limit, offset)
+	if err != nil {
+		return domain.TextAnswerPage{}, err
+	}
+	if answers == nil {
+		answers = []domain.TextAnswer{}
+	}
+	return domain.TextAnswerPage{Total: total, Answers: answers}, nil
+}
+
+// TagAnswer replaces the tags of an answer. Tags are trimmed, lower-cased and de-duplicated.
+func (u *FeedbackUsecase) TagAnswer(ctx context.Context, id int, tags []string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "FeedbackUsecase.TagAnswer")
+	defer span.End()
+
+	seen := make(map[string]bool)
+	cleaned := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		cleaned = append(cleaned, tag)
+	}
+	sort.Strings(cleaned)
+
+	if err := u.FeedbackRepo.SetAnswerTags(ctx, id, cleaned); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrAnswerNotFound
+		}
+		return nil, err
+	}
+	return cleaned, nil
+}
+
+// GetKeywords counts the most frequent keywords and two-word phrases in the answers to a question
+func (u *FeedbackUsecase) GetKeywords(ctx context.Context, question string, limit int) (textanalysis.Frequencies, error) {
+	ctx, span := tracing.Start(ctx, "FeedbackUsecase.GetKeywords")
+	defer span.End()
+
+	texts, err := u.FeedbackRepo.GetTexts(ctx, question)
+	if err != nil {
+		return textanalysis.Frequencies{}, err
+	}
+	return textanalysis.CountTerms(texts, limit), nil
+}
+
+func (u *FeedbackUsecase) GetBooths(ctx context.Context) ([]domain.Booth, error) {
+	ctx, span := tracing.Start(ctx, "FeedbackUsecase.GetBooths")
+	defer span.End()
+
+	return u.FeedbackRepo.GetBooths(ctx)
+}
+
+func (u *FeedbackUsecase) CreateBooth(ctx context.Context, booth *domain.Booth) error {
+	ctx, span := tracing.Start(ctx, "FeedbackUsecase.CreateBooth")
+	defer span.End()
+
+	return u.FeedbackRepo.CreateBooth(ctx, booth)
+}
+
+// GroupBoothAnswers groups the spellings given for a booth question under the catalog entry they most
+// likely refer to. Spellings that match no booth closely enough are returned as unmatched.
+func (u *FeedbackUsecase) GroupBoothAnswers(ctx context.Context, question string) (domain.BoothGroups, error) {
+	ctx, span := tracing.Start(ctx, "FeedbackUsecase.GroupBoothAnswers")
+	defer span.End()
+
+	booths, err := u.FeedbackRepo.GetBooths(ctx)
+	if err != nil {
+		return domain.BoothGroups{}, err
+	}
+	counts, err := u.FeedbackRepo.GetTextCounts(ctx, question)
+	if err != nil {
+		return domain.BoothGroups{}, err
+	}
+
+	entries := make([]textanalysis.CatalogEntry, len(booths))
+	groups := make(map[int]*domain.BoothGroup, len(booths))
+	for i, booth := range booths {
+		names := []string{booth.Name}
+		if booth.Aliases != nil {
+			names = append(names, *booth.Aliases...)
+		}
+		entries[i] = textanalysis.CatalogEntry{ID: booth.ID, Names: names}
+		groups[booth.ID] = &domain.BoothGroup{BoothID: booth.ID, Name: booth.Name, Variants: []domain.SpellingCount{}}
+	}
+	matcher := textanalysis.NewMatcher(entries)
+
+	result := domain.BoothGroups{Booths: []domain.BoothGroup{}, Unmatched: []domain.SpellingCount{}}
+	for _, count := range counts {
+		id, _, ok := matcher.Match(count.Text)
+		if !ok {
+			result.Unmatched = append(result.Unmatched, count)
+			continue
+		}
+		groups[id].Count += count.Count
+		groups[id].Variants = append(groups[id].Variants, count)
+	}
+
+	for _, booth := range booths {
+		if group := groups[booth.ID]; group.Count > 0 {
+			result.Booths = append(result.Booths, *group)
+		}
+	}
+	sort.SliceStable(result.Booths, func(i, j int) bool {
+		return result.Booths[i].Count > result.Booths[j].Count
+	})
+	return result, nil
+}
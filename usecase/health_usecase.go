@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/tracing"
+)
+
+// readinessTimeout bounds the dependency checks so a hanging database fails the probe instead of blocking it
+const readinessTimeout = 2 * time.Second
+
+// HealthUsecase answers the load balancer's liveness and readiness probes
+type HealthUsecase struct {
+	HealthRepo HealthRepositoryInterface
+
+	draining atomic.Bool
+}
+
+type HealthRepositoryInterface interface {
+	Ping(ctx context.Context) error
+	PendingMigrations(ctx context.Context) ([]string, error)
+}
+
+func NewHealthUsecase(healthRepo HealthRepositoryInterface) *HealthUsecase {
+	return &HealthUsecase{HealthRepo: healthRepo}
+}
+
+// SetDraining makes the instance report not ready while it shuts down, so no new traffic is routed to it
+func (u *HealthUsecase) SetDraining() {
+	u.draining.Store(true)
+}
+
+// Readiness checks that the database answers and every migration is applied. ready is false if any check failed.
+// The probe is public, so failed checks only read "unavailable" and the cause is logged.
+func (u *HealthUsecase) Readiness(ctx context.Context) (response domain.HealthResponse, ready bool) {
+	ctx, span := tracing.Start(ctx, "HealthUsecase.Readiness")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	checks := map[string]string{}
+	ready = true
+	fail := func(name string, reason string) {
+		slog.WarnContext(ctx, "Readiness check failed", "check", name, "reason", reason)
+		checks[name] = domain.HealthUnavailable
+		ready = false
+	}
+
+	if u.draining.Load() {
+		fail("server", "shutting down")
+	} else {
+		checks["server"] = domain.HealthOK
+	}
+
+	if err := u.HealthRepo.Ping(ctx); err != nil {
+		fail("database", err.Error())
+		fail("migrations", "database unavailable")
+	} else {
+		checks["database"] = domain.HealthOK
+		pending, err := u.HealthRepo.PendingMigrations(ctx)
+		switch {
+		case err != nil:
+			fail("migrations", err.Error())
+		case len(pending) > 0:
+			fail("migrations", fmt.Sprintf("pending: %s", strings.Join(pending, ", ")))
+		default:
+			checks["migrations"] = domain.HealthOK
+		}
+	}
+
+	response = domain.HealthResponse{Status: domain.HealthOK, Checks: checks}
+	if !ready {
+		response.Status = domain.HealthUnavailable
+	}
+	return response, ready
+}
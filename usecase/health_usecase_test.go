@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+)
+
+// fakeHealthRepository fails the ping with pingErr and reports pending as unapplied migrations
+type fakeHealthRepository struct {
+	pingErr error
+	pending []string
+}
+
+func (r fakeHealthRepository) Ping(ctx context.Context) error {
+	return r.pingErr
+}
+
+func (r fakeHealthRepository) PendingMigrations(ctx context.Context) ([]string, error) {
+	return r.pending, nil
+}
+
+func TestReadinessHidesFailureCauses(t *testing.T) {
+	tests := []struct {
+		name   string
+		repo   fakeHealthRepository
+		drain  bool
+		checks map[string]string
+	}{
+		{
+			name:   "ready",
+			checks: map[string]string{"server": domain.HealthOK, "database": domain.HealthOK, "migrations": domain.HealthOK},
+		},
+		{
+			name:   "database down",
+			repo:   fakeHealthRepository{pingErr: errors.New("dial tcp 10.0.0.5:5432: connect: connection refused")},
+			checks: map[string]string{"server": domain.HealthOK, "database": domain.HealthUnavailable, "migrations": domain.HealthUnavailable},
+		},
+		{
+			name:   "pending migrations",
+			repo:   fakeHealthRepository{pending: []string{"0009_questionnaire_versions_per_owner"}},
+			checks: map[string]string{"server": domain.HealthOK, "database": domain.HealthOK, "migrations": domain.HealthUnavailable},
+		},
+		{
+			name:   "draining",
+			drain:  true,
+			checks: map[string]string{"server": domain.HealthUnavailable, "database": domain.HealthOK, "migrations": domain.HealthOK},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := NewHealthUsecase(tt.repo)
+			if tt.drain {
+				u.SetDraining()
+			}
+			response, ready := u.Readiness(context.Background())
+
+			wantReady := tt.checks["server"] == domain.HealthOK && tt.checks["database"] == domain.HealthOK &&
+				tt.checks["migrations"] == domain.HealthOK
+			if ready != wantReady {
+				t.Errorf("ready = %v, want %v", ready, wantReady)
+			}
+			if len(response.Checks) != len(tt.checks) {
+				t.Errorf("checks = %v, want %v", response.Checks, tt.checks)
+			}
+			for name, want := range tt.checks {
+				if got := response.Checks[name]; got != want {
+					t.Errorf("check %s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
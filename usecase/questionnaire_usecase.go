@@ -0,0 +1,310 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/tracing"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// QuestionnaireUsecase manages the versions of the evaluation form
+type QuestionnaireUsecase struct {
+	QuestionnaireRepo QuestionnaireRepositoryInterface
+}
+
+type QuestionnaireRepositoryInterface interface {
+	Create(ctx context.Context, questionnaire *domain.Questionnaire) error
+	GetAll(ctx context.Context) ([]domain.Questionnaire, error)
+	GetById(ctx context.Context, id int) (domain.Questionnaire, error)
+	GetByVersion(ctx context.Context, version int) (domain.Questionnaire, error)
+	GetActive(ctx context.Context) (domain.Questionnaire, error)
+	GetByFaculty(ctx context.Context, faculty string) ([]domain.Questionnaire, error)
+	GetActiveByFaculties(ctx context.Context, faculties []string) ([]domain.Questionnaire, error)
+	GetLatestVersion(ctx context.Context, faculty *string) (int, error)
+	Activate(ctx context.Context, id int) error
+	SetRequireAttendance(ctx context.Context, id int, require bool) error
+}
+
+func NewQuestionnaireUsecase(questionnaireRepo QuestionnaireRepositoryInterface) *QuestionnaireUsecase {
+	return &QuestionnaireUsecase{QuestionnaireRepo: questionnaireRepo}
+}
+
+// EnsureDefault stores version 1 of the event-wide form if it has no version yet.
+func (u *QuestionnaireUsecase) EnsureDefault(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "QuestionnaireUsecase.EnsureDefault")
+	defer span.End()
+
+	latest, err := u.QuestionnaireRepo.GetLatestVersion(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if latest > 0 {
+		return nil
+	}
+	questionnaire := domain.DefaultQuestionnaire()
+	return u.QuestionnaireRepo.Create(ctx, &questionnaire)
+}
+
+func (u *QuestionnaireUsecase) GetAll(ctx context.Context) ([]domain.Questionnaire, error) {
+	ctx, span := tracing.Start(ctx, "QuestionnaireUsecase.GetAll")
+	defer span.End()
+
+	return u.QuestionnaireRepo.GetAll(ctx)
+}
+
+func (u *QuestionnaireUsecase) GetById(ctx context.Context, id int) (domain.Questionnaire, error) {
+	ctx, span := tracing.Start(ctx, "QuestionnaireUsecase.GetById")
+	defer span.End()
+
+	questionnaire, err := u.QuestionnaireRepo.GetById(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.Questionnaire{}, domain.ErrQuestionnaireNotFound
+	}
+	return questionnaire, err
+}
+
+func (u *QuestionnaireUsecase) GetActive(ctx context.Context) (domain.Questionnaire, error) {
+	ctx, span := tracing.Start(ctx, "QuestionnaireUsecase.GetActive")
+	defer span.End()
+
+	questionnaire, err := u.QuestionnaireRepo.GetActive(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.Questionnaire{}, domain.ErrQuestionnaireNotFound
+	}
+	return questionnaire, err
+}
+
+// GetByFaculty returns every version of a faculty's own survey
+func (u *QuestionnaireUsecase) GetByFaculty(ctx context.Context, faculty string) ([]domain.Questionnaire, error) {
+	ctx, span := tracing.Start(ctx, "QuestionnaireUsecase.GetByFaculty")
+	defer span.End()
+
+	return u.QuestionnaireRepo.GetByFaculty(ctx, faculty)
+}
+
+// GetActiveByFaculties returns the active survey of each faculty that has one
+func (u *QuestionnaireUsecase) GetActiveByFaculties(ctx context.Context, faculties []string) ([]domain.Questionnaire, error) {
+	ctx, span := tracing.Start(ctx, "QuestionnaireUsecase.GetActiveByFaculties")
+	defer span.End()
+
+	return u.QuestionnaireRepo.GetActiveByFaculties(ctx, faculties)
+}
+
+// Create stores a new, inactive version of the form. The repository numbers it after the newest version
+// of the same owner (the event, or the faculty).
+func (u *QuestionnaireUsecase) Create(ctx context.Context, questionnaire *domain.Questionnaire) error {
+	ctx, span := tracing.Start(ctx, "QuestionnaireUsecase.Create")
+	defer span.End()
+
+	if err := validateQuestionnaire(questionnaire); err != nil {
+		return err
+	}
+
+	questionnaire.ID = 0
+	if questionnaire.Faculty != nil && *questionnaire.Faculty == "" {
+		questionnaire.Faculty = nil
+	}
+	questionnaire.IsActive = false
+	for i := range questionnaire.Questions {
+		questionnaire.Questions[i].ID = 0
+		if questionnaire.Questions[i].Position == 0 {
+			questionnaire.Questions[i].Position = i + 1
+		}
+	}
+	err := u.QuestionnaireRepo.Create(ctx, questionnaire)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return domain.ErrQuestionnaireVersionConflict
+	}
+	return err
+}
+
+// Activate makes a version the one new evaluations are validated against
+func (u *QuestionnaireUsecase) Activate(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "QuestionnaireUsecase.Activate")
+	defer span.End()
+
+	err := u.QuestionnaireRepo.Activate(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.ErrQuestionnaireNotFound
+	}
+	return err
+}
+
+// SetRequireAttendance switches attendance gating for a version. Unlike the questions, it can change while the form is live.
+func (u *QuestionnaireUsecase) SetRequireAttendance(ctx context.Context, id int, require bool) error {
+	ctx, span := tracing.Start(ctx, "QuestionnaireUsecase.SetRequireAttendance")
+	defer span.End()
+
+	err := u.QuestionnaireRepo.SetRequireAttendance(ctx, id, require)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.ErrQuestionnaireNotFound
+	}
+	return err
+}
+
+func validateQuestionnaire(questionnaire *domain.Questionnaire) error {
+	verr := &domain.ValidationError{}
+	if len(questionnaire.Questions) == 0 {
+		verr.Add("questions", domain.FieldRequired, "at least one question is required")
+	}
+
+	seen := make(map[string]bool)
+	for i, q := range questionnaire.Questions {
+		field := fmt.Sprintf("questions[%d]", i)
+		switch {
+		case q.Key == "":
+			verr.Add(field+".key", domain.FieldRequired, "is required")
+		case seen[q.Key]:
+			verr.Add(field+".key", domain.FieldDuplicate, "duplicate key "+strconv.Quote(q.Key))
+		case isReservedQuestionKey(q.Key):
+			verr.Add(field+".key", domain.FieldReserved, "reserved key "+strconv.Quote(q.Key))
+		}
+		seen[q.Key] = true
+
+		if q.LabelTH == "" && q.LabelEN == "" {
+			verr.Add(field+".label", domain.FieldRequired, "a Thai or English label is required")
+		}
+
+		switch q.Type {
+		case domain.QuestionRating:
+			if q.ScaleMin == nil || q.ScaleMax == nil || *q.ScaleMin >= *q.ScaleMax {
+				verr.Add(field+".scale", domain.FieldInvalid, "rating questions need scaleMin < scaleMax")
+			}
+		case domain.QuestionSingleChoice:
+			if q.Options == nil || len(*q.Options) == 0 {
+				verr.Add(field+".options", domain.FieldRequired, "single choice questions need options")
+			}
+		case domain.QuestionText, domain.QuestionMultiChoice:
+		default:
+			verr.Add(field+".type", domain.FieldInvalidType, "unknown question type "+strconv.Quote(string(q.Type)))
+		}
+	}
+
+	if len(verr.Fields) > 0 {
+		return domain.ErrInvalidQuestionnaire.WithDetails(verr.Fields...)
+	}
+	return nil
+}
+
+// isReservedQuestionKey ignores case, as encoding/json matches field names case-insensitively
+func isReservedQuestionKey(key string) bool {
+	for _, reserved := range domain.ReservedQuestionKeys {
+		if strings.EqualFold(key, reserved) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseAnswers validates raw answers keyed by question key against a questionnaire and converts them
+// into EvaluationAnswers. Every problem is reported in a single *domain.ValidationError.
+func ParseAnswers(questionnaire domain.Questionnaire, raw map[string]json.RawMessage) ([]domain.EvaluationAnswer, error) {
+	verr := &domain.ValidationError{}
+	answers := make([]domain.EvaluationAnswer, 0, len(raw))
+
+	known := make(map[string]bool, len(questionnaire.Questions))
+	for _, q := range questionnaire.Questions {
+		known[q.Key] = true
+
+		value, ok := raw[q.Key]
+		if !ok || isEmptyAnswer(value) {
+			if q.Required {
+				verr.Add(q.Key, domain.FieldRequired, "is required")
+			}
+			continue
+		}
+
+		answer := domain.EvaluationAnswer{QuestionID: q.ID, QuestionKey: q.Key}
+		switch q.Type {
+		case domain.QuestionRating:
+			var score int
+			if err := json.Unmarshal(value, &score); err != nil {
+				verr.Add(q.Key, domain.FieldInvalidType, "must be an integer")
+				continue
+			}
+			if score < *q.ScaleMin || score > *q.ScaleMax {
+				verr.AddOutOfRange(q.Key, *q.ScaleMin, *q.ScaleMax)
+				continue
+			}
+			answer.Score = &score
+		case domain.QuestionText:
+			var text string
+			if err := json.Unmarshal(value, &text); err != nil {
+				verr.Add(q.Key, domain.FieldInvalidType, "must be a string")
+				continue
+			}
+			answer.Text = &text
+		case domain.QuestionSingleChoice:
+			var choice string
+			if err := json.Unmarshal(value, &choice); err != nil {
+				verr.Add(q.Key, domain.FieldInvalidType, "must be a string")
+				continue
+			}
+			if !isOption(q, choice) {
+				verr.Add(q.Key, domain.FieldInvalidOption, "is not one of the options")
+				continue
+			}
+			answer.Text = &choice
+		case domain.QuestionMultiChoice:
+			var choices []string
+			if err := json.Unmarshal(value, &choices); err != nil {
+				verr.Add(q.Key, domain.FieldInvalidType, "must be a list of strings")
+				continue
+			}
+			valid := true
+			for _, choice := range choices {
+				if q.Options != nil && len(*q.Options) > 0 && !isOption(q, choice) {
+					verr.Add(q.Key, domain.FieldInvalidOption, strconv.Quote(choice)+" is not one of the options")
+					valid = false
+				}
+			}
+			if !valid {
+				continue
+			}
+			arr := pq.StringArray(choices)
+			answer.Choices = &arr
+		}
+		answers = append(answers, answer)
+	}
+
+	unknown := make([]string, 0)
+	for key := range raw {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		verr.Add(key, domain.FieldUnknown, "is not a question in this form")
+	}
+
+	if err := verr.OrNil(); err != nil {
+		return nil, err
+	}
+	return answers, nil
+}
+
+func isEmptyAnswer(value json.RawMessage) bool {
+	s := string(value)
+	return s == "null" || s == `""` || s == "[]"
+}
+
+func isOption(q domain.Question, choice string) bool {
+	if q.Options == nil {
+		return false
+	}
+	for _, option := range *q.Options {
+		if option == choice {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/tracing"
+	"gorm.io/gorm"
+)
+
+// GetEvaluationAnalytics aggregates the rating questions into distributions, means, medians and an NPS-style
+// score, one entry per group. A non-nil scope pins faculty staff to their faculty: only students scanned at it
+// are counted, and a breakdown by faculty keeps only that faculty.
+func (u *StudentEvaluationUsecase) GetEvaluationAnalytics(ctx context.Context, groupBy domain.EvaluationGroupBy, scope *string) ([]domain.EvaluationAnalytics, error) {
+	ctx, span := tracing.Start(ctx, "StudentEvaluationUsecase.GetEvaluationAnalytics")
+	defer span.End()
+
+	groups, err := u.StudentEvaluationRepo.GetEvaluationGroupCounts(ctx, groupBy, scope)
+	if err != nil {
+		return nil, err
+	}
+	scores, err := u.StudentEvaluationRepo.GetEvaluationScoreCounts(ctx, groupBy, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	npsScaleMax, err := u.npsScaleMax(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// group -> question -> score -> count
+	distributions := make(map[string]map[string]map[int]int)
+	for _, s := range scores {
+		if distributions[s.Group] == nil {
+			distributions[s.Group] = make(map[string]map[int]int)
+		}
+		if distributions[s.Group][s.Question] == nil {
+			distributions[s.Group][s.Question] = make(map[int]int)
+		}
+		distributions[s.Group][s.Question][s.Score] += s.Count
+	}
+
+	results := []domain.EvaluationAnalytics{}
+	for _, g := range groups {
+		if scope != nil && groupBy == domain.GroupByFaculty && g.Group != *scope {
+			continue
+		}
+
+		analytics := domain.EvaluationAnalytics{Group: g.Group, Responses: g.Count, Questions: []domain.QuestionStats{}}
+		questions := make([]string, 0, len(distributions[g.Group]))
+		for question := range distributions[g.Group] {
+			questions = append(questions, question)
+		}
+		sort.Strings(questions)
+
+		for _, question := range questions {
+			distribution := distributions[g.Group][question]
+			analytics.Questions = append(analytics.Questions, questionStats(question, distribution))
+			if question == domain.NPSQuestion {
+				analytics.NPS = npsStats(distribution, npsScaleMax)
+			}
+		}
+		results = append(results, analytics)
+	}
+	return results, nil
+}
+
+func questionStats(question string, distribution map[int]int) domain.QuestionStats {
+	stats := domain.QuestionStats{Question: question, Distribution: distribution}
+
+	scores := make([]int, 0, len(distribution))
+	sum := 0
+	for score, count := range distribution {
+		scores = append(scores, score)
+		stats.Responses += count
+		sum += score * count
+	}
+	if stats.Responses == 0 {
+		return stats
+	}
+	sort.Ints(scores)
+
+	stats.Mean = float64(sum) / float64(stats.Responses)
+	stats.Median = (float64(nthScore(scores, distribution, (stats.Responses-1)/2)) +
+		float64(nthScore(scores, distribution, stats.Responses/2))) / 2
+	return stats
+}
+
+// nthScore returns the n-th (0-based) score when every response is laid out in ascending order
+func nthScore(scores []int, distribution map[int]int, n int) int {
+	for _, score := range scores {
+		if n < distribution[score] {
+			return score
+		}
+		n -= distribution[score]
+	}
+	return 0
+}
+
+// npsScaleMax is the top of the NPS question's scale on the active event questionnaire, 5 when it has none
+func (u *StudentEvaluationUsecase) npsScaleMax(ctx context.Context) (int, error) {
+	questionnaire, err := u.QuestionnaireRepo.GetActive(ctx)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return defaultNPSScaleMax, nil
+		}
+		return 0, err
+	}
+	for _, q := range questionnaire.Questions {
+		if q.Key == domain.NPSQuestion && q.ScaleMax != nil && *q.ScaleMax > 0 {
+			return *q.ScaleMax, nil
+		}
+	}
+	return defaultNPSScaleMax, nil
+}
+
+const defaultNPSScaleMax = 5
+
+// npsStats puts each score on a 0-10 scale and splits it the usual way: 9-10 promoters, 7-8 passives and
+// 0-6 detractors. On the default 1-5 scale that makes 5 a promoter, 4 passive and 1-3 detractors.
+func npsStats(distribution map[int]int, scaleMax int) domain.NPSStats {
+	var nps domain.NPSStats
+	for score, count := range distribution {
+		nps.Responses += count
+		switch {
+		case score*10 >= 9*scaleMax:
+			nps.Promoters += count
+		case score*10 >= 7*scaleMax:
+			nps.Passives += count
+		default:
+			nps.Detractors += count
+		}
+	}
+	if nps.Responses > 0 {
+		nps.Score = float64(nps.Promoters-nps.Detractors) / float64(nps.Responses) * 100
+	}
+	return nps
+}
@@ -0,0 +1,80 @@
+package usecase
+
+import "testing"
+
+func TestQuestionStats(t *testing.T) {
+	tests := []struct {
+		name         string
+		distribution map[int]int
+		responses    int
+		mean         float64
+		median       float64
+	}{
+		{"empty distribution", map[int]int{}, 0, 0, 0},
+		{"single response", map[int]int{3: 1}, 1, 3, 3},
+		{"all in one bucket", map[int]int{4: 6}, 6, 4, 4},
+		{"odd count", map[int]int{1: 1, 2: 1, 5: 1}, 3, 8.0 / 3, 2},
+		{"even count within a bucket", map[int]int{2: 1, 3: 2, 5: 1}, 4, 3.25, 3},
+		{"even count between buckets", map[int]int{2: 2, 4: 2}, 4, 3, 3},
+		{"even count between far buckets", map[int]int{1: 3, 5: 3}, 6, 3, 3},
+		{"skewed even count between buckets", map[int]int{1: 1, 3: 2, 4: 1, 5: 2}, 6, 3.5, 3.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := questionStats("q", tt.distribution)
+			if stats.Responses != tt.responses || stats.Mean != tt.mean || stats.Median != tt.median {
+				t.Errorf("responses %d, mean %v, median %v; want %d, %v, %v",
+					stats.Responses, stats.Mean, stats.Median, tt.responses, tt.mean, tt.median)
+			}
+		})
+	}
+}
+
+func TestNthScore(t *testing.T) {
+	scores, distribution := []int{1, 3, 5}, map[int]int{1: 2, 3: 1, 5: 3}
+	want := []int{1, 1, 3, 5, 5, 5}
+	for n, score := range want {
+		if got := nthScore(scores, distribution, n); got != score {
+			t.Errorf("nthScore(%d) = %d, want %d", n, got, score)
+		}
+	}
+	if got := nthScore(scores, distribution, len(want)); got != 0 {
+		t.Errorf("nthScore past the end = %d, want 0", got)
+	}
+}
+
+func TestNPSStats(t *testing.T) {
+	tests := []struct {
+		name         string
+		distribution map[int]int
+		scaleMax     int
+		promoters    int
+		passives     int
+		detractors   int
+		score        float64
+	}{
+		{"empty", map[int]int{}, 10, 0, 0, 0, 0},
+		{"0-10 at 6", map[int]int{6: 1}, 10, 0, 0, 1, -100},
+		{"0-10 at 7", map[int]int{7: 1}, 10, 0, 1, 0, 0},
+		{"0-10 at 8", map[int]int{8: 1}, 10, 0, 1, 0, 0},
+		{"0-10 at 9", map[int]int{9: 1}, 10, 1, 0, 0, 100},
+		{"0-10 at 0 and 10", map[int]int{0: 1, 10: 1}, 10, 1, 0, 1, 0},
+		{"0-10 mixed", map[int]int{3: 1, 6: 1, 7: 1, 8: 1, 9: 2, 10: 2}, 10, 4, 2, 2, 25},
+		{"1-5 at 3", map[int]int{3: 1}, 5, 0, 0, 1, -100},
+		{"1-5 at 4", map[int]int{4: 1}, 5, 0, 1, 0, 0},
+		{"1-5 at 5", map[int]int{5: 1}, 5, 1, 0, 0, 100},
+		{"1-5 mixed", map[int]int{1: 1, 2: 1, 4: 1, 5: 5}, 5, 5, 1, 2, 37.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nps := npsStats(tt.distribution, tt.scaleMax)
+			if nps.Promoters != tt.promoters || nps.Passives != tt.passives || nps.Detractors != tt.detractors || nps.Score != tt.score {
+				t.Errorf("promoters %d, passives %d, detractors %d, score %v; want %d, %d, %d, %v",
+					nps.Promoters, nps.Passives, nps.Detractors, nps.Score, tt.promoters, tt.passives, tt.detractors, tt.score)
+			}
+			if nps.Responses != nps.Promoters+nps.Passives+nps.Detractors {
+				t.Errorf("responses %d, want the sum of the groups", nps.Responses)
+			}
+		})
+	}
+}
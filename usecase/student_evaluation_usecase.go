@@ -1,73 +1,321 @@
 package usecase
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"strconv"
 
 	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/tracing"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
 )
 
 type StudentEvaluationUsecase struct {
-	StudentEvaluationRepo StudentEvaluationRepositoryInterface
+	StudentEvaluationRepo  StudentEvaluationRepositoryInterface
+	QuestionnaireRepo      QuestionnaireRepositoryInterface
+	UserRepo               UserRepositoryInterface
+	StudentTransactionRepo StudentTransactionRepositoryInterface
 }
 
 type StudentEvaluationRepositoryInterface interface {
-	CreateStudentEvaluation(evaluation *domain.StudentEvaluation) error
-	GetStudentEvaluationByStudentId(studentId string) (*domain.StudentEvaluation, error)
-	UpdateStudentEvaluation(evaluation *domain.StudentEvaluation) error
-	DeleteStudentEvaluation(studentId string) error
-	GetAllStudentEvaluations() ([]domain.StudentEvaluation, error)
-	GetStudentEvaluationCount() (int64, error)
-	GetStudentEvaluationById(id string) (*domain.StudentEvaluation, error)
+	CreateStudentEvaluation(ctx context.Context, evaluation *domain.StudentEvaluation) error
+	GetStudentEvaluationByStudentId(ctx context.Context, studentId string) (*domain.StudentEvaluation, error)
+	UpdateStudentEvaluation(ctx context.Context, evaluation *domain.StudentEvaluation) error
+	DeleteStudentEvaluation(ctx context.Context, studentId string) error
+	GetAllStudentEvaluations(ctx context.Context) ([]domain.StudentEvaluation, error)
+	GetStudentEvaluationCount(ctx context.Context) (int64, error)
+	GetStudentEvaluationById(ctx context.Context, id string) (*domain.StudentEvaluation, error)
+	GetEvaluationScoreCounts(ctx context.Context, groupBy domain.EvaluationGroupBy, scope *string) ([]domain.EvaluationScoreCount, error)
+	GetEvaluationGroupCounts(ctx context.Context, groupBy domain.EvaluationGroupBy, scope *string) ([]domain.EvaluationGroupCount, error)
+	GetLegacyStudentEvaluations(ctx context.Context, limit int) ([]domain.StudentEvaluation, error)
 }
 
-func NewStudentEvaluationUsecase(studentEvaluationRepo StudentEvaluationRepositoryInterface) *StudentEvaluationUsecase {
-	return &StudentEvaluationUsecase{StudentEvaluationRepo: studentEvaluationRepo}
+func NewStudentEvaluationUsecase(
+	studentEvaluationRepo StudentEvaluationRepositoryInterface,
+	questionnaireRepo QuestionnaireRepositoryInterface,
+	userRepo UserRepositoryInterface,
+	studentTransactionRepo StudentTransactionRepositoryInterface,
+) *StudentEvaluationUsecase {
+	return &StudentEvaluationUsecase{
+		StudentEvaluationRepo:  studentEvaluationRepo,
+		QuestionnaireRepo:      questionnaireRepo,
+		UserRepo:               userRepo,
+		StudentTransactionRepo: studentTransactionRepo,
+	}
+}
+
+// HasAttended reports whether the student was scanned at the gate (LastEntered) or at any faculty booth
+func (u *StudentEvaluationUsecase) HasAttended(ctx context.Context, studentId string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "StudentEvaluationUsecase.HasAttended")
+	defer span.End()
+
+	student, err := u.UserRepo.GetById(ctx, studentId)
+	if err != nil {
+		return false, err
+	}
+	if student.LastEntered != nil {
+		return true, nil
+	}
+	transactions, err := u.StudentTransactionRepo.GetByStudentId(ctx, studentId)
+	if err != nil {
+		return false, err
+	}
+	return len(transactions) > 0, nil
+}
+
+// HasEvaluated reports whether the student has submitted the event evaluation
+func (u *StudentEvaluationUsecase) HasEvaluated(ctx context.Context, studentId string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "StudentEvaluationUsecase.HasEvaluated")
+	defer span.End()
+
+	_, err := u.StudentEvaluationRepo.GetStudentEvaluationByStudentId(ctx, studentId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// CreateStudentEvaluation validates answers keyed by question key against the active questionnaire and stores them.
+// If the questionnaire requires attendance, students who never entered the event are rejected.
+func (u *StudentEvaluationUsecase) CreateStudentEvaluation(ctx context.Context, studentId string, raw map[string]json.RawMessage) (*domain.StudentEvaluation, error) {
+	ctx, span := tracing.Start(ctx, "StudentEvaluationUsecase.CreateStudentEvaluation")
+	defer span.End()
+
+	_, err := u.StudentEvaluationRepo.GetStudentEvaluationByStudentId(ctx, studentId)
+	if err == nil {
+		return nil, domain.ErrStudentEvaluationAlreadyExists
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	questionnaire, err := u.QuestionnaireRepo.GetActive(ctx)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrNoActiveQuestionnaire
+		}
+		return nil, err
+	}
+	if questionnaire.RequireAttendance {
+		attended, err := u.HasAttended(ctx, studentId)
+		if err != nil {
+			return nil, err
+		}
+		if !attended {
+			return nil, domain.ErrStudentNotAttended.WithMessage("Only students who entered the event can submit an evaluation")
+		}
+	}
+
+	answers, err := ParseAnswers(questionnaire, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluation := &domain.StudentEvaluation{
+		StudentId:       studentId,
+		QuestionnaireID: &questionnaire.ID,
+		Answers:         answers,
+	}
+	fillLegacyColumns(evaluation)
+
+	if err := u.StudentEvaluationRepo.CreateStudentEvaluation(ctx, evaluation); err != nil {
+		// A concurrent submission won the unique index on student_id
+		if existing, _ := u.StudentEvaluationRepo.GetStudentEvaluationByStudentId(ctx, studentId); existing != nil {
+			return nil, domain.ErrStudentEvaluationAlreadyExists
+		}
+		return nil, err
+	}
+	return evaluation, nil
+}
+
+func (u *StudentEvaluationUsecase) GetStudentEvaluationByStudentId(ctx context.Context, studentId string) (*domain.StudentEvaluation, error) {
+	ctx, span := tracing.Start(ctx, "StudentEvaluationUsecase.GetStudentEvaluationByStudentId")
+	defer span.End()
+
+	evaluation, err := u.StudentEvaluationRepo.GetStudentEvaluationByStudentId(ctx, studentId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrStudentEvaluationNotFound
+	}
+	return evaluation, err
 }
 
-func (u *StudentEvaluationUsecase) CreateStudentEvaluation(evaluation *domain.StudentEvaluation) error {
-	isExist, _ := u.StudentEvaluationRepo.GetStudentEvaluationByStudentId(evaluation.StudentId)
+// UpdateStudentEvaluation replaces a student's answers, validated against the questionnaire version
+// the evaluation was submitted with.
+func (u *StudentEvaluationUsecase) UpdateStudentEvaluation(ctx context.Context, studentId string, raw map[string]json.RawMessage) (*domain.StudentEvaluation, error) {
+	ctx, span := tracing.Start(ctx, "StudentEvaluationUsecase.UpdateStudentEvaluation")
+	defer span.End()
+
+	evaluation, err := u.GetStudentEvaluationByStudentId(ctx, studentId)
+	if err != nil {
+		return nil, err
+	}
+
+	var questionnaire domain.Questionnaire
+	if evaluation.QuestionnaireID != nil {
+		questionnaire, err = u.QuestionnaireRepo.GetById(ctx, *evaluation.QuestionnaireID)
+	} else {
+		questionnaire, err = u.QuestionnaireRepo.GetActive(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	answers, err := ParseAnswers(questionnaire, raw)
+	if err != nil {
+		return nil, err
+	}
 
-	if isExist != nil {
-		return domain.ErrStudentEvaluationAlreadyExists
+	updated := &domain.StudentEvaluation{
+		ID:              evaluation.ID,
+		StudentId:       evaluation.StudentId,
+		QuestionnaireID: &questionnaire.ID,
+		Answers:         answers,
 	}
-	return u.StudentEvaluationRepo.CreateStudentEvaluation(evaluation)
+	fillLegacyColumns(updated)
+
+	if err := u.StudentEvaluationRepo.UpdateStudentEvaluation(ctx, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
 }
 
-func (u *StudentEvaluationUsecase) GetStudentEvaluationByStudentId(studentId string) (*domain.StudentEvaluation, error) {
-	return u.StudentEvaluationRepo.GetStudentEvaluationByStudentId(studentId)
+// MigrateLegacyEvaluations copies the fixed columns of evaluations submitted before questionnaires
+// existed into answers of version 1. It is safe to run on every start.
+func (u *StudentEvaluationUsecase) MigrateLegacyEvaluations(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "StudentEvaluationUsecase.MigrateLegacyEvaluations")
+	defer span.End()
+
+	questionnaire, err := u.QuestionnaireRepo.GetByVersion(ctx, 1)
+	if err != nil {
+		return fmt.Errorf("error loading questionnaire version 1: %w", err)
+	}
+
+	for {
+		evaluations, err := u.StudentEvaluationRepo.GetLegacyStudentEvaluations(ctx, 500)
+		if err != nil {
+			return err
+		}
+		if len(evaluations) == 0 {
+			return nil
+		}
+
+		for i := range evaluations {
+			evaluation := &evaluations[i]
+			evaluation.QuestionnaireID = &questionnaire.ID
+			evaluation.Answers = legacyAnswers(questionnaire, evaluation)
+			if err := u.StudentEvaluationRepo.UpdateStudentEvaluation(ctx, evaluation); err != nil {
+				return fmt.Errorf("error migrating evaluation %d: %w", evaluation.ID, err)
+			}
+		}
+	}
 }
 
-func (u *StudentEvaluationUsecase) UpdateStudentEvaluation(evaluation *domain.StudentEvaluation) error {
-	return u.StudentEvaluationRepo.UpdateStudentEvaluation(evaluation)
+// legacyAnswers reads the fixed columns of an evaluation as answers. Unanswered (zero) ratings are skipped
+// and values are kept as submitted, even when they fall outside the scale.
+func legacyAnswers(questionnaire domain.Questionnaire, evaluation *domain.StudentEvaluation) []domain.EvaluationAnswer {
+	columns := evaluation.LegacyColumns()
+	answers := []domain.EvaluationAnswer{}
+	for _, q := range questionnaire.Questions {
+		answer := domain.EvaluationAnswer{QuestionID: q.ID, QuestionKey: q.Key}
+		switch q.Type {
+		case domain.QuestionRating:
+			column, ok := columns.Ratings[q.Key]
+			if !ok || *column == 0 {
+				continue
+			}
+			score := *column
+			answer.Score = &score
+		case domain.QuestionText, domain.QuestionSingleChoice:
+			column, ok := columns.Texts[q.Key]
+			if !ok || *column == nil || **column == "" {
+				continue
+			}
+			text := **column
+			answer.Text = &text
+		case domain.QuestionMultiChoice:
+			column, ok := columns.Choices[q.Key]
+			if !ok || *column == nil || len(**column) == 0 {
+				continue
+			}
+			choices := append(pq.StringArray{}, **column...)
+			answer.Choices = &choices
+		default:
+			continue
+		}
+		answers = append(answers, answer)
+	}
+	return answers
 }
 
-func (u *StudentEvaluationUsecase) DeleteStudentEvaluation(studentId string) error {
-	return u.StudentEvaluationRepo.DeleteStudentEvaluation(studentId)
+// fillLegacyColumns mirrors answers into the fixed column of the same key and type, so clients reading
+// version 1 fields keep working. Answers to other questions have no column and are left out.
+func fillLegacyColumns(evaluation *domain.StudentEvaluation) {
+	columns := evaluation.LegacyColumns()
+	for _, answer := range evaluation.Answers {
+		switch {
+		case answer.Score != nil:
+			if column, ok := columns.Ratings[answer.QuestionKey]; ok {
+				*column = *answer.Score
+			}
+		case answer.Text != nil:
+			if column, ok := columns.Texts[answer.QuestionKey]; ok {
+				text := *answer.Text
+				*column = &text
+			}
+		case answer.Choices != nil:
+			if column, ok := columns.Choices[answer.QuestionKey]; ok {
+				choices := append(pq.StringArray{}, *answer.Choices...)
+				*column = &choices
+			}
+		}
+	}
 }
 
-func (u *StudentEvaluationUsecase) GetAllStudentEvaluations() ([]domain.StudentEvaluation, error) {
-	return u.StudentEvaluationRepo.GetAllStudentEvaluations()
+func (u *StudentEvaluationUsecase) DeleteStudentEvaluation(ctx context.Context, studentId string) error {
+	ctx, span := tracing.Start(ctx, "StudentEvaluationUsecase.DeleteStudentEvaluation")
+	defer span.End()
+
+	return u.StudentEvaluationRepo.DeleteStudentEvaluation(ctx, studentId)
+}
+
+func (u *StudentEvaluationUsecase) GetAllStudentEvaluations(ctx context.Context) ([]domain.StudentEvaluation, error) {
+	ctx, span := tracing.Start(ctx, "StudentEvaluationUsecase.GetAllStudentEvaluations")
+	defer span.End()
+
+	return u.StudentEvaluationRepo.GetAllStudentEvaluations(ctx)
 }
 
-func (u *StudentEvaluationUsecase) GetStudentEvaluationCount() (int64, error) {
-	return u.StudentEvaluationRepo.GetStudentEvaluationCount()
+func (u *StudentEvaluationUsecase) GetStudentEvaluationCount(ctx context.Context) (int64, error) {
+	ctx, span := tracing.Start(ctx, "StudentEvaluationUsecase.GetStudentEvaluationCount")
+	defer span.End()
+
+	return u.StudentEvaluationRepo.GetStudentEvaluationCount(ctx)
 }
 
-func (u *StudentEvaluationUsecase) GetStudentEvaluationById(id string) (*domain.StudentEvaluation, error) {
-	return u.StudentEvaluationRepo.GetStudentEvaluationById(id)
+func (u *StudentEvaluationUsecase) GetStudentEvaluationById(ctx context.Context, id string) (*domain.StudentEvaluation, error) {
+	ctx, span := tracing.Start(ctx, "StudentEvaluationUsecase.GetStudentEvaluationById")
+	defer span.End()
+
+	return u.StudentEvaluationRepo.GetStudentEvaluationById(ctx, id)
 }
 
-func (u *StudentEvaluationUsecase) GetStudentEvaluationByStudentIdAndId(studentId string, id string) (*domain.StudentEvaluation, error) {
-	evaluation, err := u.StudentEvaluationRepo.GetStudentEvaluationByStudentId(studentId)
+func (u *StudentEvaluationUsecase) GetStudentEvaluationByStudentIdAndId(ctx context.Context, studentId string, id string) (*domain.StudentEvaluation, error) {
+	ctx, span := tracing.Start(ctx, "StudentEvaluationUsecase.GetStudentEvaluationByStudentIdAndId")
+	defer span.End()
+
+	evaluation, err := u.GetStudentEvaluationByStudentId(ctx, studentId)
 	if err != nil {
 		return nil, err
 	}
 	idInt, err := strconv.Atoi(id)
 	if err != nil {
-		return nil, err
+		return nil, domain.InvalidInput("Invalid evaluation ID")
 	}
 	if evaluation.ID != idInt {
-		return nil, domain.ErrUserNotFound
+		return nil, domain.ErrStudentEvaluationNotFound
 	}
 	return evaluation, nil
 }
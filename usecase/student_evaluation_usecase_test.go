@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/lib/pq"
+)
+
+func TestIsReservedQuestionKeyIgnoresCase(t *testing.T) {
+	tests := map[string]bool{
+		"id":              true,
+		"ID":              true,
+		"StudentId":       true,
+		"studentid":       true,
+		"QUESTIONNAIREID": true,
+		"Answers":         true,
+		"overallActivity": false,
+		"identity":        false,
+	}
+	for key, want := range tests {
+		if got := isReservedQuestionKey(key); got != want {
+			t.Errorf("isReservedQuestionKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestFillLegacyColumnsMatchesExactKeyAndType(t *testing.T) {
+	score, other, text := 4, 2, "engineering"
+	evaluation := &domain.StudentEvaluation{
+		ID:        7,
+		StudentId: "student",
+		Answers: []domain.EvaluationAnswer{
+			{QuestionKey: "overallActivity", Score: &score},
+			{QuestionKey: "OverallActivity", Score: &other}, // another question in a later version
+			{QuestionKey: "Id", Score: &other},              // must never reach the primary key
+			{QuestionKey: "studentid", Text: &text},         // nor the owner
+			{QuestionKey: "interestActivity", Text: &text},  // wrong type for the column
+			{QuestionKey: "favoriteBooth", Text: &text},     // text column
+			{QuestionKey: "newSources", Choices: &pq.StringArray{"line"}},
+		},
+	}
+
+	fillLegacyColumns(evaluation)
+
+	if evaluation.ID != 7 || evaluation.StudentId != "student" {
+		t.Errorf("answers overwrote the evaluation's identity: id %d, student %q", evaluation.ID, evaluation.StudentId)
+	}
+	if evaluation.OverallActivity != 4 {
+		t.Errorf("overallActivity = %d, want 4", evaluation.OverallActivity)
+	}
+	if evaluation.InterestActivity != 0 {
+		t.Errorf("interestActivity = %d, want 0", evaluation.InterestActivity)
+	}
+	if evaluation.FavoriteBooth == nil || *evaluation.FavoriteBooth != text {
+		t.Errorf("favoriteBooth = %v, want %q", evaluation.FavoriteBooth, text)
+	}
+	if evaluation.NewSources == nil || len(*evaluation.NewSources) != 1 {
+		t.Errorf("newSources = %v, want [line]", evaluation.NewSources)
+	}
+}
+
+func TestLegacyAnswersRoundTrip(t *testing.T) {
+	questionnaire := domain.DefaultQuestionnaire()
+	booth := "arts"
+	evaluation := &domain.StudentEvaluation{
+		NewSources:         &pq.StringArray{"facebook", "line"},
+		OverallActivity:    5,
+		DesignBeautyRating: 3,
+		FavoriteBooth:      &booth,
+	}
+
+	answers := legacyAnswers(questionnaire, evaluation)
+	if len(answers) != 4 {
+		t.Fatalf("got %d answers, want 4: %+v", len(answers), answers)
+	}
+
+	copied := &domain.StudentEvaluation{Answers: answers}
+	fillLegacyColumns(copied)
+	if copied.OverallActivity != 5 || copied.DesignBeautyRating != 3 || copied.InterestActivity != 0 {
+		t.Errorf("ratings not restored: %+v", copied)
+	}
+	if copied.FavoriteBooth == nil || *copied.FavoriteBooth != booth {
+		t.Errorf("favoriteBooth = %v, want %q", copied.FavoriteBooth, booth)
+	}
+	if copied.NewSources == nil || len(*copied.NewSources) != 2 {
+		t.Errorf("newSources = %v, want 2 sources", copied.NewSources)
+	}
+}
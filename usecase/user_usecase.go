@@ -1,11 +1,18 @@
 package usecase
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/keyring"
+	"github.com/isd-sgcu/oph-67-backend/metrics"
+	"github.com/isd-sgcu/oph-67-backend/tracing"
 	"github.com/isd-sgcu/oph-67-backend/utils"
+	"gorm.io/gorm"
 )
 
 // UserUsecase provides business logic operations for user management.
@@ -13,34 +20,37 @@ import (
 type UserUsecase struct {
 	UserRepo               UserRepositoryInterface
 	StudentTransactionRepo StudentTransactionRepositoryInterface
+	Keys                   *keyring.Keyring
+	BaseURL                string // public URL of this API, used in QR code links
+	Logger                 *slog.Logger
 }
 
 // UserRepositoryInterface defines the repository methods required by UserUsecase.
 // Implementations of this interface handle data storage and retrieval operations.
 type UserRepositoryInterface interface {
-	Create(user *domain.User) error
-	GetAll() ([]domain.User, error)
-	GetById(id string) (domain.User, error)
-	GetByPhone(phone string) (domain.User, error)
-	GetByName(name string) ([]domain.User, error)
-	IsUIDExists(uid string) (bool, error)
-	Update(id string, user *domain.User) error
-	Delete(id string) error
+	Create(ctx context.Context, user *domain.User) error
+	List(ctx context.Context, filter domain.UserFilter, order domain.UserOrder, after *domain.UserCursor, limit int) ([]domain.User, error)
+	Count(ctx context.Context, filter domain.UserFilter) (int64, error)
+	GetById(ctx context.Context, id string) (domain.User, error)
+	GetByPhone(ctx context.Context, phone string) (domain.User, error)
+	IsUIDExists(ctx context.Context, uid string) (bool, error)
+	Update(ctx context.Context, id string, user *domain.User) error
+	Delete(ctx context.Context, id string) error
 }
 
 type StudentTransactionRepositoryInterface interface {
-	Create(transaction *domain.StudentTransaction) error
-	GetAll() ([]domain.StudentTransaction, error)
-	GetById(id string) (domain.StudentTransaction, error)
-	GetByStudentId(studentId string) ([]domain.StudentTransaction, error)
-	GetByStudentIdAndFaculty(studentId string, faculty string) ([]domain.StudentTransaction, error)
-	Update(id string, transaction *domain.StudentTransaction) error
-	Delete(id string) error
+	Create(ctx context.Context, transaction *domain.StudentTransaction) error
+	GetAll(ctx context.Context) ([]domain.StudentTransaction, error)
+	GetById(ctx context.Context, id string) (domain.StudentTransaction, error)
+	GetByStudentId(ctx context.Context, studentId string) ([]domain.StudentTransaction, error)
+	GetByStudentIdAndFaculty(ctx context.Context, studentId string, faculty string) ([]domain.StudentTransaction, error)
+	Update(ctx context.Context, id string, transaction *domain.StudentTransaction) error
+	Delete(ctx context.Context, id string) error
 }
 
 // NewUserUsecase initializes a new UserUsecase instance with the provided repository.
-func NewUserUsecase(userRepo UserRepositoryInterface, studentTransactionRepo StudentTransactionRepositoryInterface) *UserUsecase {
-	return &UserUsecase{UserRepo: userRepo, StudentTransactionRepo: studentTransactionRepo}
+func NewUserUsecase(userRepo UserRepositoryInterface, studentTransactionRepo StudentTransactionRepositoryInterface, keys *keyring.Keyring, baseURL string, logger *slog.Logger) *UserUsecase {
+	return &UserUsecase{UserRepo: userRepo, StudentTransactionRepo: studentTransactionRepo, Keys: keys, BaseURL: baseURL, Logger: logger}
 }
 
 // assignRole determines and assigns a user's role based on their phone number.
@@ -67,14 +77,17 @@ func isSameDay(t1, t2 time.Time) bool {
 	return y1 == y2 && m1 == m2 && d1 == d2
 }
 
-func (u *UserUsecase) Register(user *domain.User) (domain.TokenResponse, error) {
+func (u *UserUsecase) Register(ctx context.Context, user *domain.User) (domain.TokenResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserUsecase.Register")
+	defer span.End()
+
 	u.assignRole(user)
 
 	// Ensure UID is unique with a loop limit
 	maxAttempts := 10
 	for attempts := 0; attempts < maxAttempts; attempts++ {
 		user.UID = utils.GenerateUID()
-		uidExists, err := u.UserRepo.IsUIDExists(user.UID)
+		uidExists, err := u.UserRepo.IsUIDExists(ctx, user.UID)
 		if err != nil {
 			return domain.TokenResponse{}, fmt.Errorf("error checking UID uniqueness: %w", err)
 		}
@@ -87,31 +100,28 @@ func (u *UserUsecase) Register(user *domain.User) (domain.TokenResponse, error)
 	user.RegisteredAt = &now
 
 	// Check if user already exists
-	existingUser, err := u.UserRepo.GetById(user.ID)
+	existingUser, err := u.UserRepo.GetById(ctx, user.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.TokenResponse{}, fmt.Errorf("error fetching user: %w", err)
+	}
 	if err != nil {
-		fmt.Println("Error fetching user", err)
-		fmt.Println("Trying to create user")
 		// User not found, create a new one
-		if err := u.UserRepo.Create(user); err != nil {
+		u.Logger.DebugContext(ctx, "User not found, creating", "userId", user.ID)
+		if err := u.UserRepo.Create(ctx, user); err != nil {
 			return domain.TokenResponse{}, fmt.Errorf("error saving user: %w", err)
 		}
+		metrics.Registrations.WithLabelValues(string(user.Role)).Inc()
+		u.Logger.InfoContext(ctx, "User registered", "user", *user)
 		return u.generateTokenResponse(user)
 	}
 
-	// Promote to staff if already exists and is a member
-	if existingUser.Role == domain.Member {
-		existingUser.Role = domain.Staff
-		if err := u.UserRepo.Update(existingUser.ID, &existingUser); err != nil {
-			return domain.TokenResponse{}, fmt.Errorf("error updating user role: %w", err)
-		}
-	}
-
+	// Registering again only signs in; roles are granted by admins (see AddStaff)
 	return u.generateTokenResponse(&existingUser)
 }
 
 func (u *UserUsecase) generateTokenResponse(user *domain.User) (domain.TokenResponse, error) {
-	jwtSecret := utils.GetEnv("SECRET_JWT_KEY", "")
-	accessToken, err := utils.GenerateTokens(user.ID, jwtSecret)
+	keyID, jwtSecret := u.Keys.JWT.Active()
+	accessToken, err := utils.GenerateTokens(user.ID, keyID, jwtSecret)
 	if err != nil {
 		return domain.TokenResponse{}, fmt.Errorf("error generating tokens: %w", err)
 	}
@@ -122,45 +132,77 @@ func (u *UserUsecase) generateTokenResponse(user *domain.User) (domain.TokenResp
 	}, nil
 }
 
-// GetAll retrieves users, optionally filtered by name if the filter parameter is provided.
-// Returns a list of users or error if repository operation fails.
-func (u *UserUsecase) GetAll(filter string, role domain.Role) ([]domain.User, error) {
-	if filter != "" || role != "" {
-		users, err := u.UserRepo.GetByName(filter)
-		if role != "" {
-			var filteredUsers []domain.User
-			for _, user := range users {
-				if user.Role == role {
-					filteredUsers = append(filteredUsers, user)
-				}
-			}
-			return filteredUsers, nil
-		}
+// List returns a page of at most limit users matching the filter, after the cursor of the previous
+// page if there is one. Filtering, sorting and paging all happen in the database.
+func (u *UserUsecase) List(ctx context.Context, filter domain.UserFilter, order domain.UserOrder, cursor string, limit int) (domain.UserPage, error) {
+	ctx, span := tracing.Start(ctx, "UserUsecase.List")
+	defer span.End()
+
+	var after *domain.UserCursor
+	if cursor != "" {
+		parsed, err := domain.ParseUserCursor(cursor)
 		if err != nil {
-			return nil, err
+			return domain.UserPage{}, err
+		}
+		if parsed.Sort != order.Sort || parsed.Desc != order.Desc {
+			return domain.UserPage{}, domain.ErrInvalidCursor.WithMessage("Cursor was made for another sort order")
 		}
-		return users, nil
+		after = &parsed
+	}
+
+	total, err := u.UserRepo.Count(ctx, filter)
+	if err != nil {
+		return domain.UserPage{}, err
 	}
 
-	return u.UserRepo.GetAll()
+	// One extra user tells whether there is a next page
+	users, err := u.UserRepo.List(ctx, filter, order, after, limit+1)
+	if err != nil {
+		return domain.UserPage{}, err
+	}
+
+	page := domain.UserPage{Users: users, Total: total}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		page.NextCursor = domain.NewUserCursor(order, page.Users[limit-1]).String()
+	}
+	if page.Users == nil {
+		page.Users = []domain.User{}
+	}
+	return page, nil
 }
 
 // GetById fetches a single user by their unique ID.
 // Returns the user or error if not found or repository operation fails.
-func (u *UserUsecase) GetById(id string) (domain.User, error) {
-	return u.UserRepo.GetById(id)
+func (u *UserUsecase) GetById(ctx context.Context, id string) (domain.User, error) {
+	ctx, span := tracing.Start(ctx, "UserUsecase.GetById")
+	defer span.End()
+
+	user, err := u.UserRepo.GetById(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.User{}, domain.ErrUserNotFound
+	}
+	return user, err
+}
+
+// DecodeToken returns the user ID of an access token signed by any accepted JWT key
+func (u *UserUsecase) DecodeToken(token string) (string, error) {
+	return utils.DecodeToken(token, u.Keys.JWT.Secrets())
 }
 
 // SignIn generates new authentication tokens for an existing user.
 // Returns TokenResponse with access token or error if user lookup fails.
-func (u *UserUsecase) SignIn(id string) (domain.TokenResponse, error) {
-	user, err := u.GetById(id)
+func (u *UserUsecase) SignIn(ctx context.Context, id string) (domain.TokenResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserUsecase.SignIn")
+	defer span.End()
+
+	user, err := u.GetById(ctx, id)
 	if err != nil {
 		return domain.TokenResponse{}, err
 	}
 
-	jwtSecret := utils.GetEnv("SECRET_JWT_KEY", "")
-	accessToken, err := utils.GenerateTokens(user.ID, jwtSecret)
+	keyID, jwtSecret := u.Keys.JWT.Active()
+	accessToken, err := utils.GenerateTokens(user.ID, keyID, jwtSecret)
 	if err != nil {
 		return domain.TokenResponse{}, err
 	}
@@ -173,24 +215,38 @@ func (u *UserUsecase) SignIn(id string) (domain.TokenResponse, error) {
 
 // Update modifies an existing user's information.
 // Returns error if user doesn't exist or repository operation fails.
-func (u *UserUsecase) Update(id string, updatedUser *domain.User) error {
-	_, err := u.GetById(id)
+func (u *UserUsecase) Update(ctx context.Context, id string, updatedUser *domain.User) error {
+	ctx, span := tracing.Start(ctx, "UserUsecase.Update")
+	defer span.End()
+
+	_, err := u.GetById(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	return u.UserRepo.Update(id, updatedUser)
+	return u.UserRepo.Update(ctx, id, updatedUser)
 }
 
 // ScanQR records a user's entry by updating their LastEntered timestamp.
 // Returns error if user has already entered today or repository operation fails.
-func (u *UserUsecase) ScanQR(studentId string, staffId string) (domain.User, error) {
-	student, err := u.GetById(studentId)
+func (u *UserUsecase) ScanQR(ctx context.Context, studentId string, staffId string) (scanned domain.User, err error) {
+	ctx, span := tracing.Start(ctx, "UserUsecase.ScanQR")
+	defer func() { tracing.End(span, err) }()
+
+	staff, err := u.GetById(ctx, staffId)
 	if err != nil {
 		return domain.User{}, err
 	}
 
-	staff, err := u.GetById(staffId)
+	faculty := metrics.ScanFacultyCentral
+	if staff.Faculty != nil && (u.isNull(staff) || !*staff.IsCentralStaff) {
+		faculty = *staff.Faculty
+	}
+	defer func() {
+		metrics.Scans.WithLabelValues(faculty, scanOutcome(err)).Inc()
+	}()
+
+	student, err := u.GetById(ctx, studentId)
 	if err != nil {
 		return domain.User{}, err
 	}
@@ -198,52 +254,74 @@ func (u *UserUsecase) ScanQR(studentId string, staffId string) (domain.User, err
 	now := time.Now()
 
 	if u.isNull(staff) {
-		return u.processFacultyStaffEntry(studentId, *staff.Faculty, now, student)
+		return u.processFacultyStaffEntry(ctx, studentId, *staff.Faculty, now, student)
 	}
 
 	if !*staff.IsCentralStaff {
-		return u.processFacultyStaffEntry(studentId, *staff.Faculty, now, student)
+		return u.processFacultyStaffEntry(ctx, studentId, *staff.Faculty, now, student)
 	}
 
-	return u.processCentralStaffEntry(studentId, &student, now)
+	return u.processCentralStaffEntry(ctx, studentId, &student, now)
+}
+
+// scanOutcome classifies the result of a scan for the scans_total metric
+func scanOutcome(err error) string {
+	switch {
+	case err == nil:
+		return metrics.ScanAccepted
+	case errors.Is(err, domain.ErrUserAlreadyEntered):
+		return metrics.ScanDuplicate
+	case errors.Is(err, domain.ErrUserNotFound):
+		return metrics.ScanUnknownUser
+	default:
+		return metrics.ScanError
+	}
 }
 
 // UpdateRole changes a user's role to the specified value.
 // Typically used by administrators for role management.
 // Returns error if user doesn't exist or update fails.
-func (u *UserUsecase) UpdateRole(id string, role domain.Role) error {
-	user, err := u.GetById(id)
+func (u *UserUsecase) UpdateRole(ctx context.Context, id string, role domain.Role) error {
+	ctx, span := tracing.Start(ctx, "UserUsecase.UpdateRole")
+	defer span.End()
+
+	user, err := u.GetById(ctx, id)
 	if err != nil {
 		return err
 	}
 	user.Role = role
-	return u.Update(id, &user)
+	return u.Update(ctx, id, &user)
 }
 
 // GetQRURL generates the full URL for a user's QR code based on their ID.
-// Uses the PRODUCTION_BASE_URL environment variable to construct the URL.
-func (u *UserUsecase) GetQRURL(id string) (string, error) {
-	user, err := u.GetById(id)
+// Uses the configured base URL (PRODUCTION_BASE_URL) to construct the URL.
+func (u *UserUsecase) GetQRURL(ctx context.Context, id string) (string, error) {
+	ctx, span := tracing.Start(ctx, "UserUsecase.GetQRURL")
+	defer span.End()
+
+	user, err := u.GetById(ctx, id)
 	if err != nil {
 		return "", err
 	}
 
-	baseURL := utils.GetEnv("PRODUCTION_BASE_URL", "http://localhost:4000")
-
-	return fmt.Sprintf("%s/api/users/qr/%s", baseURL, user.ID), nil
+	return fmt.Sprintf("%s/api/users/qr/%s", u.BaseURL, user.ID), nil
 }
 
-// GetCertToken generates a certificate token for a user based on their name.
-// Returns the token or error if user lookup fails or token generation fails.
-func (u *UserUsecase) GetCertToken(id string) (string, error) {
-	user, err := u.GetById(id)
+// GetCertToken signs the user's name into a name-only certificate token.
+// Deprecated: certificates from /api/certificates/{event} carry a serial and can be revoked.
+func (u *UserUsecase) GetCertToken(ctx context.Context, id string) (string, error) {
+	ctx, span := tracing.Start(ctx, "UserUsecase.GetCertToken")
+	defer span.End()
+
+	user, err := u.GetById(ctx, id)
 	if err != nil {
 		return "", err
 	}
 
-	privatekey := utils.GetEnv("CERT_PRIVATE_KEY", "")
-
-	token, err := utils.GenerateED25519Signature(privatekey, user.Name)
+	token, err := u.Keys.Cert.SignLegacy(user.Name)
+	if errors.Is(err, keyring.ErrClaimsLikeName) {
+		return "", domain.InvalidInput("This name cannot be signed into a certificate token")
+	}
 	if err != nil {
 		return "", err
 	}
@@ -253,14 +331,23 @@ func (u *UserUsecase) GetCertToken(id string) (string, error) {
 
 // RemoveStaff removes a user from the system by their ID.
 // Returns error if repository operation fails.
-func (u *UserUsecase) RemoveStaff(id string) error {
-	return u.UserRepo.Update(id, &domain.User{Role: domain.Member})
+func (u *UserUsecase) RemoveStaff(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "UserUsecase.RemoveStaff")
+	defer span.End()
+
+	return u.UserRepo.Update(ctx, id, &domain.User{Role: domain.Member})
 }
 
-// AddStaff promotes a user to staff role by looking up their phone number.
-// Returns error if user not found, already staff, or update fails.
-func (u *UserUsecase) AddStaff(phone string) error {
-	user, err := u.UserRepo.GetByPhone(phone)
+// AddStaff promotes a user to staff role by looking up their phone number, assigning them to
+// the central team or to a faculty. Returns error if user not found, already staff, or update fails.
+func (u *UserUsecase) AddStaff(ctx context.Context, phone string, faculty *string, isCentralStaff bool) error {
+	ctx, span := tracing.Start(ctx, "UserUsecase.AddStaff")
+	defer span.End()
+
+	user, err := u.UserRepo.GetByPhone(ctx, phone)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.ErrUserNotFound
+	}
 	if err != nil {
 		return err
 	}
@@ -270,12 +357,36 @@ func (u *UserUsecase) AddStaff(phone string) error {
 	}
 
 	user.Role = domain.Staff
-	return u.Update(user.ID, &user)
+	user.IsCentralStaff = &isCentralStaff
+	if faculty != nil {
+		user.Faculty = faculty
+	}
+	return u.Update(ctx, user.ID, &user)
+}
+
+// SetStaffScope moves a staff member to the central team or to a faculty. Only admins may do this, as the
+// scope decides which students the staff member sees and exports.
+func (u *UserUsecase) SetStaffScope(ctx context.Context, id string, faculty *string, isCentralStaff bool) error {
+	ctx, span := tracing.Start(ctx, "UserUsecase.SetStaffScope")
+	defer span.End()
+
+	user, err := u.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.Role != domain.Staff {
+		return domain.ErrUserNotStaff
+	}
+
+	return u.UserRepo.Update(ctx, id, &domain.User{Faculty: faculty, IsCentralStaff: &isCentralStaff})
 }
 
 // Delete All user
-func (u *UserUsecase) Delete(id string) error {
-	return u.UserRepo.Delete(id)
+func (u *UserUsecase) Delete(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "UserUsecase.Delete")
+	defer span.End()
+
+	return u.UserRepo.Delete(ctx, id)
 }
 
 func (u *UserUsecase) isNull(staff domain.User) bool {
@@ -286,34 +397,39 @@ func (u *UserUsecase) isNull(staff domain.User) bool {
 // 	return lastEntered != nil && isSameDay(*lastEntered, now)
 // }
 
-func (u *UserUsecase) processCentralStaffEntry(studentId string, student *domain.User, now time.Time) (domain.User, error) {
+func (u *UserUsecase) processCentralStaffEntry(ctx context.Context, studentId string, student *domain.User, now time.Time) (domain.User, error) {
 	student.LastEntered = &now
 	student.Faculty = nil
-	err := u.Update(studentId, student)
+	err := u.Update(ctx, studentId, student)
 	if err != nil {
 		return domain.User{}, err
 	}
 	return *student, nil
 }
 
-func (u *UserUsecase) processFacultyStaffEntry(studentId, faculty string, now time.Time, student domain.User) (domain.User, error) {
-	existingTransactions, err := u.StudentTransactionRepo.GetByStudentIdAndFaculty(studentId, faculty)
+func (u *UserUsecase) processFacultyStaffEntry(ctx context.Context, studentId, faculty string, now time.Time, student domain.User) (domain.User, error) {
+	existingTransactions, err := u.StudentTransactionRepo.GetByStudentIdAndFaculty(ctx, studentId, faculty)
 	if err != nil {
 		return domain.User{}, err
 	}
 	student.LastEntered = &now
-	updateErr := u.UserRepo.Update(studentId, &student)
+	updateErr := u.UserRepo.Update(ctx, studentId, &student)
 	if updateErr != nil {
 		return domain.User{}, fmt.Errorf("failed to update last entered time: %w", updateErr)
 	}
 
 	for _, transaction := range existingTransactions {
 		if isSameDay(transaction.RegisteredAt, now) {
-			return student, domain.ErrUserAlreadyEntered
+			return student, domain.ErrUserAlreadyEntered.WithDetails(domain.FieldError{
+				Field:   "id",
+				Code:    domain.FieldDuplicate,
+				Message: "already scanned at " + faculty + " today",
+				Params:  map[string]interface{}{"enteredAt": transaction.RegisteredAt},
+			})
 		}
 	}
 
-	err = u.StudentTransactionRepo.Create(&domain.StudentTransaction{
+	err = u.StudentTransactionRepo.Create(ctx, &domain.StudentTransaction{
 		ID:                    utils.GenerateUID(),
 		StudentRegistrationID: studentId,
 		Faculty:               faculty,
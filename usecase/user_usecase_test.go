@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+)
+
+// pagedUserRepository returns its users for every listing and records the cursor it was given
+type pagedUserRepository struct {
+	UserRepositoryInterface
+	users []domain.User
+	after *domain.UserCursor
+	limit int
+}
+
+func (r *pagedUserRepository) List(ctx context.Context, filter domain.UserFilter, order domain.UserOrder, after *domain.UserCursor, limit int) ([]domain.User, error) {
+	r.after, r.limit = after, limit
+	return r.users[:min(limit, len(r.users))], nil
+}
+
+func (r *pagedUserRepository) Count(ctx context.Context, filter domain.UserFilter) (int64, error) {
+	return 7, nil
+}
+
+func TestListCursorCrossesNullSortValues(t *testing.T) {
+	entered := time.Date(2024, 11, 16, 9, 0, 0, 0, time.UTC)
+	repo := &pagedUserRepository{users: []domain.User{
+		{ID: "a", LastEntered: &entered},
+		{ID: "b"},
+		{ID: "c"},
+	}}
+	u := NewUserUsecase(repo, nil, nil, "", nil)
+	order := domain.UserOrder{Sort: domain.UserSortLastEntered}
+
+	page, err := u.List(context.Background(), domain.UserFilter{}, order, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.limit != 3 || repo.after != nil {
+		t.Errorf("first page asked for %d users after %v, want 3 from the start", repo.limit, repo.after)
+	}
+	if len(page.Users) != 2 || page.Total != 7 || page.NextCursor == "" {
+		t.Fatalf("first page has %d users of %d, next cursor %q; want 2 of 7 and a cursor", len(page.Users), page.Total, page.NextCursor)
+	}
+
+	// The last user of the page was never scanned in, so the cursor continues among the users without a value
+	repo.users = repo.users[2:]
+	page, err = u.List(context.Background(), domain.UserFilter{}, order, page.NextCursor, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.after == nil || repo.after.Value != nil || repo.after.ID != "b" {
+		t.Errorf("second page listed after %+v, want after user b without a value", repo.after)
+	}
+	if len(page.Users) != 1 || page.NextCursor != "" {
+		t.Errorf("second page has %d users and next cursor %q, want the last user and no cursor", len(page.Users), page.NextCursor)
+	}
+}
+
+func TestListRejectsForeignCursors(t *testing.T) {
+	u := NewUserUsecase(&pagedUserRepository{}, nil, nil, "", nil)
+	byName := domain.NewUserCursor(domain.UserOrder{Sort: domain.UserSortName}, domain.User{ID: "a", Name: "A"}).String()
+
+	for _, cursor := range []string{"not a cursor", byName} {
+		_, err := u.List(context.Background(), domain.UserFilter{}, domain.UserOrder{Sort: domain.UserSortLastEntered}, cursor, 2)
+		if !errors.Is(err, domain.ErrInvalidCursor) {
+			t.Errorf("cursor %q: error %v, want %v", cursor, err, domain.ErrInvalidCursor)
+		}
+	}
+
+	page, err := u.List(context.Background(), domain.UserFilter{}, domain.UserOrder{Sort: domain.UserSortID}, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Users == nil {
+		t.Error("an empty page lists null instead of no users")
+	}
+}
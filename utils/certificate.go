@@ -1,26 +1,123 @@
-package utils
-
-import (
-	"crypto/ed25519"
-	"encoding/base64"
-	"errors"
-)
-
-// CreateToken creates a token(URLsafe) using the provided message and private key
-func GenerateED25519Signature(privateKey string, message string) (string, error) {
-	priavteKeyBytes, err := base64.RawURLEncoding.DecodeString(privateKey)
-	if err != nil {
-		return "", err
-	}
-	if len(priavteKeyBytes) != ed25519.PrivateKeySize {
-		return "", errors.New("invalid private key size")
-	}
-
-	signature := ed25519.Sign(priavteKeyBytes, []byte(message))
-	encodedMessage := base64.RawURLEncoding.EncodeToString([]byte(message))
-
-	// Combine the encoded message and signature
-	token := encodedMessage + "." + base64.RawURLEncoding.EncodeToString(signature)
-
-	return token, nil
-}
+package utils
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidCertToken = errors.New("invalid certificate token")
+
+// CreateToken creates a token(URLsafe) using the provided message and private key
+func GenerateED25519Signature(privateKey string, message string) (string, error) {
+	priavteKeyBytes, err := base64.RawURLEncoding.DecodeString(privateKey)
+	if err != nil {
+		return "", err
+	}
+	if len(priavteKeyBytes) != ed25519.PrivateKeySize {
+		return "", errors.New("invalid private key size")
+	}
+
+	signature := ed25519.Sign(priavteKeyBytes, []byte(message))
+	encodedMessage := base64.RawURLEncoding.EncodeToString([]byte(message))
+
+	// Combine the encoded message and signature
+	token := encodedMessage + "." + base64.RawURLEncoding.EncodeToString(signature)
+
+	return token, nil
+}
+
+// GenerateKeyedED25519Signature creates a token `keyID.base64(message).base64(sig)` so the verifier
+// knows which key signed it after keys are rotated
+func GenerateKeyedED25519Signature(keyID string, privateKey string, message string) (string, error) {
+	if keyID == "" || strings.Contains(keyID, ".") {
+		return "", errors.New("invalid key ID")
+	}
+	token, err := GenerateED25519Signature(privateKey, message)
+	if err != nil {
+		return "", err
+	}
+	return keyID + "." + token, nil
+}
+
+// VerifyED25519Signature checks a token made by GenerateKeyedED25519Signature and returns its key ID and the
+// signed message. Tokens without a key ID are rejected here; see VerifyLegacyED25519Signature.
+func VerifyED25519Signature(publicKeys map[string]ed25519.PublicKey, token string) (string, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] == "" {
+		return "", "", ErrInvalidCertToken
+	}
+	keyID, parts := parts[0], parts[1:]
+
+	message, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", "", ErrInvalidCertToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", "", ErrInvalidCertToken
+	}
+
+	key, ok := publicKeys[keyID]
+	if !ok || !ed25519.Verify(key, message, signature) {
+		return "", "", ErrInvalidCertToken
+	}
+	return keyID, string(message), nil
+}
+
+// VerifyLegacyED25519Signature checks a name-only token `base64(name).base64(sig)` made by GenerateED25519Signature
+// against each of the public keys and returns the signed name. Names that read as certificate claims are rejected, so a
+// keyed token with its key ID cut off is never taken for a name-only one.
+func VerifyLegacyED25519Signature(publicKeys []ed25519.PublicKey, token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return "", ErrInvalidCertToken
+	}
+	message, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", ErrInvalidCertToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrInvalidCertToken
+	}
+	if LooksLikeCertificateClaims(string(message)) {
+		return "", ErrInvalidCertToken
+	}
+	for _, key := range publicKeys {
+		if ed25519.Verify(key, message, signature) {
+			return string(message), nil
+		}
+	}
+	return "", ErrInvalidCertToken
+}
+
+// LooksLikeCertificateClaims reports whether a signed message could be read as JSON certificate claims
+func LooksLikeCertificateClaims(message string) bool {
+	return strings.HasPrefix(strings.TrimLeft(message, " \t\r\n"), "{")
+}
+
+// ParseED25519PublicKeys parses "keyID=base64key,keyID=base64key" (URLsafe base64) into public keys by key ID
+func ParseED25519PublicKeys(spec string) (map[string]ed25519.PublicKey, error) {
+	keys := make(map[string]ed25519.PublicKey)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		keyID, encoded, ok := strings.Cut(entry, "=")
+		if !ok || keyID == "" {
+			return nil, errors.New("public keys must be keyID=base64key")
+		}
+		key, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, err
+		}
+		if len(key) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid public key size for " + keyID)
+		}
+		keys[keyID] = key
+	}
+	return keys, nil
+}
@@ -1,8 +1,11 @@
 package utils
 
 import (
+	crand "crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"math/rand"
+	"strings"
 	"time"
 )
 
@@ -25,3 +28,12 @@ func GenerateUID() string {
 
 	return uid
 }
+
+// GenerateSerial generates an unguessable certificate serial in the format CERT-0123456789ABCDEF
+func GenerateSerial() (string, error) {
+	b := make([]byte, 8)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return "CERT-" + strings.ToUpper(hex.EncodeToString(b)), nil
+}
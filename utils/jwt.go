@@ -6,13 +6,16 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// generateTokens creates an access and refresh token
-func GenerateTokens(userID string, jwtSecret string) (string, error) {
+// generateTokens creates an access and refresh token. The key ID is sent in the "kid" header.
+func GenerateTokens(userID string, keyID string, jwtSecret string) (string, error) {
 	// Access Token
 	accessTokenClaims := jwt.MapClaims{
 		"userId": userID,
 	}
 	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessTokenClaims)
+	if keyID != "" {
+		accessToken.Header["kid"] = keyID
+	}
 	access, err := accessToken.SignedString([]byte(jwtSecret))
 	if err != nil {
 		return "", err
@@ -21,20 +24,31 @@ func GenerateTokens(userID string, jwtSecret string) (string, error) {
 	return access, nil
 }
 
-// DecodeToken decodes the JWT token and returns the userID and any error encountered
-func DecodeToken(tokenString string, jwtSecret string) (string, error) {
-	// Parse and validate the token
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Check the signing method to ensure it's using the expected algorithm
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
+// DecodeToken decodes the JWT token and returns the userID and any error encountered.
+// The secret is picked by the token's "kid" header; tokens without one are tried against every secret.
+func DecodeToken(tokenString string, jwtSecrets map[string]string) (string, error) {
+	var token *jwt.Token
+	var err error
+	for _, secret := range candidateSecrets(tokenString, jwtSecrets) {
+		// Parse and validate the token
+		token, err = jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+			// Check the signing method to ensure it's using the expected algorithm
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, errors.New("unexpected signing method")
+			}
+			return []byte(secret), nil
+		})
+		if err == nil {
+			break
 		}
-		return []byte(jwtSecret), nil
-	})
+	}
 
 	if err != nil {
 		return "", err
 	}
+	if token == nil {
+		return "", errors.New("invalid token")
+	}
 
 	// Check if the token is valid and extract claims
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
@@ -48,3 +62,22 @@ func DecodeToken(tokenString string, jwtSecret string) (string, error) {
 
 	return "", errors.New("invalid token")
 }
+
+// candidateSecrets returns the secret named by the token's "kid" header, or every secret when it has none
+func candidateSecrets(tokenString string, jwtSecrets map[string]string) []string {
+	unverified, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+	if err == nil {
+		if keyID, ok := unverified.Header["kid"].(string); ok {
+			if secret, ok := jwtSecrets[keyID]; ok {
+				return []string{secret}
+			}
+			return nil
+		}
+	}
+
+	secrets := make([]string, 0, len(jwtSecrets))
+	for _, secret := range jwtSecrets {
+		secrets = append(secrets, secret)
+	}
+	return secrets
+}
@@ -0,0 +1,42 @@
+package utils
+
+import "testing"
+
+func TestProvinceCode(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		ok   bool
+	}{
+		{"กรุงเทพมหานคร", "TH-10", true},
+		{"กรุงเทพ", "TH-10", true},
+		{" จังหวัดกรุงเทพมหานคร ", "TH-10", true},
+		{"Bangkok", "TH-10", true},
+		{"BANGKOK", "TH-10", true},
+		{"จ.เชียงใหม่", "TH-50", true},
+		{"Chiang Mai", "TH-50", true},
+		{"Lopburi", "TH-16", true},
+		{"lop-buri", "TH-16", true},
+		{"Ayutthaya", "TH-14", true},
+		{"Phra Nakhon Si Ayutthaya", "TH-14", true},
+		{"", "", false},
+		{"Atlantis", "", false},
+	}
+	for _, tt := range tests {
+		code, ok := ProvinceCode(tt.name)
+		if code != tt.code || ok != tt.ok {
+			t.Errorf("ProvinceCode(%q) = %q, %v; want %q, %v", tt.name, code, ok, tt.code, tt.ok)
+		}
+	}
+}
+
+func TestProvinceName(t *testing.T) {
+	for code := range thaiProvinces {
+		if got, ok := ProvinceCode(ProvinceName(code)); got != code || !ok {
+			t.Errorf("ProvinceCode(ProvinceName(%q)) = %q, %v", code, got, ok)
+		}
+	}
+	if name := ProvinceName("TH-00"); name != "" {
+		t.Errorf("ProvinceName(TH-00) = %q, want empty", name)
+	}
+}
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"time"
+)
+
+// SignExpiring signs a resource ID together with an expiry time using HMAC-SHA256 (URLsafe base64)
+func SignExpiring(secret string, id string, expires time.Time) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(id + "." + strconv.FormatInt(expires.Unix(), 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyExpiring checks a signature produced by SignExpiring and that the link has not expired
+func VerifyExpiring(secret string, id string, expiresUnix int64, signature string, now time.Time) bool {
+	if now.Unix() > expiresUnix {
+		return false
+	}
+	expected := SignExpiring(secret, id, time.Unix(expiresUnix, 0))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
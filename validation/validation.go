@@ -0,0 +1,146 @@
+// Package validation checks request DTOs against their `validate` struct tags and reports every
+// rejected field as a domain.FieldError, named after its JSON key and using the same codes as the
+// questionnaire answers.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/isd-sgcu/oph-67-backend/domain"
+	"github.com/isd-sgcu/oph-67-backend/utils"
+)
+
+// exampleDate is formatted in the expected layout to show clients what a date should look like
+var exampleDate = time.Date(2004, time.May, 2, 0, 0, 0, 0, time.UTC)
+
+// validate is shared by all requests; it caches the parsed tags of every struct it has seen
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(fieldName)
+	if err := v.RegisterValidation("phone", func(fl validator.FieldLevel) bool {
+		return utils.IsValidPhone(fl.Field().String())
+	}); err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Struct validates a request DTO and returns a *domain.ValidationError listing every rejected field,
+// or nil when the request is valid
+func Struct(request interface{}) error {
+	err := validate.Struct(request)
+	if err == nil {
+		return nil
+	}
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		// Not a struct; a programming error rather than bad input
+		return err
+	}
+
+	verr := &domain.ValidationError{}
+	for _, fe := range errs {
+		verr.Fields = append(verr.Fields, fieldError(fe))
+	}
+	return verr
+}
+
+// fieldError describes a failed tag with a stable code
+func fieldError(fe validator.FieldError) domain.FieldError {
+	field := fe.Namespace()
+	// Drop the struct name, e.g. "StudentRegisterRequest.selectedSources[0]"
+	if i := strings.IndexByte(field, '.'); i >= 0 {
+		field = field[i+1:]
+	}
+
+	switch fe.Tag() {
+	case "required", "required_unless":
+		return domain.FieldError{Field: field, Code: domain.FieldRequired, Message: "is required"}
+	case "email":
+		return domain.FieldError{Field: field, Code: domain.FieldInvalid, Message: "must be a valid email address"}
+	case "phone":
+		return domain.FieldError{Field: field, Code: domain.FieldInvalid, Message: "must be a valid phone number, e.g. 0812345678"}
+	case "datetime":
+		example := exampleDate.Format(fe.Param())
+		return domain.FieldError{
+			Field:   field,
+			Code:    domain.FieldInvalidType,
+			Message: "must be a date like " + example,
+			Params:  map[string]interface{}{"example": example},
+		}
+	case "oneof":
+		options := strings.Fields(fe.Param())
+		return domain.FieldError{
+			Field:   field,
+			Code:    domain.FieldInvalidOption,
+			Message: "must be one of " + strings.Join(options, ", "),
+			Params:  map[string]interface{}{"options": options},
+		}
+	case "min", "max", "gte", "lte":
+		return boundError(field, fe)
+	default:
+		return domain.FieldError{Field: field, Code: domain.FieldInvalid, Message: "is invalid"}
+	}
+}
+
+// boundError describes a number out of range, or a string or list of the wrong length
+func boundError(field string, fe validator.FieldError) domain.FieldError {
+	limit := param(fe.Param())
+	bound := "at least"
+	key := "min"
+	if fe.Tag() == "max" || fe.Tag() == "lte" {
+		bound, key = "at most", "max"
+	}
+
+	switch fe.Kind() {
+	case reflect.String:
+		return domain.FieldError{
+			Field:   field,
+			Code:    domain.FieldInvalid,
+			Message: fmt.Sprintf("must be %s %s characters long", bound, fe.Param()),
+			Params:  map[string]interface{}{key: limit},
+		}
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return domain.FieldError{
+			Field:   field,
+			Code:    domain.FieldInvalid,
+			Message: fmt.Sprintf("must have %s %s items", bound, fe.Param()),
+			Params:  map[string]interface{}{key: limit},
+		}
+	default:
+		return domain.FieldError{
+			Field:   field,
+			Code:    domain.FieldOutOfRange,
+			Message: fmt.Sprintf("must be %s %s", bound, fe.Param()),
+			Params:  map[string]interface{}{key: limit},
+		}
+	}
+}
+
+// param returns a numeric tag parameter as a number, so it is reported like other ranges
+func param(p string) interface{} {
+	if n, err := strconv.Atoi(p); err == nil {
+		return n
+	}
+	return p
+}
+
+// fieldName names a field by its JSON key, which is also its form key, so errors match the request
+func fieldName(f reflect.StructField) string {
+	name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
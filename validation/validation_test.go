@@ -0,0 +1,111 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/isd-sgcu/oph-67-backend/domain"
+)
+
+// fieldCodes returns "field:code" for every rejected field of err, sorted
+func fieldCodes(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var verr *domain.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("error %v is not a *domain.ValidationError", err)
+	}
+	codes := make([]string, len(verr.Fields))
+	for i, f := range verr.Fields {
+		codes[i] = f.Field + ":" + f.Code
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+func TestStructReportsEveryRejectedField(t *testing.T) {
+	tests := []struct {
+		name    string
+		request interface{}
+		want    []string
+	}{
+		{"valid staff", &domain.StaffRegisterRequest{ID: "1", Name: "a", Phone: "0812345678", Email: "a@b.co"}, nil},
+		{"staff missing fields", &domain.StaffRegisterRequest{}, []string{"email:required", "id:required", "name:required", "phone:required"}},
+		{"staff year out of range", &domain.StaffRegisterRequest{ID: "1", Name: "a", Phone: "0812345678", Email: "a@b.co", Year: ptr(-1)}, []string{"year:out_of_range"}},
+		{"staff bad phone and email", &domain.StaffRegisterRequest{ID: "1", Name: "a", Phone: "12", Email: "a"}, []string{"email:invalid", "phone:invalid"}},
+
+		{"valid student", &domain.StudentRegisterRequest{ID: "1", Name: "a", Phone: "0812345678", Email: "a@b.co", BirthDate: ptr("2008-01-31")}, nil},
+		{"student missing fields", &domain.StudentRegisterRequest{}, []string{"email:required", "id:required", "name:required", "phone:required"}},
+		{"student bad birth date", &domain.StudentRegisterRequest{ID: "1", Name: "a", Phone: "0812345678", Email: "a@b.co", BirthDate: ptr("31/01/2008")}, []string{"birthDate:invalid_type"}},
+
+		{"empty update", &domain.UpdateUserRequest{}, nil},
+		{"update year out of range", &domain.UpdateUserRequest{Year: ptr(0)}, []string{"year:out_of_range"}},
+		{"update empty name", &domain.UpdateUserRequest{Name: ptr("")}, []string{"name:invalid"}},
+
+		{"central staff", &domain.AddStaffRequest{IsCentralStaff: true}, nil},
+		{"faculty staff without faculty", &domain.AddStaffRequest{}, []string{"faculty:required"}},
+
+		{"valid role", &domain.RoleRequest{Role: "staff"}, nil},
+		{"missing role", &domain.RoleRequest{}, []string{"role:required"}},
+		{"unknown role", &domain.RoleRequest{Role: "superuser"}, []string{"role:invalid_option"}},
+
+		{"missing answers", &domain.EvaluationRequest{}, []string{"answers:required"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fieldCodes(t, Struct(tt.request)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rejected %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type boundedRequest struct {
+	Score    int      `json:"score" validate:"min=1,max=5"`
+	Code     string   `json:"code" validate:"omitempty,min=2"`
+	Tags     []string `json:"tags" validate:"max=2,dive,oneof=a b"`
+	Untagged int      `validate:"lte=3"`
+}
+
+func TestStructFieldErrorDetails(t *testing.T) {
+	err := Struct(&boundedRequest{Score: 6, Code: "x", Tags: []string{"a", "c", "b"}, Untagged: 4})
+	var verr *domain.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("error %v is not a *domain.ValidationError", err)
+	}
+
+	want := []domain.FieldError{
+		{Field: "score", Code: domain.FieldOutOfRange, Message: "must be at most 5", Params: map[string]interface{}{"max": 5}},
+		{Field: "code", Code: domain.FieldInvalid, Message: "must be at least 2 characters long", Params: map[string]interface{}{"min": 2}},
+		{Field: "tags", Code: domain.FieldInvalid, Message: "must have at most 2 items", Params: map[string]interface{}{"max": 2}},
+		{Field: "Untagged", Code: domain.FieldOutOfRange, Message: "must be at most 3", Params: map[string]interface{}{"max": 3}},
+	}
+	if !reflect.DeepEqual(verr.Fields, want) {
+		t.Errorf("fields\n%+v\nwant\n%+v", verr.Fields, want)
+	}
+
+	err = Struct(&boundedRequest{Score: 0, Tags: []string{"a", "c"}})
+	got := fieldCodes(t, err)
+	if want := []string{"score:out_of_range", "tags[1]:invalid_option"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rejected %v, want %v", got, want)
+	}
+	if !errors.As(err, &verr) || !reflect.DeepEqual(verr.Fields[1].Params, map[string]interface{}{"options": []string{"a", "b"}}) {
+		t.Errorf("invalid_option params %v, want the options", verr.Fields[1].Params)
+	}
+}
+
+func TestStructRejectsNonStructs(t *testing.T) {
+	err := Struct("not a struct")
+	var verr *domain.ValidationError
+	if err == nil || errors.As(err, &verr) {
+		t.Errorf("error %v, want a programming error rather than rejected fields", err)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}